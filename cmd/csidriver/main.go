@@ -17,11 +17,16 @@ import (
 	sharev1clientset "github.com/openshift/client-go/sharedresource/clientset/versioned"
 
 	"github.com/openshift/csi-driver-shared-resource/cmd/util"
+	"github.com/openshift/csi-driver-shared-resource/pkg/audit"
 	"github.com/openshift/csi-driver-shared-resource/pkg/cache"
 	"github.com/openshift/csi-driver-shared-resource/pkg/client"
 	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource/pkg/control"
 	"github.com/openshift/csi-driver-shared-resource/pkg/controller"
 	"github.com/openshift/csi-driver-shared-resource/pkg/csidriver"
+	"github.com/openshift/csi-driver-shared-resource/pkg/notify"
+	"github.com/openshift/csi-driver-shared-resource/pkg/status"
+	"github.com/openshift/csi-driver-shared-resource/pkg/usage"
 )
 
 var (
@@ -66,6 +71,15 @@ var rootCmd = &cobra.Command{
 		} else {
 			client.SetShareClient(shareClient)
 		}
+		client.ConfigureSARCache(cfg.GetSARCacheAllowedTTL(), cfg.GetSARCacheDeniedTTL())
+		if len(cfg.AuditLogPath) > 0 {
+			sink, err := audit.NewSink(cfg.AuditLogPath, int64(cfg.AuditLogMaxSizeMB)*1024*1024, cfg.AuditLogMaxBackups, cfg.GetAuditLogMaxAge())
+			if err != nil {
+				fmt.Printf("Failed to open audit log %s: %s", cfg.AuditLogPath, err.Error())
+				os.Exit(1)
+			}
+			audit.Configure(sink, nodeID)
+		}
 
 		driver, err := csidriver.NewCSIDriver(
 			csidriver.DataRoot,
@@ -108,7 +122,24 @@ var rootCmd = &cobra.Command{
 
 		rn := config.SetupNameReservation()
 		stopCh := util.SetupSignalHandler()
+		acknowledger := control.NewAcknowledger(client.GetClient(), client.DefaultNamespace, nodeID)
+		if err := acknowledger.Load(); err != nil {
+			klog.Warningf("unable to load the control acknowledgements of node %s: %s", nodeID, err.Error())
+		}
+		control.Configure(acknowledger)
 		go runOperator(c, stopCh)
+		if interval := cfg.GetUsageReportInterval(); interval > 0 {
+			publisher := usage.NewPublisher(client.GetClient(), client.DefaultNamespace, nodeID, driver.UsageVolumes)
+			go publisher.Run(interval, stopCh)
+		}
+		if len(cfg.NotificationEndpoints) > 0 {
+			notifier := notify.NewNotifier(nodeID, cfg.NotificationEndpoints, cfg.NotificationQueueSize, cfg.NotificationMaxRetries)
+			notify.Configure(notifier)
+			go notifier.Run(stopCh)
+		}
+		if cfg.ManageShareStatus {
+			go runShareStatusController(rn, cfg.GetShareRelistInterval(), stopCh)
+		}
 		go watchForConfigChanges(cfgManager)
 		driver.Run(rn)
 		prunerDone <- struct{}{}
@@ -162,6 +193,16 @@ func runOperator(c *controller.Controller, stopCh <-chan struct{}) {
 	}
 }
 
+// runShareStatusController competes with the driver instances on the other nodes to maintain the status conditions
+// of the shares.
+func runShareStatusController(rn *config.ReservedNames, shareRelist time.Duration, stopCh <-chan struct{}) {
+	err := status.RunLeaderElected(client.GetClient(), client.GetShareClient(), rn, shareRelist, client.DefaultNamespace, nodeID, stopCh)
+	if err != nil {
+		fmt.Printf("Share status controller exited: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
 // watchForConfigChanges keeps checking if the informed configuration has changed, and in this case
 // makes the operator exit. The new configuration should take place upon new instance started.
 func watchForConfigChanges(mgr *config.Manager) {
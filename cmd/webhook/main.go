@@ -8,11 +8,17 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 
+	sharev1clientset "github.com/openshift/client-go/sharedresource/clientset/versioned"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
 	"github.com/openshift/csi-driver-shared-resource/pkg/config"
 	"github.com/openshift/csi-driver-shared-resource/pkg/webhook/csidriver"
 	"github.com/openshift/csi-driver-shared-resource/pkg/webhook/dispatcher"
@@ -30,6 +36,7 @@ var (
 	listenAddress string
 	listenPort    int
 	testHooks     bool
+	cfgFilePath   string
 )
 
 var (
@@ -53,12 +60,55 @@ func init() {
 	CmdWebhook.Flags().StringVar(&listenAddress, "listen", "0.0.0.0", "Listen address")
 	CmdWebhook.Flags().IntVar(&listenPort, "port", 5000, "Secure port that the webhook listens on")
 	CmdWebhook.Flags().BoolVar(&testHooks, "testHooks", false, "Test webhook URI uniqueness and quit")
+	CmdWebhook.Flags().StringVar(&cfgFilePath, "config", "/var/run/configmaps/config/config.yaml", "driver configuration file path, for the share policy")
 }
 
 func startServer() {
-	webhook := csidriver.NewWebhook(config.SetupNameReservation())
-	dispatcher := dispatcher.NewDispatcher(webhook)
-	http.HandleFunc(webhook.GetURI(), dispatcher.HandleRequest)
+	// the share policy of the driver configuration is enforced on share admission
+	cfgManager := config.NewManager(cfgFilePath)
+	cfg, err := cfgManager.LoadConfig()
+	if err != nil {
+		fmt.Printf("Failed to load configuration file '%s': %s", cfgFilePath, err.Error())
+		os.Exit(1)
+	}
+	// and so are its admission policies, compiled again when the webhook restarts on a configuration change
+	policies, err := csidriver.CompilePolicies(cfg.AdmissionPolicies)
+	if err != nil {
+		fmt.Printf("Failed to compile the admission policies of configuration file '%s': %s", cfgFilePath, err.Error())
+		os.Exit(1)
+	}
+	// and so are its default volume attributes, which would otherwise make every pod they are set on be rejected
+	mutatingWebhook, err := csidriver.NewMutatingWebhook(cfg.DefaultVolumeAttributes)
+	if err != nil {
+		fmt.Printf("Invalid default volume attributes in configuration file '%s': %s", cfgFilePath, err.Error())
+		os.Exit(1)
+	}
+	go watchForConfigChanges(cfgManager)
+
+	// the shares are watched to look up the consumer selectors of the shares referenced by pods and the aliases of
+	// shares, and the pods to look up the consumers of the shares being deleted
+	if kubeRestConfig, err := client.GetConfig(); err != nil {
+		klog.Warningf("unable to get a kube config, shares and pods will not be looked up during admission: %s", err.Error())
+	} else {
+		if shareClient, err := sharev1clientset.NewForConfig(kubeRestConfig); err != nil {
+			klog.Warningf("unable to create a share client, shares will not be looked up during pod admission: %s", err.Error())
+		} else {
+			client.SetShareClient(shareClient)
+			if err := csidriver.StartShareInformers(shareClient, wait.NeverStop); err != nil {
+				klog.Warningf("unable to watch the shares, they will be fetched from the API server: %s", err.Error())
+			}
+		}
+		if kubeClient, err := kubernetes.NewForConfig(kubeRestConfig); err != nil {
+			klog.Warningf("unable to create a kube client, the consumers of deleted shares will not be looked up: %s", err.Error())
+		} else if err := csidriver.StartPodInformer(kubeClient, wait.NeverStop); err != nil {
+			klog.Warningf("unable to watch the pods, the consumers of deleted shares will not be looked up: %s", err.Error())
+		}
+	}
+
+	webhook := csidriver.NewWebhook(config.SetupNameReservation(), policies)
+	http.HandleFunc(webhook.GetURI(), dispatcher.NewDispatcher(webhook).HandleRequest)
+	// the mutating webhook defaults the shared resource volumes of pods before the validating webhook checks them
+	http.HandleFunc(mutatingWebhook.GetURI(), dispatcher.NewDispatcher(mutatingWebhook).HandleRequest)
 
 	if testHooks {
 		os.Exit(0)
@@ -70,7 +120,6 @@ func startServer() {
 		Addr: net.JoinHostPort(listenAddress, strconv.Itoa(listenPort)),
 	}
 	//TODO do we want to explore signal handling / graceful shutdown for the webhook, with some wrapper around the http server
-	var err error
 	if useTLS {
 		var cafile []byte
 		cafile, err = os.ReadFile(caCert)
@@ -94,6 +143,18 @@ func startServer() {
 	}
 }
 
+// watchForConfigChanges makes the webhook exit when the configuration changes on disk, so that it is restarted with
+// the new share and admission policies
+func watchForConfigChanges(mgr *config.Manager) {
+	for {
+		if mgr.ConfigHasChanged() {
+			fmt.Println("Configuration has changed on disk, restarting the webhook!")
+			os.Exit(0)
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
 func main() {
 	if err := CmdWebhook.Execute(); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+/*
+The audit log records the share access decisions of the driver, and what it did to the content of the volumes as a
+result, as JSON lines, for compliance purposes.  It answers who was granted or denied which share, when, and why.
+
+Authorize records are written for every permission check of a volume, when it is published, and when it is
+re-checked on share updates, relists and RBAC changes.  Revoke and RemoveContent records are written when a pod loses
+access to a share and when the content of its volume is removed, and UpdateContent records when the content of the
+backing resource is written to a volume.
+
+Until Configure is called with a sink, records are dropped.
+*/
+
+// Action is what the driver decided or did for a volume
+type Action string
+
+const (
+	ActionAuthorize     Action = "Authorize"
+	ActionRevoke        Action = "Revoke"
+	ActionRemoveContent Action = "RemoveContent"
+	ActionUpdateContent Action = "UpdateContent"
+)
+
+// Decision is the outcome of an Authorize record
+type Decision string
+
+const (
+	DecisionAllowed Decision = "Allowed"
+	DecisionDenied  Decision = "Denied"
+	DecisionError   Decision = "Error"
+)
+
+// Phase is when an Authorize record was taken
+type Phase string
+
+const (
+	// PhasePublish is the permission check when the kubelet publishes the volume
+	PhasePublish Phase = "publish"
+	// PhaseRelist is the permission check of an already published volume, be it from a share update, a share
+	// relist, an RBAC change or a share expiry
+	PhaseRelist Phase = "relist"
+)
+
+// Record is a single JSON line of the audit log
+type Record struct {
+	Time            time.Time `json:"time"`
+	Node            string    `json:"node"`
+	Action          Action    `json:"action"`
+	Phase           Phase     `json:"phase,omitempty"`
+	Decision        Decision  `json:"decision,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	VolumeID        string    `json:"volumeID,omitempty"`
+	Namespace       string    `json:"namespace"`
+	Pod             string    `json:"pod"`
+	PodUID          string    `json:"podUID,omitempty"`
+	ServiceAccount  string    `json:"serviceAccount"`
+	ShareKind       string    `json:"shareKind"`
+	Share           string    `json:"share"`
+	BackingResource string    `json:"backingResource,omitempty"`
+}
+
+type auditLog struct {
+	lock    sync.Mutex
+	sink    io.WriteCloser
+	encoder *json.Encoder
+	node    string
+	now     func() time.Time
+}
+
+var log = &auditLog{now: time.Now}
+
+// StdoutSink is the audit log path that writes the records to the driver's standard output instead of a file
+const StdoutSink = "stdout"
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// NewSink returns the standard output for StdoutSink, and a RotatingFile for any other path
+func NewSink(path string, maxSize int64, maxBackups int, maxAge time.Duration) (io.WriteCloser, error) {
+	if path == StdoutSink {
+		return nopCloser{os.Stdout}, nil
+	}
+	return NewRotatingFile(path, maxSize, maxBackups, maxAge)
+}
+
+// Configure sets the sink audit records are written to, and the node name recorded with them, closing the previous
+// sink if any.  A nil sink disables the audit log.
+func Configure(sink io.WriteCloser, node string) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	if log.sink != nil {
+		log.sink.Close()
+	}
+	log.sink = sink
+	log.node = node
+	log.encoder = nil
+	if sink != nil {
+		log.encoder = json.NewEncoder(sink)
+	}
+}
+
+// Enabled returns whether records are written anywhere
+func Enabled() bool {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	return log.sink != nil
+}
+
+// Log writes the record, stamped with the current time and node, to the configured sink
+func Log(r Record) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+	if log.encoder == nil {
+		return
+	}
+	r.Time = log.now().UTC()
+	r.Node = log.node
+	if err := log.encoder.Encode(&r); err != nil {
+		klog.Errorf("unable to write audit record for %s %s pod %s:%s: %s", r.Action, r.Share, r.Namespace, r.Pod, err.Error())
+	}
+}
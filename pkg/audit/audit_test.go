@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bufferSink struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferSink) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestLog(t *testing.T) {
+	now := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	log.now = func() time.Time { return now }
+	defer func() { log.now = time.Now }()
+
+	// records are dropped until a sink is configured
+	Log(Record{Action: ActionAuthorize})
+	if Enabled() {
+		t.Fatalf("expected the audit log to be disabled")
+	}
+
+	sink := &bufferSink{}
+	Configure(sink, "node1")
+	defer Configure(nil, "")
+	Log(Record{Action: ActionAuthorize, Phase: PhasePublish, Decision: DecisionDenied, Reason: "forbidden",
+		Namespace: "ns1", Pod: "pod1", ServiceAccount: "sa1", ShareKind: "Secret", Share: "share1", BackingResource: "ns2:secret1"})
+	Log(Record{Action: ActionRemoveContent, Namespace: "ns1", Pod: "pod1", ServiceAccount: "sa1", ShareKind: "Secret", Share: "share1"})
+
+	lines := strings.Split(strings.TrimSpace(sink.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got %q", sink.String())
+	}
+	r := Record{}
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil {
+		t.Fatalf("unexpected error parsing %q: %s", lines[0], err.Error())
+	}
+	if !r.Time.Equal(now) || r.Node != "node1" || r.Decision != DecisionDenied || r.BackingResource != "ns2:secret1" {
+		t.Fatalf("unexpected record %#v", r)
+	}
+
+	Configure(nil, "")
+	if !sink.closed {
+		t.Fatalf("expected the previous sink to be closed")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	f, err := NewRotatingFile(path, 10, 2, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	defer f.Close()
+	now := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+
+	// a stale backup is pruned by age on the next rotation
+	stale := path + "." + now.Add(-2*time.Hour).Format(backupTimeFormat)
+	if err = os.WriteFile(stale, []byte("old\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for i := 0; i < 4; i++ {
+		now = now.Add(time.Second)
+		if _, err = f.Write([]byte("12345678\n")); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %v", backups)
+	}
+	for _, backup := range backups {
+		if backup == stale {
+			t.Fatalf("expected stale backup %s to be pruned", stale)
+		}
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "12345678\n" {
+		t.Fatalf("unexpected content after rotation %q", string(content))
+	}
+}
@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFile is an append only file that is rotated once it grows past a maximum size, keeping a bounded number
+// of rotated backups for a bounded time
+type RotatingFile struct {
+	lock       sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	file       *os.File
+	size       int64
+	now        func() time.Time
+}
+
+// NewRotatingFile opens, or creates, the file at path.  A zero maxSize disables rotation, and a zero maxBackups or
+// maxAge does not prune rotated backups by count or age respectively.
+func NewRotatingFile(path string, maxSize int64, maxBackups int, maxAge time.Duration) (*RotatingFile, error) {
+	r := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		maxAge:     maxAge,
+		now:        time.Now,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer, rotating the file first if the write would grow it past its maximum size
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.file == nil {
+		return 0, fmt.Errorf("audit log %s is closed", r.path)
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close implements io.Closer
+func (r *RotatingFile) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	backup := r.path + "." + r.now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(r.path, backup); err != nil {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.prune()
+	return nil
+}
+
+// prune removes the rotated backups beyond the maximum count, and those older than the maximum age
+func (r *RotatingFile) prune() {
+	backups, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		klog.Warningf("unable to list audit log backups of %s: %s", r.path, err.Error())
+		return
+	}
+	// the backup time format sorts chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	for i, backup := range backups {
+		remove := r.maxBackups > 0 && i >= r.maxBackups
+		if !remove && r.maxAge > 0 {
+			t, err := time.Parse(backupTimeFormat, strings.TrimPrefix(backup, r.path+"."))
+			remove = err == nil && r.now().UTC().Sub(t) > r.maxAge
+		}
+		if remove {
+			if err := os.Remove(backup); err != nil {
+				klog.Warningf("unable to remove audit log backup %s: %s", backup, err.Error())
+			}
+		}
+	}
+}
@@ -47,11 +47,8 @@ func UpsertConfigMap(configmap *corev1.ConfigMap) {
 	// first, find the shares pointing to this configmap, and call the callbacks, in case certain pods
 	// have had their permissions revoked; this will also handle if we had share events arrive before
 	// the corresponding configmap
-	sharecConfigMapList := client.ListSharedConfigMap()
-	for _, share := range sharecConfigMapList {
-		if share.Spec.ConfigMapRef.Namespace == configmap.Namespace && share.Spec.ConfigMapRef.Name == configmap.Name {
-			shareConfigMapsUpdateCallbacks.Range(buildRanger(buildCallbackMap(share.Name, share)))
-		}
+	for _, share := range client.ListSharedConfigMapsForConfigMap(configmap.Namespace, configmap.Name) {
+		shareConfigMapsUpdateCallbacks.Range(buildRanger(buildCallbackMap(share.Name, share)))
 	}
 	// otherwise process any share that arrived after the configmap
 	configmapUpsertCallbacks.Range(buildRanger(buildCallbackMap(key, configmap)))
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+/*
+Some old fashioned comments that describe what we are doing in this golang file.
+
+When Roles, RoleBindings, ClusterRoles or ClusterRoleBindings that may grant "use" on shares change, the controller
+calls ReauthorizeNamespace with the namespace affected, or metav1.NamespaceAll for cluster scoped RBAC objects.  The
+CSI driver registers a callback per volume, which re-runs the permission check for that volume when its pod lives in
+the affected namespace, so that revocation takes effect without waiting for the share relist.
+
+On the use of sync.Map, see the comments in share.go
+*/
+
+var (
+	// rbacUpdateCallbacks has a key of the CSI volume ID and a value of the function to be called when RBAC objects
+	// that may grant "use" on shares change
+	rbacUpdateCallbacks = sync.Map{}
+)
+
+// ReauthorizeNamespace calls the registered RBAC update callbacks for the given namespace, where metav1.NamespaceAll
+// means all namespaces
+func ReauthorizeNamespace(namespace string) {
+	if namespace == metav1.NamespaceAll {
+		klog.V(4).Info("ReauthorizeNamespace all namespaces")
+	} else {
+		klog.V(4).Infof("ReauthorizeNamespace namespace %s", namespace)
+	}
+	rbacUpdateCallbacks.Range(buildRanger(buildCallbackMap(namespace, nil)))
+}
+
+// RegisterRBACUpdateCallback will be called as part of the kubelet sending a mount CSI volume request for a pod; it
+// records the CSI driver function to be called when the RBAC objects that may grant "use" on shares change
+func RegisterRBACUpdateCallback(volID string, f func(key, value interface{}) bool) {
+	rbacUpdateCallbacks.Store(volID, f)
+}
+
+// UnregisterRBACUpdateCallback will be called as part of the kubelet sending a delete CSI volume request for a pod
+// that is going away, and we remove the corresponding function for that volID
+func UnregisterRBACUpdateCallback(volID string) {
+	rbacUpdateCallbacks.Delete(volID)
+}
@@ -48,11 +48,8 @@ func UpsertSecret(secret *corev1.Secret) {
 	// first, find the shares pointing to this secret, and call the callbacks, in case certain pods
 	// have had their permissions revoked; this will also handle if we had share events arrive before
 	// the corresponding secret
-	sharedSecretsList := client.ListSharedSecrets()
-	for _, share := range sharedSecretsList {
-		if share.Spec.SecretRef.Namespace == secret.Namespace && share.Spec.SecretRef.Name == secret.Name {
-			shareSecretsUpdateCallbacks.Range(buildRanger(buildCallbackMap(share.Name, share)))
-		}
+	for _, share := range client.ListSharedSecretsForSecret(secret.Namespace, secret.Name) {
+		shareSecretsUpdateCallbacks.Range(buildRanger(buildCallbackMap(share.Name, share)))
 	}
 
 	// otherwise process any share that arrived after the secret
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/klog/v2"
+
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// ShareAliases returns the former names of a share, listed in its AliasesAnnotation
+func ShareAliases(annotations map[string]string) []string {
+	aliases := []string{}
+	for _, alias := range strings.Split(annotations[consts.AliasesAnnotation], ",") {
+		if alias = strings.TrimSpace(alias); len(alias) > 0 {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// ShareHasAlias returns whether the share lists the given name among its aliases
+func ShareHasAlias(annotations map[string]string, alias string) bool {
+	for _, a := range ShareAliases(annotations) {
+		if a == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// SharedConfigMapForAlias returns the SharedConfigMap listing the given name among its aliases, using the AliasIndex
+// when the informer has been set up, and otherwise falling back to scanning all of them; it returns nil when no
+// SharedConfigMap does, and an error when several do
+func SharedConfigMapForAlias(alias string) (*sharev1alpha1.SharedConfigMap, error) {
+	shares := []*sharev1alpha1.SharedConfigMap{}
+	indexed := false
+	if singleton.SharedConfigMapsIndexer != nil {
+		objs, err := singleton.SharedConfigMapsIndexer.ByIndex(AliasIndex, alias)
+		if err == nil {
+			indexed = true
+			for _, obj := range objs {
+				if scm, ok := obj.(*sharev1alpha1.SharedConfigMap); ok {
+					shares = append(shares, scm)
+				}
+			}
+		} else {
+			klog.V(4).Infof("SharedConfigMapForAlias index lookup for %s got error: %s", alias, err.Error())
+		}
+	}
+	if !indexed {
+		for _, scm := range ListSharedConfigMap() {
+			if ShareHasAlias(scm.Annotations, alias) {
+				shares = append(shares, scm)
+			}
+		}
+	}
+	names := []string{}
+	for _, scm := range shares {
+		names = append(names, scm.Name)
+	}
+	if err := ambiguousAlias(alias, names); err != nil || len(shares) == 0 {
+		return nil, err
+	}
+	return shares[0], nil
+}
+
+// SharedSecretForAlias returns the SharedSecret listing the given name among its aliases, using the AliasIndex when
+// the informer has been set up, and otherwise falling back to scanning all of them; it returns nil when no
+// SharedSecret does, and an error when several do
+func SharedSecretForAlias(alias string) (*sharev1alpha1.SharedSecret, error) {
+	shares := []*sharev1alpha1.SharedSecret{}
+	indexed := false
+	if singleton.SharedSecretsIndexer != nil {
+		objs, err := singleton.SharedSecretsIndexer.ByIndex(AliasIndex, alias)
+		if err == nil {
+			indexed = true
+			for _, obj := range objs {
+				if ss, ok := obj.(*sharev1alpha1.SharedSecret); ok {
+					shares = append(shares, ss)
+				}
+			}
+		} else {
+			klog.V(4).Infof("SharedSecretForAlias index lookup for %s got error: %s", alias, err.Error())
+		}
+	}
+	if !indexed {
+		for _, ss := range ListSharedSecrets() {
+			if ShareHasAlias(ss.Annotations, alias) {
+				shares = append(shares, ss)
+			}
+		}
+	}
+	names := []string{}
+	for _, ss := range shares {
+		names = append(names, ss.Name)
+	}
+	if err := ambiguousAlias(alias, names); err != nil || len(shares) == 0 {
+		return nil, err
+	}
+	return shares[0], nil
+}
+
+// ambiguousAlias returns an error when more than one share claims the alias, as none of them can be picked safely
+func ambiguousAlias(alias string, names []string) error {
+	if len(names) < 2 {
+		return nil
+	}
+	sort.Strings(names)
+	return fmt.Errorf("alias %s is claimed by several shares: %s", alias, strings.Join(names, ", "))
+}
+
+// ExecuteSARForShareOrAlias runs the SubjectAccessReview of the user against the share and, when it is denied and the
+// volume references the share through one of its aliases, against the alias, so that the RBAC granting the use of
+// the former name of a renamed share keeps working as long as the share lists it among its aliases
+func ExecuteSARForShareOrAlias(shareName, alias string, annotations map[string]string, podNamespace, podName string, user *authenticationv1.UserInfo, kind consts.ResourceReferenceType) (bool, error) {
+	allowed, err := ExecuteSARForUser(shareName, podNamespace, podName, user, kind)
+	if allowed || len(alias) == 0 || !ShareHasAlias(annotations, alias) {
+		return allowed, err
+	}
+	klog.V(4).Infof("share %s denied to pod %s:%s, checking its alias %s", shareName, podNamespace, podName, alias)
+	return ExecuteSARForUser(alias, podNamespace, podName, user, kind)
+}
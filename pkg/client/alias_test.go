@@ -0,0 +1,64 @@
+package client
+
+import (
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	fakekubetesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func TestSharedConfigMapForAlias(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		AliasIndex: SharedConfigMapAliasIndexFunc,
+	})
+	indexer.Add(&sharev1alpha1.SharedConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca-2027", Annotations: map[string]string{consts.AliasesAnnotation: "ca, ca-2026 "}},
+	})
+	indexer.Add(&sharev1alpha1.SharedConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca-2028", Annotations: map[string]string{consts.AliasesAnnotation: "ca"}},
+	})
+	SetSharedConfigMapsIndexer(indexer)
+	defer SetSharedConfigMapsIndexer(nil)
+
+	if scm, err := SharedConfigMapForAlias("ca-2026"); err != nil || scm == nil || scm.Name != "ca-2027" {
+		t.Errorf("expected ca-2027 for alias ca-2026, got %v %v", scm, err)
+	}
+	if scm, err := SharedConfigMapForAlias("other"); err != nil || scm != nil {
+		t.Errorf("expected no share for alias other, got %v %v", scm, err)
+	}
+	if _, err := SharedConfigMapForAlias("ca"); err == nil {
+		t.Errorf("expected an error for the alias claimed by two shares")
+	}
+}
+
+func TestExecuteSARForShareOrAlias(t *testing.T) {
+	kubeClient := fakekubeclientset.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		sar := action.(fakekubetesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		// the RBAC only grants the former name of the share
+		allowed := sar.Spec.ResourceAttributes.Name == "old-name"
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}}, nil
+	})
+	SetClient(kubeClient)
+	defer SetClient(nil)
+	user := ServiceAccountUser("ns1", "sa1")
+	annotations := map[string]string{consts.AliasesAnnotation: "old-name"}
+
+	if allowed, err := ExecuteSARForShareOrAlias("new-name", "old-name", annotations, "ns1", "pod1", user, consts.ResourceReferenceTypeSecret); !allowed {
+		t.Errorf("expected the alias to be allowed during the transition, got %v", err)
+	}
+	if allowed, _ := ExecuteSARForShareOrAlias("new-name", "", annotations, "ns1", "pod1", user, consts.ResourceReferenceTypeSecret); allowed {
+		t.Errorf("expected the share referenced by its name to be denied")
+	}
+	if allowed, _ := ExecuteSARForShareOrAlias("new-name", "old-name", map[string]string{}, "ns1", "pod1", user, consts.ResourceReferenceTypeSecret); allowed {
+		t.Errorf("expected the alias to be denied once the share no longer lists it")
+	}
+}
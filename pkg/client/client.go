@@ -6,32 +6,42 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
 	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	ktypedclient "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
 
 	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
 	sharev1clientset "github.com/openshift/client-go/sharedresource/clientset/versioned"
 
 	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+	"github.com/openshift/csi-driver-shared-resource/pkg/metrics"
 )
 
 const (
 	DefaultNamespace             = "openshift-cluster-csi-drivers"
 	DriverConfigurationConfigMap = "csi-driver-shared-resource-config"
 	DriverConfigurationDataKey   = "config.yaml"
+
+	// eventBurstSize and eventQPS rate limit the events recorded per involved object, type and reason, so that a
+	// relist or a flapping permission does not flood the namespace of a pod with events
+	eventBurstSize = 10
+	eventQPS       = 1. / 60.
 )
 
 var (
@@ -43,7 +53,11 @@ var (
 
 // SetClient sets the internal kubernetes client interface. Useful for testing.
 func SetClient(client kubernetes.Interface) {
+	initLock.Lock()
+	defer initLock.Unlock()
 	kubeClient = client
+	// the event recorder sinks to the client, so is recreated on next use
+	recorder = nil
 }
 
 func GetClient() kubernetes.Interface {
@@ -92,6 +106,22 @@ func GetConfig() (*rest.Config, error) {
 	return nil, fmt.Errorf("could not locate a kubeconfig")
 }
 
+// eventSpamKey rate limits events per involved object, type and reason, so that frequent events of one reason do not
+// suppress the others
+func eventSpamKey(event *corev1.Event) string {
+	return strings.Join([]string{
+		event.Source.Component,
+		event.Source.Host,
+		event.InvolvedObject.Kind,
+		event.InvolvedObject.Namespace,
+		event.InvolvedObject.Name,
+		string(event.InvolvedObject.UID),
+		event.InvolvedObject.APIVersion,
+		event.Type,
+		event.Reason,
+	}, "")
+}
+
 func initClient() error {
 	initLock.Lock()
 	defer initLock.Unlock()
@@ -106,16 +136,63 @@ func initClient() error {
 		}
 
 	}
-	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartRecordingToSink(&ktypedclient.EventSinkImpl{Interface: kubeClient.CoreV1().Events(DefaultNamespace)})
-	recorder = eventBroadcaster.NewRecorder(runtime.NewScheme(), corev1.EventSource{Component: DefaultNamespace})
+	if recorder == nil {
+		// the events are recorded in the namespace of the involved object, be it a consuming pod or a backing resource
+		eventBroadcaster := record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{
+			BurstSize:   eventBurstSize,
+			QPS:         eventQPS,
+			SpamKeyFunc: eventSpamKey,
+		})
+		eventBroadcaster.StartRecordingToSink(&ktypedclient.EventSinkImpl{Interface: kubeClient.CoreV1().Events(metav1.NamespaceAll)})
+		recorder = eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: DefaultNamespace})
+	}
 	return nil
 }
 
+// ExecuteSAR checks whether the pod's service account is allowed to "use" the given share, consulting the node local
+// SubjectAccessReview cache first when it is enabled
 func ExecuteSAR(shareName, podNamespace, podName, podSA string, kind consts.ResourceReferenceType) (bool, error) {
+	return ExecuteSARForUser(shareName, podNamespace, podName, ServiceAccountUser(podNamespace, podSA), kind)
+}
+
+// ExecuteSARForUser checks whether the given pod user, typically validated from a kubelet supplied service account
+// token, is allowed to "use" the given share.
+func ExecuteSARForUser(shareName, podNamespace, podName string, user *authenticationv1.UserInfo, kind consts.ResourceReferenceType) (bool, error) {
+	if user == nil {
+		return false, status.Errorf(codes.Internal,
+			"subjectaccessreviews share %s podNamespace %s podName %s has no user to check", shareName, podNamespace, podName)
+	}
+	// NOTE: user extra fields, like the pod name and uid of bound tokens, are not part of the cache key, so the
+	// cached result of a service account is shared by all its pods
+	key := sarCacheKey{shareName: shareName, kind: kind, namespace: podNamespace, user: userCacheKey(user)}
+	cacheEnabled := sarResults.enabled()
+	allowed, found, generation := sarResults.get(key)
+	if cacheEnabled {
+		metrics.IncSARCacheCounters(found)
+	}
+	if found {
+		if allowed {
+			return true, nil
+		}
+		return false, status.Errorf(codes.PermissionDenied,
+			"subjectaccessreviews share %s podNamespace %s podName %s user %s returned forbidden",
+			shareName, podNamespace, podName, user.Username)
+	}
+
+	allowed, answered, err := executeSAR(shareName, podNamespace, podName, user, kind)
+	// only cache actual allowed/denied answers from the API server, not failures to get an answer
+	if answered {
+		sarResults.set(key, allowed, generation)
+	}
+	return allowed, err
+}
+
+// executeSAR returns whether the SubjectAccessReview allowed the request, whether the API server actually
+// answered the SubjectAccessReview, and the error to surface when not allowed
+func executeSAR(shareName, podNamespace, podName string, user *authenticationv1.UserInfo, kind consts.ResourceReferenceType) (bool, bool, error) {
 	err := initClient()
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 	sarClient := kubeClient.AuthorizationV1().SubjectAccessReviews()
 	resource := ""
@@ -132,37 +209,70 @@ func ExecuteSAR(shareName, podNamespace, podName, podSA string, kind consts.Reso
 		Name:      shareName,
 		Namespace: podNamespace,
 	}
-	sar := &authorizationv1.SubjectAccessReview{
-		Spec: authorizationv1.SubjectAccessReviewSpec{
-			ResourceAttributes: resourceAttributes,
-			User:               fmt.Sprintf("system:serviceaccount:%s:%s", podNamespace, podSA),
-			// adding the standard SA group to allow for RBAC based on that group; otherwise,
-			// the SAR will not pass
-			Groups: []string{"system:serviceaccounts"},
-		}}
-
-	resp, err := sarClient.Create(context.TODO(), sar, metav1.CreateOptions{})
+	resp, err := sarClient.Create(context.TODO(), subjectAccessReview(user, resourceAttributes), metav1.CreateOptions{})
 	if err == nil && resp != nil {
 		if resp.Status.Allowed {
-			return true, nil
+			metrics.IncSARRequestCounter("allowed")
+			return true, true, nil
 		}
-		return false, status.Errorf(codes.PermissionDenied,
-			"subjectaccessreviews share %s podNamespace %s podName %s podSA %s returned forbidden",
-			shareName, podNamespace, podName, podSA)
+		metrics.IncSARRequestCounter("denied")
+		return false, true, status.Errorf(codes.PermissionDenied,
+			"subjectaccessreviews share %s podNamespace %s podName %s user %s returned forbidden",
+			shareName, podNamespace, podName, user.Username)
 	}
 
+	metrics.IncSARRequestCounter("error")
 	if kerrors.IsForbidden(err) {
-		return false, status.Errorf(codes.PermissionDenied,
-			"subjectaccessreviews share %s podNamespace %s podName %s podSA %s returned forbidden: %s",
-			shareName, podNamespace, podName, podSA, err.Error())
+		return false, false, status.Errorf(codes.PermissionDenied,
+			"subjectaccessreviews share %s podNamespace %s podName %s user %s returned forbidden: %s",
+			shareName, podNamespace, podName, user.Username, err.Error())
 	}
 
-	return false, status.Errorf(codes.Internal,
-		"subjectaccessreviews share %s podNamespace %s podName %s podSA %s returned error: %s",
-		shareName, podNamespace, podName, podSA, err.Error())
+	return false, false, status.Errorf(codes.Internal,
+		"subjectaccessreviews share %s podNamespace %s podName %s user %s returned error: %s",
+		shareName, podNamespace, podName, user.Username, err.Error())
+}
+
+// subjectAccessReview returns the SubjectAccessReview of the resource attributes for the user
+func subjectAccessReview(user *authenticationv1.UserInfo, resourceAttributes *authorizationv1.ResourceAttributes) *authorizationv1.SubjectAccessReview {
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: resourceAttributes,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+		}}
+	if len(user.Extra) > 0 {
+		sar.Spec.Extra = map[string]authorizationv1.ExtraValue{}
+		for k, v := range user.Extra {
+			sar.Spec.Extra[k] = authorizationv1.ExtraValue(v)
+		}
+	}
+	return sar
 }
 
 func GetPod(namespace, name string) (*corev1.Pod, error) {
 	initClient()
 	return kubeClient.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 }
+
+// RecordPodEvent records an event on the given pod, in the pod's namespace
+func RecordPodEvent(namespace, name, uid, eventType, reason, messageFmt string, args ...interface{}) {
+	if err := initClient(); err != nil {
+		klog.Warningf("unable to record event %s on pod %s:%s: %s", reason, namespace, name, err.Error())
+		return
+	}
+	ref := &corev1.ObjectReference{
+		Kind:       "Pod",
+		APIVersion: "v1",
+		Namespace:  namespace,
+		Name:       name,
+		UID:        types.UID(uid),
+	}
+	recorder.Eventf(ref, eventType, reason, messageFmt, args...)
+}
+
+func GetNamespace(name string) (*corev1.Namespace, error) {
+	initClient()
+	return kubeClient.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
+}
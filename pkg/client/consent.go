@@ -0,0 +1,59 @@
+package client
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// ValidateOwnerConsent returns a PermissionDenied error when the backing resource does not carry the consent of its
+// owner to be exposed by the share: the ShareableLabel set to "true" and, when set, the ShareableByAnnotation naming
+// the share
+func ValidateOwnerConsent(kind consts.ResourceReferenceType, shareName string, backing metav1.Object) error {
+	if backing.GetLabels()[consts.ShareableLabel] != "true" {
+		return status.Errorf(codes.PermissionDenied,
+			"share %s backing %s %s/%s does not consent to be shared, it needs the label %s=true",
+			shareName, kind, backing.GetNamespace(), backing.GetName(), consts.ShareableLabel)
+	}
+	shareableBy, ok := backing.GetAnnotations()[consts.ShareableByAnnotation]
+	if !ok {
+		return nil
+	}
+	for _, name := range strings.Split(shareableBy, ",") {
+		if strings.TrimSpace(name) == shareName {
+			return nil
+		}
+	}
+	return status.Errorf(codes.PermissionDenied,
+		"share %s backing %s %s/%s only consents to be shared by %q in its %s annotation",
+		shareName, kind, backing.GetNamespace(), backing.GetName(), shareableBy, consts.ShareableByAnnotation)
+}
+
+// ValidateBackingResourceConsent gets the backing resource of the share to check the consent of its owner; a backing
+// resource which does not exist yet results in a NotFound error
+func ValidateBackingResourceConsent(kind consts.ResourceReferenceType, shareName, backingNamespace, backingName string) error {
+	initClient()
+	var backing metav1.Object
+	var err error
+	switch kind {
+	case consts.ResourceReferenceTypeSecret:
+		backing, err = GetSecret(backingNamespace, backingName)
+	default:
+		backing, err = GetConfigMap(backingNamespace, backingName)
+	}
+	if kerrors.IsNotFound(err) {
+		return status.Errorf(codes.NotFound,
+			"share %s backing %s %s/%s does not exist, its consent is checked once it does", shareName, kind, backingNamespace, backingName)
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal,
+			"share %s could not get backing %s %s/%s to check its consent: %s", shareName, kind, backingNamespace, backingName, err.Error())
+	}
+	return ValidateOwnerConsent(kind, shareName, backing)
+}
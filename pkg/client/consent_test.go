@@ -0,0 +1,82 @@
+package client
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func TestValidateBackingResourceConsent(t *testing.T) {
+	SetClient(fakekubeclientset.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "private"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "public",
+			Labels: map[string]string{consts.ShareableLabel: "true"}}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "restricted",
+			Labels:      map[string]string{consts.ShareableLabel: "true"},
+			Annotations: map[string]string{consts.ShareableByAnnotation: "share1, share2"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "public",
+			Labels: map[string]string{consts.ShareableLabel: "true"}}},
+	))
+	for _, test := range []struct {
+		name         string
+		kind         consts.ResourceReferenceType
+		shareName    string
+		backingName  string
+		expectedCode codes.Code
+	}{
+		{
+			name:         "no consent",
+			kind:         consts.ResourceReferenceTypeSecret,
+			shareName:    "share1",
+			backingName:  "private",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "consent to any share",
+			kind:         consts.ResourceReferenceTypeSecret,
+			shareName:    "share1",
+			backingName:  "public",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "consent to a named share",
+			kind:         consts.ResourceReferenceTypeSecret,
+			shareName:    "share2",
+			backingName:  "restricted",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "consent to other shares",
+			kind:         consts.ResourceReferenceTypeSecret,
+			shareName:    "share3",
+			backingName:  "restricted",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "configmap consent",
+			kind:         consts.ResourceReferenceTypeConfigMap,
+			shareName:    "share1",
+			backingName:  "public",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "backing resource not created yet",
+			kind:         consts.ResourceReferenceTypeConfigMap,
+			shareName:    "share1",
+			backingName:  "missing",
+			expectedCode: codes.NotFound,
+		},
+	} {
+		err := ValidateBackingResourceConsent(test.kind, test.shareName, "ns", test.backingName)
+		if status.Code(err) != test.expectedCode {
+			t.Errorf("testcase %s: expected code %s got %v", test.name, test.expectedCode, err)
+		}
+	}
+}
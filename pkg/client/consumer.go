@@ -0,0 +1,71 @@
+package client
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// ConsumerSelectors parses the consumer selector annotations of a share, returning nil for a selector that is not set
+func ConsumerSelectors(annotations map[string]string) (labels.Selector, labels.Selector, error) {
+	var nsSelector, podSelector labels.Selector
+	var err error
+	if s, ok := annotations[consts.ConsumerNamespaceSelectorAnnotation]; ok {
+		if nsSelector, err = labels.Parse(s); err != nil {
+			return nil, nil, err
+		}
+	}
+	if s, ok := annotations[consts.ConsumerPodSelectorAnnotation]; ok {
+		if podSelector, err = labels.Parse(s); err != nil {
+			return nil, nil, err
+		}
+	}
+	return nsSelector, podSelector, nil
+}
+
+// ValidateConsumer checks the pod, and its namespace, against the consumer selector annotations of the given share.
+// When pod is nil and the share has a pod selector, the pod is retrieved from the API server; the webhook supplies
+// the pod as it may not exist yet.
+func ValidateConsumer(shareName string, annotations map[string]string, podNamespace, podName string, pod *corev1.Pod) error {
+	nsSelector, podSelector, err := ConsumerSelectors(annotations)
+	if err != nil {
+		return status.Errorf(codes.PermissionDenied,
+			"share %s has an invalid consumer selector: %s", shareName, err.Error())
+	}
+	if nsSelector == nil && podSelector == nil {
+		return nil
+	}
+	if nsSelector != nil {
+		ns, err := GetNamespace(podNamespace)
+		if err != nil {
+			return status.Errorf(codes.Internal,
+				"share %s podNamespace %s podName %s could not get namespace: %s", shareName, podNamespace, podName, err.Error())
+		}
+		if !nsSelector.Matches(labels.Set(ns.Labels)) {
+			return status.Errorf(codes.PermissionDenied,
+				"share %s podNamespace %s podName %s namespace labels do not match consumer selector %q",
+				shareName, podNamespace, podName, nsSelector.String())
+		}
+	}
+	if podSelector != nil {
+		if pod == nil {
+			pod, err = GetPod(podNamespace, podName)
+			if err != nil {
+				return status.Errorf(codes.Internal,
+					"share %s podNamespace %s podName %s could not get pod: %s", shareName, podNamespace, podName, err.Error())
+			}
+		}
+		if !podSelector.Matches(labels.Set(pod.Labels)) {
+			return status.Errorf(codes.PermissionDenied,
+				"share %s podNamespace %s podName %s pod labels do not match consumer selector %q",
+				shareName, podNamespace, podName, podSelector.String())
+		}
+	}
+	klog.V(4).Infof("share %s podNamespace %s podName %s passed consumer selectors", shareName, podNamespace, podName)
+	return nil
+}
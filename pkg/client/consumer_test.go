@@ -0,0 +1,90 @@
+package client
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func TestValidateConsumer(t *testing.T) {
+	SetClient(fakekubeclientset.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"env": "prod"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev", Labels: map[string]string{"env": "dev"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "build", Namespace: "prod", Labels: map[string]string{"team": "build"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod", Labels: map[string]string{"team": "web"}}},
+	))
+	selectors := map[string]string{
+		consts.ConsumerNamespaceSelectorAnnotation: "env=prod",
+		consts.ConsumerPodSelectorAnnotation:       "team in (build)",
+	}
+	for _, test := range []struct {
+		name         string
+		annotations  map[string]string
+		podNamespace string
+		podName      string
+		pod          *corev1.Pod
+		expectedCode codes.Code
+	}{
+		{
+			name:         "no selectors",
+			podNamespace: "dev",
+			podName:      "missing",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "matching namespace and pod",
+			annotations:  selectors,
+			podNamespace: "prod",
+			podName:      "build",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "pod labels do not match",
+			annotations:  selectors,
+			podNamespace: "prod",
+			podName:      "web",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "namespace labels do not match",
+			annotations:  map[string]string{consts.ConsumerNamespaceSelectorAnnotation: "env=prod"},
+			podNamespace: "dev",
+			podName:      "build",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "supplied pod is used instead of the API",
+			annotations:  selectors,
+			podNamespace: "prod",
+			podName:      "new",
+			pod:          &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "new", Labels: map[string]string{"team": "build"}}},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "pod not found",
+			annotations:  selectors,
+			podNamespace: "prod",
+			podName:      "missing",
+			expectedCode: codes.Internal,
+		},
+		{
+			name:         "invalid selector",
+			annotations:  map[string]string{consts.ConsumerPodSelectorAnnotation: "team in build"},
+			podNamespace: "prod",
+			podName:      "build",
+			expectedCode: codes.PermissionDenied,
+		},
+	} {
+		err := ValidateConsumer("share1", test.annotations, test.podNamespace, test.podName, test.pod)
+		if status.Code(err) != test.expectedCode {
+			t.Errorf("testcase %s: expected code %s got %v", test.name, test.expectedCode, err)
+		}
+	}
+}
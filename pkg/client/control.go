@@ -0,0 +1,17 @@
+package client
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// ValidateNotRevoked returns a PermissionDenied error when the share carries a revoke-all token
+func ValidateNotRevoked(shareName string, annotations map[string]string) error {
+	if token := annotations[consts.RevokeAllAnnotation]; len(token) > 0 {
+		return status.Errorf(codes.PermissionDenied, "share %s access is revoked for every consumer by the %s token %q",
+			shareName, consts.RevokeAllAnnotation, token)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// Deprecation is the deprecation of a share, with the message reported to its consumers and the time after which it
+// behaves as revoked, if any
+type Deprecation struct {
+	Message  string
+	SunsetAt *time.Time
+}
+
+// String describes the deprecation for the events and warnings reported to the consumers of the share
+func (d *Deprecation) String() string {
+	description := "it is deprecated"
+	if len(d.Message) > 0 {
+		description = fmt.Sprintf("%s: %s", description, d.Message)
+	}
+	if d.SunsetAt != nil {
+		description = fmt.Sprintf("%s; it can no longer be consumed after %s", description, d.SunsetAt.Format(time.RFC3339))
+	}
+	return description
+}
+
+// ShareDeprecation parses the deprecation annotations of a share, returning nil when the share is not deprecated; a
+// share with a sunset time is deprecated even without a message
+func ShareDeprecation(annotations map[string]string) (*Deprecation, error) {
+	message, deprecated := annotations[consts.DeprecatedAnnotation]
+	s, sunset := annotations[consts.SunsetAtAnnotation]
+	if !deprecated && !sunset {
+		return nil, nil
+	}
+	deprecation := &Deprecation{Message: strings.TrimSpace(message)}
+	if sunset {
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %s", consts.SunsetAtAnnotation, err.Error())
+		}
+		deprecation.SunsetAt = &t
+	}
+	return deprecation, nil
+}
+
+// NextShareSunset returns the sunset time of the share, when it is after now
+func NextShareSunset(annotations map[string]string, now time.Time) (time.Time, bool) {
+	deprecation, err := ShareDeprecation(annotations)
+	if err != nil || deprecation == nil || deprecation.SunsetAt == nil || !deprecation.SunsetAt.After(now) {
+		return time.Time{}, false
+	}
+	return *deprecation.SunsetAt, true
+}
+
+// ValidateNotSunset returns a PermissionDenied error when the share is deprecated and its sunset time has passed
+func ValidateNotSunset(shareName string, annotations map[string]string, now time.Time) error {
+	deprecation, err := ShareDeprecation(annotations)
+	if err != nil {
+		return status.Errorf(codes.PermissionDenied, "share %s has an %s", shareName, err.Error())
+	}
+	if deprecation != nil && deprecation.SunsetAt != nil && !now.Before(*deprecation.SunsetAt) {
+		return status.Errorf(codes.PermissionDenied, "share %s is deprecated and was sunset at %s",
+			shareName, deprecation.SunsetAt.Format(time.RFC3339))
+	}
+	return nil
+}
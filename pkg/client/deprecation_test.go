@@ -0,0 +1,90 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func TestShareDeprecation(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	annotations := map[string]string{
+		consts.DeprecatedAnnotation: "use share2 instead",
+		consts.SunsetAtAnnotation:   "2026-02-01T00:00:00Z",
+	}
+	for _, test := range []struct {
+		name               string
+		annotations        map[string]string
+		now                time.Time
+		expectedDeprecated bool
+		expectedCode       codes.Code
+	}{
+		{
+			name:         "not deprecated",
+			now:          now,
+			expectedCode: codes.OK,
+		},
+		{
+			name:               "deprecated without sunset",
+			annotations:        map[string]string{consts.DeprecatedAnnotation: "use share2 instead"},
+			now:                now,
+			expectedDeprecated: true,
+			expectedCode:       codes.OK,
+		},
+		{
+			name:               "sunset not reached",
+			annotations:        annotations,
+			now:                now,
+			expectedDeprecated: true,
+			expectedCode:       codes.OK,
+		},
+		{
+			name:               "sunset passed",
+			annotations:        annotations,
+			now:                now.Add(31 * 24 * time.Hour),
+			expectedDeprecated: true,
+			expectedCode:       codes.PermissionDenied,
+		},
+		{
+			name:               "sunset without message",
+			annotations:        map[string]string{consts.SunsetAtAnnotation: "2025-12-01T00:00:00Z"},
+			now:                now,
+			expectedDeprecated: true,
+			expectedCode:       codes.PermissionDenied,
+		},
+		{
+			name:         "invalid sunset",
+			annotations:  map[string]string{consts.DeprecatedAnnotation: "", consts.SunsetAtAnnotation: "next month"},
+			now:          now,
+			expectedCode: codes.PermissionDenied,
+		},
+	} {
+		deprecation, _ := ShareDeprecation(test.annotations)
+		if (deprecation != nil) != test.expectedDeprecated {
+			t.Errorf("testcase %s: expected deprecated %v got %v", test.name, test.expectedDeprecated, deprecation)
+		}
+		err := ValidateNotSunset("share1", test.annotations, test.now)
+		if status.Code(err) != test.expectedCode {
+			t.Errorf("testcase %s: expected code %s got %v", test.name, test.expectedCode, err)
+		}
+	}
+
+	deprecation, err := ShareDeprecation(annotations)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if expected := "it is deprecated: use share2 instead; it can no longer be consumed after 2026-02-01T00:00:00Z"; deprecation.String() != expected {
+		t.Errorf("expected description %q got %q", expected, deprecation.String())
+	}
+	next, ok := NextShareSunset(annotations, now)
+	if !ok || !next.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected next sunset to be the share's, got %v %v", next, ok)
+	}
+	if _, ok = NextShareSunset(annotations, now.Add(60*24*time.Hour)); ok {
+		t.Errorf("expected no upcoming sunset")
+	}
+}
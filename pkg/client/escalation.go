@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// ValidateUserCanGet checks with a SubjectAccessReview that the user, typically the one creating a share, can get
+// the backing resource of the share, so that nobody exposes through a share what they cannot read themselves; it
+// returns a PermissionDenied error when they cannot
+func ValidateUserCanGet(user *authenticationv1.UserInfo, kind consts.ResourceReferenceType, shareName, backingNamespace, backingName string) error {
+	if err := initClient(); err != nil {
+		return err
+	}
+	resource := "configmaps"
+	if kind == consts.ResourceReferenceTypeSecret {
+		resource = "secrets"
+	}
+	sar := subjectAccessReview(user, &authorizationv1.ResourceAttributes{
+		Verb:      "get",
+		Resource:  resource,
+		Name:      backingName,
+		Namespace: backingNamespace,
+	})
+	resp, err := kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+	if err != nil {
+		return status.Errorf(codes.Internal,
+			"subjectaccessreviews share %s user %s get %s %s/%s returned error: %s",
+			shareName, user.Username, kind, backingNamespace, backingName, err.Error())
+	}
+	if !resp.Status.Allowed {
+		return status.Errorf(codes.PermissionDenied,
+			"user %s cannot get %s %s/%s, and so cannot share it with share %s",
+			user.Username, kind, backingNamespace, backingName, shareName)
+	}
+	return nil
+}
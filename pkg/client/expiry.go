@@ -0,0 +1,96 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// ShareExpirations parses the expiry annotations of a share, returning the share wide expiry, if any, and the per
+// namespace grant expirations
+func ShareExpirations(annotations map[string]string) (*time.Time, map[string]time.Time, error) {
+	var expiresAt *time.Time
+	if s, ok := annotations[consts.ExpiresAtAnnotation]; ok {
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s annotation: %s", consts.ExpiresAtAnnotation, err.Error())
+		}
+		expiresAt = &t
+	}
+	namespaces := map[string]time.Time{}
+	if s, ok := annotations[consts.NamespaceExpiresAtAnnotation]; ok {
+		for _, grant := range strings.Split(s, ",") {
+			if len(strings.TrimSpace(grant)) == 0 {
+				continue
+			}
+			parts := strings.SplitN(grant, "=", 2)
+			if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 {
+				return nil, nil, fmt.Errorf("invalid %s annotation: grant %q is not of the form namespace=time",
+					consts.NamespaceExpiresAtAnnotation, grant)
+			}
+			t, err := time.Parse(time.RFC3339, strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid %s annotation: %s", consts.NamespaceExpiresAtAnnotation, err.Error())
+			}
+			namespaces[strings.TrimSpace(parts[0])] = t
+		}
+	}
+	return expiresAt, namespaces, nil
+}
+
+// ShareExpiry returns the time after which pods in the given namespace can no longer consume the share, which is the
+// earliest of the share wide expiry and the namespace's grant, and whether the share expires at all for the namespace
+func ShareExpiry(annotations map[string]string, namespace string) (time.Time, bool, error) {
+	expiresAt, namespaces, err := ShareExpirations(annotations)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	t, ok := namespaces[namespace]
+	if expiresAt != nil && (!ok || expiresAt.Before(t)) {
+		return *expiresAt, true, nil
+	}
+	return t, ok, nil
+}
+
+// NextShareExpiry returns the earliest expiry of the share, across the share wide expiry and all namespace grants,
+// that is after now
+func NextShareExpiry(annotations map[string]string, now time.Time) (time.Time, bool) {
+	expiresAt, namespaces, err := ShareExpirations(annotations)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var next time.Time
+	found := false
+	candidates := []time.Time{}
+	if expiresAt != nil {
+		candidates = append(candidates, *expiresAt)
+	}
+	for _, t := range namespaces {
+		candidates = append(candidates, t)
+	}
+	for _, t := range candidates {
+		if t.After(now) && (!found || t.Before(next)) {
+			next = t
+			found = true
+		}
+	}
+	return next, found
+}
+
+// ValidateExpiry returns a PermissionDenied error when the share has expired for pods in the given namespace
+func ValidateExpiry(shareName string, annotations map[string]string, podNamespace string, now time.Time) error {
+	expiry, ok, err := ShareExpiry(annotations, podNamespace)
+	if err != nil {
+		return status.Errorf(codes.PermissionDenied, "share %s has an %s", shareName, err.Error())
+	}
+	if ok && !now.Before(expiry) {
+		return status.Errorf(codes.PermissionDenied,
+			"share %s access for podNamespace %s expired at %s", shareName, podNamespace, expiry.Format(time.RFC3339))
+	}
+	return nil
+}
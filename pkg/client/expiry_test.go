@@ -0,0 +1,81 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func TestShareExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	annotations := map[string]string{
+		consts.ExpiresAtAnnotation:          "2026-02-01T00:00:00Z",
+		consts.NamespaceExpiresAtAnnotation: "contractor=2026-01-15T00:00:00Z, pipeline=2026-03-01T00:00:00Z,done=2025-12-01T00:00:00Z",
+	}
+	for _, test := range []struct {
+		name         string
+		annotations  map[string]string
+		namespace    string
+		now          time.Time
+		expectedCode codes.Code
+	}{
+		{
+			name:         "no expiry",
+			namespace:    "ns1",
+			now:          now,
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "share wide expiry not reached",
+			annotations:  annotations,
+			namespace:    "ns1",
+			now:          now,
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "share wide expiry passed",
+			annotations:  annotations,
+			namespace:    "ns1",
+			now:          now.Add(31 * 24 * time.Hour),
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "namespace grant expired",
+			annotations:  annotations,
+			namespace:    "done",
+			now:          now,
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "later namespace grant does not extend the share wide expiry",
+			annotations:  annotations,
+			namespace:    "pipeline",
+			now:          now.Add(40 * 24 * time.Hour),
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "invalid expiry",
+			annotations:  map[string]string{consts.ExpiresAtAnnotation: "tomorrow"},
+			namespace:    "ns1",
+			now:          now,
+			expectedCode: codes.PermissionDenied,
+		},
+	} {
+		err := ValidateExpiry("share1", test.annotations, test.namespace, test.now)
+		if status.Code(err) != test.expectedCode {
+			t.Errorf("testcase %s: expected code %s got %v", test.name, test.expectedCode, err)
+		}
+	}
+
+	next, ok := NextShareExpiry(annotations, now)
+	if !ok || !next.Equal(time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected next expiry to be the contractor grant, got %v %v", next, ok)
+	}
+	if _, ok = NextShareExpiry(annotations, now.Add(60*24*time.Hour)); ok {
+		t.Errorf("expected no upcoming expiry")
+	}
+}
@@ -6,20 +6,33 @@ import (
 	"sync"
 
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	corelistersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
 	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
 	sharelisterv1alpha1 "github.com/openshift/client-go/sharedresource/listers/sharedresource/v1alpha1"
 )
 
+const (
+	// BackingResourceIndex is the name of the informer index on SharedConfigMaps and SharedSecrets
+	// keyed by the namespace/name of the ConfigMap or Secret they reference
+	BackingResourceIndex = "backingResource"
+	// AliasIndex is the name of the informer index on SharedConfigMaps and SharedSecrets keyed by their aliases
+	AliasIndex = "alias"
+)
+
 type Listers struct {
 	Secrets          sync.Map
 	ConfigMaps       sync.Map
 	SharedConfigMaps sharelisterv1alpha1.SharedConfigMapLister
 	SharedSecrets    sharelisterv1alpha1.SharedSecretLister
+
+	SharedConfigMapsIndexer cache.Indexer
+	SharedSecretsIndexer    cache.Indexer
 }
 
 var singleton Listers
@@ -44,6 +57,55 @@ func SetSharedSecretsLister(s sharelisterv1alpha1.SharedSecretLister) {
 	singleton.SharedSecrets = s
 }
 
+func SetSharedConfigMapsIndexer(i cache.Indexer) {
+	singleton.SharedConfigMapsIndexer = i
+}
+
+func SetSharedSecretsIndexer(i cache.Indexer) {
+	singleton.SharedSecretsIndexer = i
+}
+
+// BackingResourceIndexKey builds the BackingResourceIndex key for a ConfigMap or Secret
+func BackingResourceIndexKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// SharedConfigMapBackingResourceIndexFunc is the cache.IndexFunc for BackingResourceIndex on SharedConfigMaps
+func SharedConfigMapBackingResourceIndexFunc(obj interface{}) ([]string, error) {
+	share, ok := obj.(*sharev1alpha1.SharedConfigMap)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object vs. shared configmap: %T", obj)
+	}
+	return []string{BackingResourceIndexKey(share.Spec.ConfigMapRef.Namespace, share.Spec.ConfigMapRef.Name)}, nil
+}
+
+// SharedSecretBackingResourceIndexFunc is the cache.IndexFunc for BackingResourceIndex on SharedSecrets
+func SharedSecretBackingResourceIndexFunc(obj interface{}) ([]string, error) {
+	share, ok := obj.(*sharev1alpha1.SharedSecret)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object vs. shared secret: %T", obj)
+	}
+	return []string{BackingResourceIndexKey(share.Spec.SecretRef.Namespace, share.Spec.SecretRef.Name)}, nil
+}
+
+// SharedConfigMapAliasIndexFunc is the cache.IndexFunc for AliasIndex on SharedConfigMaps
+func SharedConfigMapAliasIndexFunc(obj interface{}) ([]string, error) {
+	share, ok := obj.(*sharev1alpha1.SharedConfigMap)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object vs. shared configmap: %T", obj)
+	}
+	return ShareAliases(share.Annotations), nil
+}
+
+// SharedSecretAliasIndexFunc is the cache.IndexFunc for AliasIndex on SharedSecrets
+func SharedSecretAliasIndexFunc(obj interface{}) ([]string, error) {
+	share, ok := obj.(*sharev1alpha1.SharedSecret)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object vs. shared secret: %T", obj)
+	}
+	return ShareAliases(share.Annotations), nil
+}
+
 func GetListers() *Listers {
 	return &singleton
 }
@@ -98,20 +160,49 @@ func GetConfigMap(namespace, name string) (*corev1.ConfigMap, error) {
 	return nil, fmt.Errorf("no configmap lister or kubeClient available for namespace %s", namespace)
 }
 
+// RefetchSecret gets the secret from the API server, bypassing the listers, for when the content has to be current
+func RefetchSecret(namespace, name string) (*corev1.Secret, error) {
+	if kubeClient == nil {
+		return GetSecret(namespace, name)
+	}
+	return kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// RefetchConfigMap gets the configmap from the API server, bypassing the listers, for when the content has to be
+// current
+func RefetchConfigMap(namespace, name string) (*corev1.ConfigMap, error) {
+	if kubeClient == nil {
+		return GetConfigMap(namespace, name)
+	}
+	return kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
 func GetSharedSecret(name string) *sharev1alpha1.SharedSecret {
+	s, _ := FetchSharedSecret(name)
+	return s
+}
+
+// FetchSharedSecret returns the SharedSecret from the lister or, when the lister does not have it, from the API
+// server; it returns nil without error when the SharedSecret does not exist, or cannot be looked up without a share
+// client, and the error of the API server otherwise
+func FetchSharedSecret(name string) (*sharev1alpha1.SharedSecret, error) {
 	if singleton.SharedSecrets != nil {
 		s, err := singleton.SharedSecrets.Get(name)
 		if err == nil {
-			return s
+			return s, nil
 		}
 	}
-	if shareClient != nil {
-		s, err := shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), name, metav1.GetOptions{})
-		if err == nil {
-			return s
-		}
+	if shareClient == nil {
+		return nil, nil
+	}
+	s, err := shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), name, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return s, nil
 }
 
 func ListSharedSecrets() map[string]*sharev1alpha1.SharedSecret {
@@ -136,19 +227,31 @@ func ListSharedSecrets() map[string]*sharev1alpha1.SharedSecret {
 }
 
 func GetSharedConfigMap(name string) *sharev1alpha1.SharedConfigMap {
+	s, _ := FetchSharedConfigMap(name)
+	return s
+}
+
+// FetchSharedConfigMap returns the SharedConfigMap from the lister or, when the lister does not have it, from the
+// API server; it returns nil without error when the SharedConfigMap does not exist, or cannot be looked up without a
+// share client, and the error of the API server otherwise
+func FetchSharedConfigMap(name string) (*sharev1alpha1.SharedConfigMap, error) {
 	if singleton.SharedConfigMaps != nil {
 		s, err := singleton.SharedConfigMaps.Get(name)
 		if err == nil {
-			return s
+			return s, nil
 		}
 	}
-	if shareClient != nil {
-		s, err := shareClient.SharedresourceV1alpha1().SharedConfigMaps().Get(context.TODO(), name, metav1.GetOptions{})
-		if err == nil {
-			return s
-		}
+	if shareClient == nil {
+		return nil, nil
+	}
+	s, err := shareClient.SharedresourceV1alpha1().SharedConfigMaps().Get(context.TODO(), name, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return nil, nil
 	}
-	return nil
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 func ListSharedConfigMap() map[string]*sharev1alpha1.SharedConfigMap {
@@ -171,3 +274,51 @@ func ListSharedConfigMap() map[string]*sharev1alpha1.SharedConfigMap {
 	}
 	return ret
 }
+
+// ListSharedConfigMapsForConfigMap returns the SharedConfigMaps referencing the given ConfigMap, using the
+// BackingResourceIndex when the informer has been set up, and otherwise falling back to scanning all of them
+func ListSharedConfigMapsForConfigMap(namespace, name string) []*sharev1alpha1.SharedConfigMap {
+	ret := []*sharev1alpha1.SharedConfigMap{}
+	if singleton.SharedConfigMapsIndexer != nil {
+		objs, err := singleton.SharedConfigMapsIndexer.ByIndex(BackingResourceIndex, BackingResourceIndexKey(namespace, name))
+		if err == nil {
+			for _, obj := range objs {
+				if scm, ok := obj.(*sharev1alpha1.SharedConfigMap); ok {
+					ret = append(ret, scm)
+				}
+			}
+			return ret
+		}
+		klog.V(4).Infof("ListSharedConfigMapsForConfigMap index lookup for %s/%s got error: %s", namespace, name, err.Error())
+	}
+	for _, scm := range ListSharedConfigMap() {
+		if scm.Spec.ConfigMapRef.Namespace == namespace && scm.Spec.ConfigMapRef.Name == name {
+			ret = append(ret, scm)
+		}
+	}
+	return ret
+}
+
+// ListSharedSecretsForSecret returns the SharedSecrets referencing the given Secret, using the
+// BackingResourceIndex when the informer has been set up, and otherwise falling back to scanning all of them
+func ListSharedSecretsForSecret(namespace, name string) []*sharev1alpha1.SharedSecret {
+	ret := []*sharev1alpha1.SharedSecret{}
+	if singleton.SharedSecretsIndexer != nil {
+		objs, err := singleton.SharedSecretsIndexer.ByIndex(BackingResourceIndex, BackingResourceIndexKey(namespace, name))
+		if err == nil {
+			for _, obj := range objs {
+				if ss, ok := obj.(*sharev1alpha1.SharedSecret); ok {
+					ret = append(ret, ss)
+				}
+			}
+			return ret
+		}
+		klog.V(4).Infof("ListSharedSecretsForSecret index lookup for %s/%s got error: %s", namespace, name, err.Error())
+	}
+	for _, ss := range ListSharedSecrets() {
+		if ss.Spec.SecretRef.Namespace == namespace && ss.Spec.SecretRef.Name == name {
+			ret = append(ret, ss)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+)
+
+func TestListSharesForBackingResource(t *testing.T) {
+	cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		BackingResourceIndex: SharedConfigMapBackingResourceIndexFunc,
+	})
+	sIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		BackingResourceIndex: SharedSecretBackingResourceIndexFunc,
+	})
+	for _, name := range []string{"share1", "share2"} {
+		cmIndexer.Add(&sharev1alpha1.SharedConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: sharev1alpha1.SharedConfigMapSpec{
+				ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "ns", Name: "cm"},
+			},
+		})
+		sIndexer.Add(&sharev1alpha1.SharedSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: sharev1alpha1.SharedSecretSpec{
+				SecretRef: sharev1alpha1.SharedSecretReference{Namespace: "ns", Name: "secret"},
+			},
+		})
+	}
+	cmIndexer.Add(&sharev1alpha1.SharedConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "share3"},
+		Spec: sharev1alpha1.SharedConfigMapSpec{
+			ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "other", Name: "cm"},
+		},
+	})
+	SetSharedConfigMapsIndexer(cmIndexer)
+	SetSharedSecretsIndexer(sIndexer)
+	defer SetSharedConfigMapsIndexer(nil)
+	defer SetSharedSecretsIndexer(nil)
+
+	if l := ListSharedConfigMapsForConfigMap("ns", "cm"); len(l) != 2 {
+		t.Errorf("expected 2 shared configmaps for ns/cm, got %d", len(l))
+	}
+	if l := ListSharedConfigMapsForConfigMap("other", "cm"); len(l) != 1 || l[0].Name != "share3" {
+		t.Errorf("expected share3 for other/cm, got %#v", l)
+	}
+	if l := ListSharedConfigMapsForConfigMap("ns", "missing"); len(l) != 0 {
+		t.Errorf("expected no shared configmaps for ns/missing, got %d", len(l))
+	}
+	if l := ListSharedSecretsForSecret("ns", "secret"); len(l) != 2 {
+		t.Errorf("expected 2 shared secrets for ns/secret, got %d", len(l))
+	}
+	if l := ListSharedSecretsForSecret("other", "secret"); len(l) != 0 {
+		t.Errorf("expected no shared secrets for other/secret, got %d", len(l))
+	}
+}
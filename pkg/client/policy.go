@@ -0,0 +1,93 @@
+package client
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// ValidateSharePolicy checks the backing resource of the share against the administrator's share policy, returning
+// a PermissionDenied error when the policy does not allow it to be shared. A backing Secret which does not exist yet
+// passes the Secret type rules, it is checked again when a volume is mounted.
+func ValidateSharePolicy(policy config.SharePolicy, kind consts.ResourceReferenceType, shareName, backingNamespace, backingName string) error {
+	if err := validateNamespacePolicy(policy, shareName, backingNamespace); err != nil {
+		return err
+	}
+	if kind != consts.ResourceReferenceTypeSecret || len(policy.DeniedSecretTypes) == 0 {
+		return nil
+	}
+	initClient()
+	secret, err := GetSecret(backingNamespace, backingName)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal,
+			"share %s could not get Secret %s/%s to check its type against the share policy: %s", shareName, backingNamespace, backingName, err.Error())
+	}
+	for _, deniedType := range policy.DeniedSecretTypes {
+		if string(secret.Type) == deniedType {
+			return status.Errorf(codes.PermissionDenied,
+				"share %s backing Secret %s/%s is of type %s, which the share policy does not allow to share",
+				shareName, backingNamespace, backingName, secret.Type)
+		}
+	}
+	return nil
+}
+
+func validateNamespacePolicy(policy config.SharePolicy, shareName, backingNamespace string) error {
+	var allowedSelector, deniedSelector labels.Selector
+	var err error
+	if len(policy.AllowedNamespaceSelector) > 0 {
+		if allowedSelector, err = labels.Parse(policy.AllowedNamespaceSelector); err != nil {
+			return status.Errorf(codes.PermissionDenied,
+				"share %s cannot be checked against the share policy, its allowed namespace selector is invalid: %s", shareName, err.Error())
+		}
+	}
+	if len(policy.DeniedNamespaceSelector) > 0 {
+		if deniedSelector, err = labels.Parse(policy.DeniedNamespaceSelector); err != nil {
+			return status.Errorf(codes.PermissionDenied,
+				"share %s cannot be checked against the share policy, its denied namespace selector is invalid: %s", shareName, err.Error())
+		}
+	}
+	namespaceLabels := labels.Set{}
+	if policy.HasNamespaceSelectors() {
+		ns, err := GetNamespace(backingNamespace)
+		if err != nil {
+			return status.Errorf(codes.Internal,
+				"share %s could not get backing namespace %s to check it against the share policy: %s", shareName, backingNamespace, err.Error())
+		}
+		namespaceLabels = labels.Set(ns.Labels)
+	}
+
+	for _, denied := range policy.DeniedNamespaces {
+		if denied == backingNamespace {
+			return status.Errorf(codes.PermissionDenied,
+				"share %s backing namespace %s is denied by the share policy", shareName, backingNamespace)
+		}
+	}
+	if deniedSelector != nil && deniedSelector.Matches(namespaceLabels) {
+		return status.Errorf(codes.PermissionDenied,
+			"share %s backing namespace %s labels match the denied namespace selector %q of the share policy",
+			shareName, backingNamespace, deniedSelector.String())
+	}
+	if !policy.HasNamespaceAllowRules() {
+		return nil
+	}
+	for _, allowed := range policy.AllowedNamespaces {
+		if allowed == backingNamespace {
+			return nil
+		}
+	}
+	if allowedSelector != nil && allowedSelector.Matches(namespaceLabels) {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied,
+		"share %s backing namespace %s is neither in the allowed namespaces nor matches the allowed namespace selector of the share policy",
+		shareName, backingNamespace)
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func TestValidateSharePolicy(t *testing.T) {
+	SetClient(fakekubeclientset.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared", Labels: map[string]string{"sharing": "allowed"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", Labels: map[string]string{"sharing": "allowed", "system": "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "tls"}, Type: corev1.SecretTypeTLS},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "token"}, Type: corev1.SecretTypeServiceAccountToken},
+	))
+	selectors := config.SharePolicy{
+		AllowedNamespaceSelector: "sharing=allowed",
+		DeniedNamespaceSelector:  "system=true",
+	}
+	for _, test := range []struct {
+		name             string
+		policy           config.SharePolicy
+		kind             consts.ResourceReferenceType
+		backingNamespace string
+		backingName      string
+		expectedCode     codes.Code
+	}{
+		{
+			name:             "empty policy",
+			kind:             consts.ResourceReferenceTypeSecret,
+			backingNamespace: "kube-system",
+			backingName:      "anything",
+			expectedCode:     codes.OK,
+		},
+		{
+			name:             "allowed namespace",
+			policy:           config.SharePolicy{AllowedNamespaces: []string{"shared"}},
+			kind:             consts.ResourceReferenceTypeConfigMap,
+			backingNamespace: "shared",
+			expectedCode:     codes.OK,
+		},
+		{
+			name:             "namespace not allowed",
+			policy:           config.SharePolicy{AllowedNamespaces: []string{"shared"}},
+			kind:             consts.ResourceReferenceTypeConfigMap,
+			backingNamespace: "other",
+			expectedCode:     codes.PermissionDenied,
+		},
+		{
+			name:             "denied namespace",
+			policy:           config.SharePolicy{DeniedNamespaces: []string{"kube-system"}},
+			kind:             consts.ResourceReferenceTypeConfigMap,
+			backingNamespace: "kube-system",
+			expectedCode:     codes.PermissionDenied,
+		},
+		{
+			name:             "namespace labels allowed",
+			policy:           selectors,
+			kind:             consts.ResourceReferenceTypeConfigMap,
+			backingNamespace: "shared",
+			expectedCode:     codes.OK,
+		},
+		{
+			name:             "denied namespace labels take precedence",
+			policy:           selectors,
+			kind:             consts.ResourceReferenceTypeConfigMap,
+			backingNamespace: "kube-system",
+			expectedCode:     codes.PermissionDenied,
+		},
+		{
+			name:             "namespace labels not allowed",
+			policy:           selectors,
+			kind:             consts.ResourceReferenceTypeConfigMap,
+			backingNamespace: "other",
+			expectedCode:     codes.PermissionDenied,
+		},
+		{
+			name:             "namespace not found",
+			policy:           selectors,
+			kind:             consts.ResourceReferenceTypeConfigMap,
+			backingNamespace: "missing",
+			expectedCode:     codes.Internal,
+		},
+		{
+			name:             "invalid selector",
+			policy:           config.SharePolicy{AllowedNamespaceSelector: "sharing in allowed"},
+			kind:             consts.ResourceReferenceTypeConfigMap,
+			backingNamespace: "shared",
+			expectedCode:     codes.PermissionDenied,
+		},
+		{
+			name:             "secret type allowed",
+			policy:           config.SharePolicy{DeniedSecretTypes: []string{string(corev1.SecretTypeServiceAccountToken)}},
+			kind:             consts.ResourceReferenceTypeSecret,
+			backingNamespace: "shared",
+			backingName:      "tls",
+			expectedCode:     codes.OK,
+		},
+		{
+			name:             "secret type denied",
+			policy:           config.SharePolicy{DeniedSecretTypes: []string{string(corev1.SecretTypeServiceAccountToken)}},
+			kind:             consts.ResourceReferenceTypeSecret,
+			backingNamespace: "shared",
+			backingName:      "token",
+			expectedCode:     codes.PermissionDenied,
+		},
+		{
+			name:             "secret not created yet",
+			policy:           config.SharePolicy{DeniedSecretTypes: []string{string(corev1.SecretTypeServiceAccountToken)}},
+			kind:             consts.ResourceReferenceTypeSecret,
+			backingNamespace: "shared",
+			backingName:      "missing",
+			expectedCode:     codes.OK,
+		},
+	} {
+		err := ValidateSharePolicy(test.policy, test.kind, "share1", test.backingNamespace, test.backingName)
+		if status.Code(err) != test.expectedCode {
+			t.Errorf("testcase %s: expected code %s got %v", test.name, test.expectedCode, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+/*
+The SubjectAccessReview cache keeps the result of the "use" permission check for a given share, pod namespace and
+user, i.e. the pod's service account and its groups, so that the publish of many volumes for the same service
+account, as well as the share relist going through every volume on the node, do not each result in a
+SubjectAccessReview against the API server.
+
+Allowed and denied results have separate TTLs, and entries are invalidated early when the share is updated or
+deleted, or when RBAC objects that may grant "use" on shares change.  A generation counter is bumped on every
+invalidation so that a SubjectAccessReview that was in flight while an invalidation occurred does not store a
+possibly stale result.
+
+With both TTLs at zero, which is the default until ConfigureSARCache is called, the cache is disabled.
+*/
+
+type sarCacheKey struct {
+	shareName string
+	kind      consts.ResourceReferenceType
+	namespace string
+	user      string
+}
+
+func userCacheKey(user *authenticationv1.UserInfo) string {
+	groups := append([]string{}, user.Groups...)
+	sort.Strings(groups)
+	return user.Username + "|" + strings.Join(groups, ",")
+}
+
+type sarCacheEntry struct {
+	allowed bool
+	expires time.Time
+}
+
+type sarCache struct {
+	lock       sync.Mutex
+	entries    map[sarCacheKey]sarCacheEntry
+	generation uint64
+	allowedTTL time.Duration
+	deniedTTL  time.Duration
+	now        func() time.Time
+}
+
+var sarResults = newSARCache(0, 0)
+
+func newSARCache(allowedTTL, deniedTTL time.Duration) *sarCache {
+	return &sarCache{
+		entries:    map[sarCacheKey]sarCacheEntry{},
+		allowedTTL: allowedTTL,
+		deniedTTL:  deniedTTL,
+		now:        time.Now,
+	}
+}
+
+// ConfigureSARCache sets the TTLs of the node local SubjectAccessReview cache, dropping any cached entry.  A zero
+// TTL disables caching for that type of result.
+func ConfigureSARCache(allowedTTL, deniedTTL time.Duration) {
+	klog.V(2).Infof("configured SubjectAccessReview cache with allowed ttl %s and denied ttl %s", allowedTTL, deniedTTL)
+	sarResults.lock.Lock()
+	defer sarResults.lock.Unlock()
+	sarResults.allowedTTL = allowedTTL
+	sarResults.deniedTTL = deniedTTL
+	sarResults.entries = map[sarCacheKey]sarCacheEntry{}
+	sarResults.generation++
+}
+
+// InvalidateSARCacheForShare drops the cached permission checks of the given share
+func InvalidateSARCacheForShare(kind consts.ResourceReferenceType, shareName string) {
+	klog.V(4).Infof("invalidating SubjectAccessReview cache for %s share %s", kind, shareName)
+	sarResults.invalidate(func(key sarCacheKey) bool {
+		return key.kind == kind && key.shareName == shareName
+	})
+}
+
+// InvalidateSARCacheForNamespace drops the cached permission checks for service accounts in the given namespace
+func InvalidateSARCacheForNamespace(namespace string) {
+	klog.V(4).Infof("invalidating SubjectAccessReview cache for namespace %s", namespace)
+	sarResults.invalidate(func(key sarCacheKey) bool {
+		return key.namespace == namespace
+	})
+}
+
+// InvalidateSARCache drops all cached permission checks
+func InvalidateSARCache() {
+	klog.V(4).Info("invalidating SubjectAccessReview cache")
+	sarResults.invalidate(func(key sarCacheKey) bool {
+		return true
+	})
+}
+
+func (c *sarCache) enabled() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.allowedTTL > 0 || c.deniedTTL > 0
+}
+
+// get returns the cached result, whether an unexpired result was found, and the current generation to be
+// supplied to a subsequent set
+func (c *sarCache) get(key sarCacheKey) (bool, bool, uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return false, false, c.generation
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return false, false, c.generation
+	}
+	return entry.allowed, true, c.generation
+}
+
+func (c *sarCache) set(key sarCacheKey, allowed bool, generation uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if generation != c.generation {
+		// an invalidation occurred while the SubjectAccessReview was in flight
+		return
+	}
+	ttl := c.deniedTTL
+	if allowed {
+		ttl = c.allowedTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.entries[key] = sarCacheEntry{allowed: allowed, expires: c.now().Add(ttl)}
+}
+
+func (c *sarCache) invalidate(match func(key sarCacheKey) bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key := range c.entries {
+		if match(key) {
+			delete(c.entries, key)
+		}
+	}
+	c.generation++
+}
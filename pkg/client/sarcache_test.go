@@ -0,0 +1,125 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	fakekubetesting "k8s.io/client-go/testing"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func fakeSARClient(allowed *bool, calls *int) *fakekubeclientset.Clientset {
+	kubeClient := fakekubeclientset.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		*calls++
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: *allowed}}, nil
+	})
+	return kubeClient
+}
+
+func TestExecuteSARCache(t *testing.T) {
+	allowed := true
+	calls := 0
+	SetClient(fakeSARClient(&allowed, &calls))
+	ConfigureSARCache(time.Minute, time.Minute)
+	defer ConfigureSARCache(0, 0)
+	now := time.Now()
+	sarResults.now = func() time.Time { return now }
+	defer func() { sarResults.now = time.Now }()
+
+	for i := 0; i < 3; i++ {
+		if a, err := ExecuteSAR("share1", "ns1", "pod1", "sa1", consts.ResourceReferenceTypeSecret); !a || err != nil {
+			t.Fatalf("expected allowed, got %v %v", a, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 subjectaccessreview, got %d", calls)
+	}
+
+	// different service account, kind and namespace are distinct entries
+	ExecuteSAR("share1", "ns1", "pod1", "sa2", consts.ResourceReferenceTypeSecret)
+	ExecuteSAR("share1", "ns1", "pod1", "sa1", consts.ResourceReferenceTypeConfigMap)
+	ExecuteSAR("share1", "ns2", "pod1", "sa1", consts.ResourceReferenceTypeSecret)
+	if calls != 4 {
+		t.Fatalf("expected 4 subjectaccessreviews, got %d", calls)
+	}
+
+	// invalidation of the share drops only its entries of that kind
+	allowed = false
+	InvalidateSARCacheForShare(consts.ResourceReferenceTypeSecret, "share1")
+	a, err := ExecuteSAR("share1", "ns1", "pod1", "sa1", consts.ResourceReferenceTypeSecret)
+	if a || status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected denied, got %v %v", a, err)
+	}
+	if calls != 5 {
+		t.Fatalf("expected 5 subjectaccessreviews, got %d", calls)
+	}
+	if a, _ := ExecuteSAR("share1", "ns1", "pod1", "sa1", consts.ResourceReferenceTypeConfigMap); !a {
+		t.Fatalf("expected cached allowed result for configmap share")
+	}
+
+	// denied results are cached as well
+	a, err = ExecuteSAR("share1", "ns1", "pod2", "sa1", consts.ResourceReferenceTypeSecret)
+	if a || status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected cached denied, got %v %v", a, err)
+	}
+	if calls != 5 {
+		t.Fatalf("expected 5 subjectaccessreviews, got %d", calls)
+	}
+
+	// namespace invalidation
+	InvalidateSARCacheForNamespace("ns2")
+	ExecuteSAR("share1", "ns2", "pod1", "sa1", consts.ResourceReferenceTypeSecret)
+	if calls != 6 {
+		t.Fatalf("expected 6 subjectaccessreviews, got %d", calls)
+	}
+
+	// expiry
+	now = now.Add(2 * time.Minute)
+	ExecuteSAR("share1", "ns1", "pod1", "sa2", consts.ResourceReferenceTypeSecret)
+	if calls != 7 {
+		t.Fatalf("expected 7 subjectaccessreviews, got %d", calls)
+	}
+}
+
+func TestExecuteSARCacheSeparateTTLs(t *testing.T) {
+	allowed := false
+	calls := 0
+	SetClient(fakeSARClient(&allowed, &calls))
+	ConfigureSARCache(time.Minute, 0)
+	defer ConfigureSARCache(0, 0)
+
+	ExecuteSAR("share1", "ns1", "pod1", "sa1", consts.ResourceReferenceTypeSecret)
+	ExecuteSAR("share1", "ns1", "pod1", "sa1", consts.ResourceReferenceTypeSecret)
+	if calls != 2 {
+		t.Fatalf("expected denied results to not be cached, got %d subjectaccessreviews", calls)
+	}
+	allowed = true
+	ExecuteSAR("share1", "ns1", "pod1", "sa1", consts.ResourceReferenceTypeSecret)
+	ExecuteSAR("share1", "ns1", "pod1", "sa1", consts.ResourceReferenceTypeSecret)
+	if calls != 3 {
+		t.Fatalf("expected allowed results to be cached, got %d subjectaccessreviews", calls)
+	}
+}
+
+func TestSARCacheStaleGeneration(t *testing.T) {
+	c := newSARCache(time.Minute, time.Minute)
+	key := sarCacheKey{shareName: "share1", kind: consts.ResourceReferenceTypeSecret, namespace: "ns1", user: "system:serviceaccount:ns1:sa1|system:serviceaccounts"}
+	_, found, generation := c.get(key)
+	if found {
+		t.Fatalf("unexpected entry found")
+	}
+	// an invalidation while the SubjectAccessReview is in flight means its result is not stored
+	c.invalidate(func(key sarCacheKey) bool { return true })
+	c.set(key, true, generation)
+	if _, found, _ = c.get(key); found {
+		t.Fatalf("unexpected entry stored with a stale generation")
+	}
+}
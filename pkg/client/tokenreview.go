@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// PodUIDExtraKey is the user extra field set by the API server on service account tokens bound to a pod
+	PodUIDExtraKey = "authentication.kubernetes.io/pod-uid"
+	// PodNameExtraKey is the user extra field set by the API server on service account tokens bound to a pod
+	PodNameExtraKey = "authentication.kubernetes.io/pod-name"
+)
+
+// ServiceAccountUser returns the user info we assume for a pod's service account when we do not have a token from the
+// kubelet to prove the pod's identity
+func ServiceAccountUser(namespace, serviceAccount string) *authenticationv1.UserInfo {
+	return &authenticationv1.UserInfo{
+		Username: fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccount),
+		// adding the standard SA group to allow for RBAC based on that group; otherwise,
+		// the SAR will not pass
+		Groups: []string{"system:serviceaccounts"},
+	}
+}
+
+// ValidatePodServiceAccountToken runs a TokenReview on the service account token the kubelet supplied for a pod, and
+// verifies the token belongs to the pod's service account and is bound to the pod, returning the validated user info
+func ValidatePodServiceAccountToken(token, audience, podNamespace, podName, podUID, podSA string) (*authenticationv1.UserInfo, error) {
+	err := initClient()
+	if err != nil {
+		return nil, err
+	}
+	tr := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token: token,
+		},
+	}
+	if len(audience) > 0 {
+		tr.Spec.Audiences = []string{audience}
+	}
+	resp, err := kubeClient.AuthenticationV1().TokenReviews().Create(context.TODO(), tr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"tokenreviews podNamespace %s podName %s podSA %s returned error: %s", podNamespace, podName, podSA, err.Error())
+	}
+	if !resp.Status.Authenticated {
+		return nil, status.Errorf(codes.Unauthenticated,
+			"tokenreviews podNamespace %s podName %s podSA %s did not authenticate the token: %s", podNamespace, podName, podSA, resp.Status.Error)
+	}
+	user := resp.Status.User
+	expectedUser := ServiceAccountUser(podNamespace, podSA).Username
+	if user.Username != expectedUser {
+		return nil, status.Errorf(codes.Unauthenticated,
+			"tokenreviews podNamespace %s podName %s podSA %s authenticated user %s instead of %s", podNamespace, podName, podSA, user.Username, expectedUser)
+	}
+	if !extraContains(user, PodUIDExtraKey, podUID) {
+		return nil, status.Errorf(codes.Unauthenticated,
+			"tokenreviews podNamespace %s podName %s podSA %s token is not bound to pod uid %s", podNamespace, podName, podSA, podUID)
+	}
+	if len(user.Extra[PodNameExtraKey]) > 0 && !extraContains(user, PodNameExtraKey, podName) {
+		return nil, status.Errorf(codes.Unauthenticated,
+			"tokenreviews podNamespace %s podName %s podSA %s token is not bound to pod name %s", podNamespace, podName, podSA, podName)
+	}
+	klog.V(4).Infof("tokenreviews podNamespace %s podName %s podSA %s authenticated user %s groups %v", podNamespace, podName, podSA, user.Username, user.Groups)
+	return &user, nil
+}
+
+func extraContains(user authenticationv1.UserInfo, key, value string) bool {
+	for _, v := range user.Extra[key] {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
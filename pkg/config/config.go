@@ -6,7 +6,20 @@ import (
 	"k8s.io/klog/v2"
 )
 
-const DefaultResyncDuration = 10 * time.Minute
+const (
+	DefaultResyncDuration     = 10 * time.Minute
+	DefaultSARCacheAllowedTTL = 5 * time.Minute
+	DefaultSARCacheDeniedTTL  = 30 * time.Second
+	// DefaultRevocationGracePeriod removes the content of a volume as soon as its pod loses access to the share
+	DefaultRevocationGracePeriod  = time.Duration(0)
+	DefaultAuditLogMaxSizeMB      = 100
+	DefaultAuditLogMaxBackups     = 5
+	DefaultAuditLogMaxAge         = 7 * 24 * time.Hour
+	DefaultUsageReportInterval    = time.Minute
+	DefaultReloadMinInterval      = time.Minute
+	DefaultNotificationQueueSize  = 1000
+	DefaultNotificationMaxRetries = 5
+)
 
 // Config configuration attributes.
 type Config struct {
@@ -15,6 +28,50 @@ type Config struct {
 	// RefreshResources toggles actively watching for resources, when disabled it will only read
 	// resources before mount.
 	RefreshResources bool `yaml:"refreshResources,omitempty"`
+	// SARCacheAllowedTTL how long an allowed SubjectAccessReview result is cached on the node, "0s"
+	// disables caching of allowed results.
+	SARCacheAllowedTTL string `yaml:"sarCacheAllowedTTL,omitempty"`
+	// SARCacheDeniedTTL how long a denied SubjectAccessReview result is cached on the node, "0s"
+	// disables caching of denied results.
+	SARCacheDeniedTTL string `yaml:"sarCacheDeniedTTL,omitempty"`
+	// ServiceAccountTokenAudience the audience of the pod service account token requested by the kubelet via the
+	// CSIDriver tokenRequests setting; when multiple tokens are supplied, the one for this audience is validated.
+	ServiceAccountTokenAudience string `yaml:"serviceAccountTokenAudience,omitempty"`
+	// RevocationGracePeriod how long the content of a volume is kept after its pod lost access to the share, "0s"
+	// removes it right away.
+	RevocationGracePeriod string `yaml:"revocationGracePeriod,omitempty"`
+	// AuditLogPath file the share access audit records are written to as JSON lines, "stdout" for the driver's
+	// standard output; empty disables the audit log.
+	AuditLogPath string `yaml:"auditLogPath,omitempty"`
+	// AuditLogMaxSizeMB size in megabytes after which the audit log file is rotated, 0 disables rotation.
+	AuditLogMaxSizeMB int `yaml:"auditLogMaxSizeMB,omitempty"`
+	// AuditLogMaxBackups how many rotated audit log files are kept, 0 keeps them all.
+	AuditLogMaxBackups int `yaml:"auditLogMaxBackups,omitempty"`
+	// AuditLogMaxAge how long rotated audit log files are kept, "0s" keeps them regardless of age.
+	AuditLogMaxAge string `yaml:"auditLogMaxAge,omitempty"`
+	// ManageShareStatus when enabled, one leader elected driver instance maintains the status conditions of the
+	// shares.
+	ManageShareStatus bool `yaml:"manageShareStatus,omitempty"`
+	// UsageReportInterval how often the driver publishes the usage report of its node when the volumes changed,
+	// "0s" disables the usage reports.
+	UsageReportInterval string `yaml:"usageReportInterval,omitempty"`
+	// ReloadMinInterval the minimum time between two reloads, annotations or restarts, of the same pod or workload
+	// opted in to reloads on share content changes.
+	ReloadMinInterval string `yaml:"reloadMinInterval,omitempty"`
+	// NotificationEndpoints URLs the share lifecycle CloudEvents are sent to, empty disables the notifications.
+	NotificationEndpoints []string `yaml:"notificationEndpoints,omitempty"`
+	// NotificationQueueSize how many notifications are queued per endpoint before new ones are dropped.
+	NotificationQueueSize int `yaml:"notificationQueueSize,omitempty"`
+	// NotificationMaxRetries how many times the delivery of a notification is retried.
+	NotificationMaxRetries int `yaml:"notificationMaxRetries,omitempty"`
+	// SharePolicy restricts the namespaces and the Secret types the shares can expose, enforced by the admission
+	// webhook and on every volume mount.
+	SharePolicy SharePolicy `yaml:"sharePolicy,omitempty"`
+	// AdmissionPolicies CEL rules the admission webhook checks the shares, and the pods consuming them, against.
+	AdmissionPolicies []AdmissionPolicy `yaml:"admissionPolicies,omitempty"`
+	// DefaultVolumeAttributes volume attributes, like refreshResource or fileMode, the mutating admission webhook sets
+	// on the shared resource volumes of the pods which do not set them.
+	DefaultVolumeAttributes map[string]string `yaml:"defaultVolumeAttributes,omitempty"`
 }
 
 var LoadedConfig Config
@@ -30,10 +87,68 @@ func (c *Config) GetShareRelistInterval() time.Duration {
 	return resyncDuration
 }
 
+// GetSARCacheAllowedTTL returns the SARCacheAllowedTTL value as duration. On error, default value
+// is employed instead.
+func (c *Config) GetSARCacheAllowedTTL() time.Duration {
+	return parseDurationOrDefault("SARCacheAllowedTTL", c.SARCacheAllowedTTL, DefaultSARCacheAllowedTTL)
+}
+
+// GetSARCacheDeniedTTL returns the SARCacheDeniedTTL value as duration. On error, default value
+// is employed instead.
+func (c *Config) GetSARCacheDeniedTTL() time.Duration {
+	return parseDurationOrDefault("SARCacheDeniedTTL", c.SARCacheDeniedTTL, DefaultSARCacheDeniedTTL)
+}
+
+// GetRevocationGracePeriod returns the RevocationGracePeriod value as duration. On error, default value
+// is employed instead.
+func (c *Config) GetRevocationGracePeriod() time.Duration {
+	return parseDurationOrDefault("RevocationGracePeriod", c.RevocationGracePeriod, DefaultRevocationGracePeriod)
+}
+
+// GetAuditLogMaxAge returns the AuditLogMaxAge value as duration. On error, default value
+// is employed instead.
+func (c *Config) GetAuditLogMaxAge() time.Duration {
+	return parseDurationOrDefault("AuditLogMaxAge", c.AuditLogMaxAge, DefaultAuditLogMaxAge)
+}
+
+// GetUsageReportInterval returns the UsageReportInterval value as duration. On error, default value
+// is employed instead.
+func (c *Config) GetUsageReportInterval() time.Duration {
+	return parseDurationOrDefault("UsageReportInterval", c.UsageReportInterval, DefaultUsageReportInterval)
+}
+
+// GetReloadMinInterval returns the ReloadMinInterval value as duration. On error, default value
+// is employed instead.
+func (c *Config) GetReloadMinInterval() time.Duration {
+	return parseDurationOrDefault("ReloadMinInterval", c.ReloadMinInterval, DefaultReloadMinInterval)
+}
+
+func parseDurationOrDefault(name, value string, defaultDuration time.Duration) time.Duration {
+	if len(value) == 0 {
+		return defaultDuration
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		klog.Errorf("Error on parsing %s '%s': %v", name, value, err)
+		return defaultDuration
+	}
+	return d
+}
+
 // NewConfig returns a Config instance using the default attribute values.
 func NewConfig() Config {
 	return Config{
-		ShareRelistInterval: DefaultResyncDuration.String(),
-		RefreshResources:    true,
+		ShareRelistInterval:    DefaultResyncDuration.String(),
+		RefreshResources:       true,
+		SARCacheAllowedTTL:     DefaultSARCacheAllowedTTL.String(),
+		SARCacheDeniedTTL:      DefaultSARCacheDeniedTTL.String(),
+		RevocationGracePeriod:  DefaultRevocationGracePeriod.String(),
+		AuditLogMaxSizeMB:      DefaultAuditLogMaxSizeMB,
+		AuditLogMaxBackups:     DefaultAuditLogMaxBackups,
+		AuditLogMaxAge:         DefaultAuditLogMaxAge.String(),
+		UsageReportInterval:    DefaultUsageReportInterval.String(),
+		ReloadMinInterval:      DefaultReloadMinInterval.String(),
+		NotificationQueueSize:  DefaultNotificationQueueSize,
+		NotificationMaxRetries: DefaultNotificationMaxRetries,
 	}
 }
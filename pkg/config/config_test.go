@@ -24,3 +24,36 @@ func TestConfig_GetShareRelistInterval(t *testing.T) {
 		}
 	})
 }
+
+func TestConfig_GetSARCacheTTLs(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg := NewConfig()
+		if cfg.GetSARCacheAllowedTTL() != DefaultSARCacheAllowedTTL {
+			t.Errorf("unexpected allowed ttl %s", cfg.GetSARCacheAllowedTTL())
+		}
+		if cfg.GetSARCacheDeniedTTL() != DefaultSARCacheDeniedTTL {
+			t.Errorf("unexpected denied ttl %s", cfg.GetSARCacheDeniedTTL())
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cfg := NewConfig()
+		cfg.SARCacheAllowedTTL = "0s"
+		cfg.SARCacheDeniedTTL = "0"
+		if cfg.GetSARCacheAllowedTTL() != 0 || cfg.GetSARCacheDeniedTTL() != 0 {
+			t.Errorf("expected caching to be disabled, got %s and %s", cfg.GetSARCacheAllowedTTL(), cfg.GetSARCacheDeniedTTL())
+		}
+	})
+
+	t.Run("bogus and negative ttls, expecting defaults returned", func(t *testing.T) {
+		cfg := NewConfig()
+		cfg.SARCacheAllowedTTL = "xxxxx"
+		cfg.SARCacheDeniedTTL = "-1m"
+		if cfg.GetSARCacheAllowedTTL() != DefaultSARCacheAllowedTTL {
+			t.Errorf("unexpected allowed ttl %s", cfg.GetSARCacheAllowedTTL())
+		}
+		if cfg.GetSARCacheDeniedTTL() != DefaultSARCacheDeniedTTL {
+			t.Errorf("unexpected denied ttl %s", cfg.GetSARCacheDeniedTTL())
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package config
+
+const (
+	// OwnerConsentWarn makes the admission webhook warn about shares of backing resources without owner consent
+	OwnerConsentWarn = "Warn"
+	// OwnerConsentEnforce makes the admission webhook deny shares of backing resources without owner consent, and
+	// the driver refuse to project, and revoke, their content
+	OwnerConsentEnforce = "Enforce"
+)
+
+const (
+	// DeletionProtectionWarn makes the admission webhook warn about the deletion of shares consumed by pods, listing
+	// their workloads
+	DeletionProtectionWarn = "Warn"
+	// DeletionProtectionEnforce makes the admission webhook deny the deletion of shares consumed by pods, unless they
+	// carry the "sharedresource.openshift.io/force-delete" annotation
+	DeletionProtectionEnforce = "Enforce"
+)
+
+// SharePolicy restricts the backing resources the shares can expose; the empty policy allows any backing resource.
+// A namespace has to be allowed, by name or by labels, when any allow rule is set, and must not be denied, by name or
+// by labels; deny rules take precedence over allow rules.
+type SharePolicy struct {
+	// AllowedNamespaces the namespaces whose ConfigMaps and Secrets can be shared.
+	AllowedNamespaces []string `yaml:"allowedNamespaces,omitempty"`
+	// AllowedNamespaceSelector the label selector of the namespaces whose ConfigMaps and Secrets can be shared.
+	AllowedNamespaceSelector string `yaml:"allowedNamespaceSelector,omitempty"`
+	// DeniedNamespaces the namespaces whose ConfigMaps and Secrets cannot be shared.
+	DeniedNamespaces []string `yaml:"deniedNamespaces,omitempty"`
+	// DeniedNamespaceSelector the label selector of the namespaces whose ConfigMaps and Secrets cannot be shared.
+	DeniedNamespaceSelector string `yaml:"deniedNamespaceSelector,omitempty"`
+	// DeniedSecretTypes the types of the Secrets which cannot be shared, like "kubernetes.io/service-account-token".
+	DeniedSecretTypes []string `yaml:"deniedSecretTypes,omitempty"`
+	// OwnerConsent whether backing resources have to consent to be shared through the "sharedresource.openshift.io/shareable"
+	// label, with OwnerConsentWarn or OwnerConsentEnforce; empty does not require any consent.
+	OwnerConsent string `yaml:"ownerConsent,omitempty"`
+	// CreatorCheckBypassGroups the groups whose members can create shares, or change their backing resource, without
+	// being able to get the backing resource themselves.
+	CreatorCheckBypassGroups []string `yaml:"creatorCheckBypassGroups,omitempty"`
+	// DeletionProtection whether the deletion of shares consumed by pods is guarded, with DeletionProtectionWarn or
+	// DeletionProtectionEnforce; empty does not guard it.
+	DeletionProtection string `yaml:"deletionProtection,omitempty"`
+}
+
+// HasNamespaceSelectors returns whether the policy selects namespaces by labels
+func (p *SharePolicy) HasNamespaceSelectors() bool {
+	return len(p.AllowedNamespaceSelector) > 0 || len(p.DeniedNamespaceSelector) > 0
+}
+
+// HasNamespaceAllowRules returns whether only some namespaces are allowed
+func (p *SharePolicy) HasNamespaceAllowRules() bool {
+	return len(p.AllowedNamespaces) > 0 || len(p.AllowedNamespaceSelector) > 0
+}
+
+// EnforcesOwnerConsent returns whether the driver refuses to project backing resources without owner consent
+func (p *SharePolicy) EnforcesOwnerConsent() bool {
+	return p.OwnerConsent == OwnerConsentEnforce
+}
+
+const (
+	// AdmissionPolicyDeny makes the admission webhook deny the requests failing an admission policy
+	AdmissionPolicyDeny = "Deny"
+	// AdmissionPolicyWarn makes the admission webhook warn about the requests failing an admission policy
+	AdmissionPolicyWarn = "Warn"
+)
+
+// AdmissionPolicy is a rule, written as a CEL expression, the admission webhook checks the shares, and the pods
+// consuming them, against.
+type AdmissionPolicy struct {
+	// Name identifies the policy in the denial messages, warnings and logs.
+	Name string `yaml:"name"`
+	// Kinds the kinds of the objects the policy applies to, among "SharedSecret", "SharedConfigMap" and "Pod"; empty
+	// applies it to all of them.
+	Kinds []string `yaml:"kinds,omitempty"`
+	// Expression the CEL expression a request has to evaluate to true against to pass the policy.
+	Expression string `yaml:"expression"`
+	// Action what is done with the requests failing the policy, AdmissionPolicyDeny, the default, or
+	// AdmissionPolicyWarn.
+	Action string `yaml:"action,omitempty"`
+	// Message explains the policy to the users whose requests fail it.
+	Message string `yaml:"message,omitempty"`
+}
@@ -6,3 +6,99 @@ const (
 )
 
 type ResourceReferenceType string
+
+const (
+	// ConsumerNamespaceSelectorAnnotation on a SharedSecret or SharedConfigMap holds a label selector, in the
+	// kubectl "-l" syntax, that the namespace of a consuming pod has to match
+	ConsumerNamespaceSelectorAnnotation = "sharedresource.openshift.io/consumer-namespace-selector"
+	// ConsumerPodSelectorAnnotation on a SharedSecret or SharedConfigMap holds a label selector, in the kubectl
+	// "-l" syntax, that the labels of a consuming pod have to match
+	ConsumerPodSelectorAnnotation = "sharedresource.openshift.io/consumer-pod-selector"
+)
+
+const (
+	// ExpiresAtAnnotation on a SharedSecret or SharedConfigMap holds the RFC 3339 time after which no pod can
+	// consume the share anymore
+	ExpiresAtAnnotation = "sharedresource.openshift.io/expires-at"
+	// NamespaceExpiresAtAnnotation on a SharedSecret or SharedConfigMap holds comma separated "namespace=time"
+	// grants, with RFC 3339 times, after which pods in the given namespace cannot consume the share anymore
+	NamespaceExpiresAtAnnotation = "sharedresource.openshift.io/namespace-expires-at"
+)
+
+const (
+	// DeprecatedAnnotation on a SharedSecret or SharedConfigMap marks the share as deprecated, and holds the message,
+	// such as the share replacing it, reported to its consumers
+	DeprecatedAnnotation = "sharedresource.openshift.io/deprecated"
+	// SunsetAtAnnotation on a deprecated SharedSecret or SharedConfigMap holds the RFC 3339 time after which the share
+	// behaves as revoked for every consumer
+	SunsetAtAnnotation = "sharedresource.openshift.io/sunset-at"
+	// AliasesAnnotation on a SharedSecret or SharedConfigMap holds the comma separated former names of the share,
+	// which volumes can keep referencing while the share is being renamed
+	AliasesAnnotation = "sharedresource.openshift.io/aliases"
+)
+
+const (
+	// RefreshNowAnnotation on a SharedSecret or SharedConfigMap holds a token; every new token makes every node re-run
+	// the permission checks of the share's volumes and rewrite their content right away
+	RefreshNowAnnotation = "sharedresource.openshift.io/refresh-now"
+	// RevokeAllAnnotation on a SharedSecret or SharedConfigMap holds a token; while it is set, the content of the
+	// share is removed from every consumer, without revocation grace period, and no new consumer is allowed
+	RevokeAllAnnotation = "sharedresource.openshift.io/revoke-all"
+	// ControlAcknowledgedCondition on a share's status is whether every node acknowledged its control tokens
+	ControlAcknowledgedCondition = "ControlAcknowledged"
+	// ForceDeleteAnnotation set to "true" on a SharedSecret or SharedConfigMap lets it be deleted while pods consume
+	// it, when the share policy protects the deletion of shares
+	ForceDeleteAnnotation = "sharedresource.openshift.io/force-delete"
+)
+
+const (
+	// ShareableLabel set to "true" on a Secret or ConfigMap is its owner's consent to have it exposed by shares, which
+	// the share policy can require
+	ShareableLabel = "sharedresource.openshift.io/shareable"
+	// ShareableByAnnotation on a Secret or ConfigMap carrying the ShareableLabel holds the comma separated names of
+	// the only shares which can expose it
+	ShareableByAnnotation = "sharedresource.openshift.io/shareable-by"
+)
+
+const (
+	// ReloadAnnotation on a SharedSecret, SharedConfigMap or consuming pod opts the consuming pods in to a reload
+	// when the driver writes new content into their volumes, with ReloadAnnotate or ReloadRestart; the pod's
+	// annotation, which can also be ReloadNone, takes precedence over the share's
+	ReloadAnnotation = "sharedresource.openshift.io/reload"
+	// ReloadAnnotate patches the ContentHashAnnotation of the pod
+	ReloadAnnotate = "annotate"
+	// ReloadRestart restarts the Deployment, StatefulSet or DaemonSet owning the pod
+	ReloadRestart = "restart"
+	// ReloadNone opts a pod out of the reload requested by the share
+	ReloadNone = "none"
+	// ContentHashAnnotation on a pod opted in with ReloadAnnotate holds a hash of the content versions of all its
+	// share volumes
+	ContentHashAnnotation = "sharedresource.openshift.io/content-hash"
+	// RestartedForAnnotation on the pod template of a workload restarted for pods opted in with ReloadRestart holds
+	// the comma separated "kind/share=resourceVersion" content versions the workload was restarted for
+	RestartedForAnnotation = "sharedresource.openshift.io/restarted-for"
+	// RestartedAtAnnotation on the pod template of a workload restarted for pods opted in with ReloadRestart holds
+	// the RFC 3339 time of the latest restart
+	RestartedAtAnnotation = "sharedresource.openshift.io/restarted-at"
+)
+
+const (
+	// VolumeDefaultsAnnotation on a pod holds the comma separated "volume/setting=value" settings the mutating
+	// admission webhook defaulted on its shared resource volumes
+	VolumeDefaultsAnnotation = "sharedresource.openshift.io/volume-defaults"
+)
+
+const (
+	// BackingResourceAvailableCondition on a share's status is whether the referenced Secret or ConfigMap exists
+	BackingResourceAvailableCondition = "BackingResourceAvailable"
+	// DriverCanReadCondition on a share's status is whether the driver's service account can read the referenced
+	// Secret or ConfigMap
+	DriverCanReadCondition = "DriverCanRead"
+	// ReservedNameValidCondition on a share's status is whether the share respects the OpenShift reserved names
+	ReservedNameValidCondition = "ReservedNameValid"
+	// InUseCondition on a share's status is whether pods on any node consume the share
+	InUseCondition = "InUse"
+	// ContentPropagatedCondition on a share's status is whether the refreshing volumes consuming the share hold the
+	// current content of the referenced Secret or ConfigMap
+	ContentPropagatedCondition = "ContentPropagated"
+)
@@ -0,0 +1,249 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+/*
+Administrators act on every node at once through the control annotations of a share: a new RefreshNowAnnotation
+token makes every node re-run the permission checks and rewrite the content of the share's volumes, and a
+RevokeAllAnnotation token removes the content from every consumer, and denies new ones, until it is removed.
+
+Every driver instance acknowledges the tokens it processed in the ConfigMap named AckPrefix followed by its node name,
+in the driver's namespace, with one key per share, so that administrators know when an action is done on every node.
+The share status controller aggregates the acknowledgements into the ControlAcknowledged condition of the share.
+*/
+
+const (
+	// AckPrefix prefixes the node name in the name of the ConfigMap holding the acknowledgements of a node
+	AckPrefix = "csi-driver-shared-resource-ack-"
+	// AckLabel labels the acknowledgement ConfigMaps with AckLabelNode
+	AckLabel     = "sharedresource.openshift.io/acknowledgements"
+	AckLabelNode = "node"
+)
+
+// Tokens are the control tokens of a share
+type Tokens struct {
+	RefreshNow string `json:"refreshNow,omitempty"`
+	RevokeAll  string `json:"revokeAll,omitempty"`
+}
+
+// ShareTokens returns the control tokens of the share annotations
+func ShareTokens(annotations map[string]string) Tokens {
+	return Tokens{
+		RefreshNow: annotations[consts.RefreshNowAnnotation],
+		RevokeAll:  annotations[consts.RevokeAllAnnotation],
+	}
+}
+
+// Acknowledgement is what a node acknowledged for a share
+type Acknowledgement struct {
+	Tokens
+	Time time.Time `json:"time"`
+}
+
+// AckKey returns the key of the acknowledgements of a share in the ConfigMap of a node
+func AckKey(kind consts.ResourceReferenceType, share string) string {
+	return string(kind) + "." + share
+}
+
+// ParseAckKey returns the kind and name of the share of an acknowledgement key
+func ParseAckKey(key string) (consts.ResourceReferenceType, string, bool) {
+	kind, share, ok := strings.Cut(key, ".")
+	return consts.ResourceReferenceType(kind), share, ok
+}
+
+// Acknowledger writes the acknowledgements of a node to its ConfigMap
+type Acknowledger struct {
+	kubeClient kubernetes.Interface
+	namespace  string
+	node       string
+	now        func() time.Time
+
+	lock   sync.Mutex
+	loaded bool
+	acks   map[string]Acknowledgement
+}
+
+// NewAcknowledger returns the acknowledger of the given node
+func NewAcknowledger(kubeClient kubernetes.Interface, namespace, node string) *Acknowledger {
+	return &Acknowledger{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		node:       node,
+		now:        time.Now,
+		acks:       map[string]Acknowledgement{},
+	}
+}
+
+// Load reads the acknowledgements the node made before a restart, creating its ConfigMap if needed, so that the
+// node is counted by the status controller even before it acknowledges anything
+func (a *Acknowledger) Load() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.load()
+}
+
+func (a *Acknowledger) load() error {
+	if a.loaded {
+		return nil
+	}
+	cm, err := a.kubeClient.CoreV1().ConfigMaps(a.namespace).Get(context.TODO(), AckPrefix+a.node, metav1.GetOptions{})
+	switch {
+	case kerrors.IsNotFound(err):
+		if err = a.write(); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		for key, value := range cm.Data {
+			ack := Acknowledgement{}
+			if err := json.Unmarshal([]byte(value), &ack); err == nil {
+				a.acks[key] = ack
+			}
+		}
+	}
+	a.loaded = true
+	return nil
+}
+
+// Acknowledge records that the node processed the tokens of the share, only writing the ConfigMap when they changed;
+// a share without tokens has its acknowledgements removed
+func (a *Acknowledger) Acknowledge(kind consts.ResourceReferenceType, share string, tokens Tokens) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if err := a.load(); err != nil {
+		return err
+	}
+	key := AckKey(kind, share)
+	previous, ok := a.acks[key]
+	if tokens == (Tokens{}) {
+		if !ok {
+			return nil
+		}
+		delete(a.acks, key)
+	} else {
+		if ok && previous.Tokens == tokens {
+			return nil
+		}
+		a.acks[key] = Acknowledgement{Tokens: tokens, Time: a.now().UTC()}
+	}
+	if err := a.write(); err != nil {
+		// acknowledge again on the next sync of the share
+		if ok {
+			a.acks[key] = previous
+		} else {
+			delete(a.acks, key)
+		}
+		return err
+	}
+	return nil
+}
+
+func (a *Acknowledger) write() error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: a.namespace,
+			Name:      AckPrefix + a.node,
+			Labels:    map[string]string{AckLabel: AckLabelNode},
+		},
+		Data: map[string]string{},
+	}
+	for key, ack := range a.acks {
+		data, err := json.Marshal(ack)
+		if err != nil {
+			return err
+		}
+		cm.Data[key] = string(data)
+	}
+	// the acknowledgements are garbage collected along with their node
+	if node, err := a.kubeClient.CoreV1().Nodes().Get(context.TODO(), a.node, metav1.GetOptions{}); err == nil {
+		cm.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "v1",
+			Kind:       "Node",
+			Name:       node.Name,
+			UID:        node.UID,
+		}}
+	}
+	_, err := a.kubeClient.CoreV1().ConfigMaps(a.namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	if kerrors.IsNotFound(err) {
+		_, err = a.kubeClient.CoreV1().ConfigMaps(a.namespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+	}
+	return err
+}
+
+// Progress is how many nodes acknowledged the tokens of a share
+type Progress struct {
+	Tokens
+	Nodes            int
+	RefreshNowAcked  int
+	RevokeAllAcked   int
+	PendingNodeNames []string
+}
+
+// Done returns whether every node acknowledged the tokens
+func (p Progress) Done() bool {
+	return len(p.PendingNodeNames) == 0
+}
+
+// Aggregate counts the acknowledgements of the tokens of a share in the acknowledgement ConfigMaps of the nodes
+func Aggregate(kind consts.ResourceReferenceType, share string, tokens Tokens, cms []*corev1.ConfigMap) Progress {
+	progress := Progress{Tokens: tokens, Nodes: len(cms), PendingNodeNames: []string{}}
+	key := AckKey(kind, share)
+	for _, cm := range cms {
+		ack := Acknowledgement{}
+		if value, ok := cm.Data[key]; ok {
+			json.Unmarshal([]byte(value), &ack)
+		}
+		if len(tokens.RefreshNow) > 0 && ack.RefreshNow == tokens.RefreshNow {
+			progress.RefreshNowAcked++
+		}
+		if len(tokens.RevokeAll) > 0 && ack.RevokeAll == tokens.RevokeAll {
+			progress.RevokeAllAcked++
+		}
+		if ack.Tokens != tokens {
+			progress.PendingNodeNames = append(progress.PendingNodeNames, strings.TrimPrefix(cm.Name, AckPrefix))
+		}
+	}
+	sort.Strings(progress.PendingNodeNames)
+	return progress
+}
+
+var (
+	lock         sync.Mutex
+	acknowledger *Acknowledger
+)
+
+// Configure sets the acknowledger of the node; a nil acknowledger disables the acknowledgements
+func Configure(a *Acknowledger) {
+	lock.Lock()
+	defer lock.Unlock()
+	acknowledger = a
+}
+
+// Acknowledge records, with the configured acknowledger if any, that the node processed the tokens of the share
+func Acknowledge(kind consts.ResourceReferenceType, share string, tokens Tokens) {
+	lock.Lock()
+	a := acknowledger
+	lock.Unlock()
+	if a == nil {
+		return
+	}
+	if err := a.Acknowledge(kind, share, tokens); err != nil {
+		klog.Warningf("unable to acknowledge the control tokens of %s %s: %s", kind, share, err.Error())
+	}
+}
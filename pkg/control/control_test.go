@@ -0,0 +1,61 @@
+package control
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func TestAcknowledger(t *testing.T) {
+	kubeClient := fakekubeclientset.NewSimpleClientset()
+	a := NewAcknowledger(kubeClient, "ns", "node1")
+	if err := a.Load(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if _, err := kubeClient.CoreV1().ConfigMaps("ns").Get(context.TODO(), AckPrefix+"node1", metav1.GetOptions{}); err != nil {
+		t.Fatalf("the acknowledgement ConfigMap was not created: %s", err.Error())
+	}
+
+	tokens := Tokens{RefreshNow: "1"}
+	if err := a.Acknowledge(consts.ResourceReferenceTypeSecret, "share1", tokens); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	writes := len(kubeClient.Actions())
+	if err := a.Acknowledge(consts.ResourceReferenceTypeSecret, "share1", tokens); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(kubeClient.Actions()) != writes {
+		t.Fatalf("unchanged tokens should not be written again")
+	}
+
+	cm, _ := kubeClient.CoreV1().ConfigMaps("ns").Get(context.TODO(), AckPrefix+"node1", metav1.GetOptions{})
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: AckPrefix + "node2"}}
+	progress := Aggregate(consts.ResourceReferenceTypeSecret, "share1", tokens, []*corev1.ConfigMap{cm, other})
+	if progress.Nodes != 2 || progress.RefreshNowAcked != 1 || progress.Done() ||
+		len(progress.PendingNodeNames) != 1 || progress.PendingNodeNames[0] != "node2" {
+		t.Fatalf("unexpected progress %#v", progress)
+	}
+
+	// a restarted driver instance does not acknowledge the same tokens again
+	restarted := NewAcknowledger(kubeClient, "ns", "node1")
+	writes = len(kubeClient.Actions())
+	if err := restarted.Acknowledge(consts.ResourceReferenceTypeSecret, "share1", tokens); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(kubeClient.Actions()) != writes+1 {
+		t.Fatalf("expected only the ConfigMap to be read, got %v", kubeClient.Actions()[writes:])
+	}
+
+	if err := restarted.Acknowledge(consts.ResourceReferenceTypeSecret, "share1", Tokens{}); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	cm, _ = kubeClient.CoreV1().ConfigMaps("ns").Get(context.TODO(), AckPrefix+"node1", metav1.GetOptions{})
+	if _, ok := cm.Data[AckKey(consts.ResourceReferenceTypeSecret, "share1")]; ok {
+		t.Fatalf("the acknowledgement of a share without tokens should be removed")
+	}
+}
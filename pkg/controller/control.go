@@ -0,0 +1,17 @@
+package controller
+
+import (
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+	"github.com/openshift/csi-driver-shared-resource/pkg/control"
+)
+
+// acknowledgeShareSync acknowledges the control tokens of a synced share, whose volumes on the node were re-checked
+// and rewritten, or had their content removed, by the sync
+func (c *Controller) acknowledgeShareSync(verb client.ObjectAction, kind consts.ResourceReferenceType, name string, annotations map[string]string) {
+	tokens := control.Tokens{}
+	if verb != client.DeleteObjectAction {
+		tokens = control.ShareTokens(annotations)
+	}
+	control.Acknowledge(kind, name, tokens)
+}
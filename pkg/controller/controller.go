@@ -18,6 +18,7 @@ import (
 
 	objcache "github.com/openshift/csi-driver-shared-resource/pkg/cache"
 	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
 	"github.com/openshift/csi-driver-shared-resource/pkg/metrics"
 )
 
@@ -38,6 +39,8 @@ type Controller struct {
 	secretWorkqueue          workqueue.TypedRateLimitingInterface[any]
 	sharedConfigMapWorkqueue workqueue.TypedRateLimitingInterface[any]
 	sharedSecretWorkqueue    workqueue.TypedRateLimitingInterface[any]
+	rbacWorkqueue            workqueue.TypedRateLimitingInterface[any]
+	shareExpiryWorkqueue     workqueue.TypedRateLimitingInterface[any]
 
 	secretWatchObjs    sync.Map
 	configMapWatchObjs sync.Map
@@ -48,9 +51,16 @@ type Controller struct {
 	sharedConfigMapInformerFactory shareinformer.SharedInformerFactory
 	sharedSecretInformerFactory    shareinformer.SharedInformerFactory
 
+	rbacInformerFactory informers.SharedInformerFactory
+
 	listers *client.Listers
 
 	refreshResources bool
+
+	// startTime and notifiedShares, which has a key of the notification subject of a share and a value of its
+	// last notified shareVersion, filter the replayed share events out of the notifications
+	startTime      time.Time
+	notifiedShares sync.Map
 }
 
 // NewController instantiate a new controller with relisting interval, and optional refresh-resources
@@ -70,14 +80,20 @@ func NewController(shareRelist time.Duration, refreshResources bool) (*Controlle
 			"shared-configmap-changes"),
 		sharedSecretWorkqueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[any](),
 			"shared-secret-changes"),
+		rbacWorkqueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[any](),
+			"shared-resource-rbac-changes"),
+		shareExpiryWorkqueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[any](),
+			"shared-resource-expirations"),
 		secretWatchObjs:                sync.Map{},
 		configMapWatchObjs:             sync.Map{},
 		sharedConfigMapInformerFactory: shareInformerFactory,
 		sharedSecretInformerFactory:    shareInformerFactory,
 		sharedConfigMapInformer:        shareInformerFactory.Sharedresource().V1alpha1().SharedConfigMaps().Informer(),
 		sharedSecretInformer:           shareInformerFactory.Sharedresource().V1alpha1().SharedSecrets().Informer(),
+		rbacInformerFactory:            informers.NewSharedInformerFactory(kubeClient, DefaultResyncDuration),
 		listers:                        client.GetListers(),
 		refreshResources:               refreshResources,
+		startTime:                      time.Now(),
 	}
 
 	c.cfgMapWorkqueue = workqueue.NewNamedRateLimitingQueue(
@@ -85,10 +101,30 @@ func NewController(shareRelist time.Duration, refreshResources bool) (*Controlle
 	c.secretWorkqueue = workqueue.NewNamedRateLimitingQueue(
 		workqueue.DefaultTypedControllerRateLimiter[any](), "shared-resource-secret-changes")
 
+	// index the shares by their backing resource so configmap/secret events can find their shares without
+	// listing every share, and by their aliases so volumes referencing a former name find the renamed share
+	if err := c.sharedConfigMapInformer.AddIndexers(cache.Indexers{
+		client.BackingResourceIndex: client.SharedConfigMapBackingResourceIndexFunc,
+		client.AliasIndex:           client.SharedConfigMapAliasIndexFunc,
+	}); err != nil {
+		return nil, err
+	}
+	if err := c.sharedSecretInformer.AddIndexers(cache.Indexers{
+		client.BackingResourceIndex: client.SharedSecretBackingResourceIndexFunc,
+		client.AliasIndex:           client.SharedSecretAliasIndexFunc,
+	}); err != nil {
+		return nil, err
+	}
+
 	client.SetSharedConfigMapsLister(c.sharedConfigMapInformerFactory.Sharedresource().V1alpha1().SharedConfigMaps().Lister())
 	client.SetSharedSecretsLister(c.sharedSecretInformerFactory.Sharedresource().V1alpha1().SharedSecrets().Lister())
+	client.SetSharedConfigMapsIndexer(c.sharedConfigMapInformer.GetIndexer())
+	client.SetSharedSecretsIndexer(c.sharedSecretInformer.GetIndexer())
 	c.sharedConfigMapInformer.AddEventHandler(c.sharedConfigMapEventHandler())
 	c.sharedSecretInformer.AddEventHandler(c.sharedSecretEventHandler())
+	if err := c.registerRBACInformers(); err != nil {
+		return nil, err
+	}
 
 	return c, nil
 }
@@ -98,9 +134,14 @@ func (c *Controller) Run(stopCh <-chan struct{}) error {
 	defer c.secretWorkqueue.ShutDown()
 	defer c.sharedConfigMapWorkqueue.ShutDown()
 	defer c.sharedSecretWorkqueue.ShutDown()
+	defer c.rbacWorkqueue.ShutDown()
+	defer c.shareExpiryWorkqueue.ShutDown()
 
 	c.sharedConfigMapInformerFactory.Start(stopCh)
 	c.sharedSecretInformerFactory.Start(stopCh)
+	// we do not wait for the RBAC informers to sync; they only serve to react sooner to permission changes, and
+	// the relist and the SubjectAccessReview cache TTLs still bound how long a stale permission check can last
+	c.rbacInformerFactory.Start(stopCh)
 
 	if !cache.WaitForCacheSync(stopCh, c.sharedConfigMapInformer.HasSynced) {
 		return fmt.Errorf("failed to wait for sharedconfigmap caches to sync")
@@ -113,6 +154,8 @@ func (c *Controller) Run(stopCh <-chan struct{}) error {
 	go wait.Until(c.secretEventProcessor, time.Second, stopCh)
 	go wait.Until(c.sharedConfigMapEventProcessor, time.Second, stopCh)
 	go wait.Until(c.sharedSecretEventProcessor, time.Second, stopCh)
+	go wait.Until(c.rbacEventProcessor, time.Second, stopCh)
+	go wait.Until(c.shareExpiryEventProcessor, time.Second, stopCh)
 
 	// start the Prometheus metrics serner
 	klog.Info("Starting the metrics server")
@@ -431,6 +474,7 @@ func (c *Controller) sharedConfigMapEventHandler() cache.ResourceEventHandlerFun
 		AddFunc: func(o interface{}) {
 			switch v := o.(type) {
 			case *sharev1alpha1.SharedConfigMap:
+				c.scheduleShareExpiry(consts.ResourceReferenceTypeConfigMap, v.Name, v.Annotations)
 				c.addSharedConfigMapToQueue(v, client.AddObjectAction)
 			default:
 				//log unrecognized type
@@ -439,6 +483,10 @@ func (c *Controller) sharedConfigMapEventHandler() cache.ResourceEventHandlerFun
 		UpdateFunc: func(o, n interface{}) {
 			switch v := n.(type) {
 			case *sharev1alpha1.SharedConfigMap:
+				if old, ok := o.(*sharev1alpha1.SharedConfigMap); !ok || old.ResourceVersion != v.ResourceVersion {
+					client.InvalidateSARCacheForShare(consts.ResourceReferenceTypeConfigMap, v.Name)
+				}
+				c.scheduleShareExpiry(consts.ResourceReferenceTypeConfigMap, v.Name, v.Annotations)
 				c.addSharedConfigMapToQueue(v, client.UpdateObjectAction)
 			default:
 				//log unrecognized type
@@ -450,11 +498,13 @@ func (c *Controller) sharedConfigMapEventHandler() cache.ResourceEventHandlerFun
 				switch vv := v.Obj.(type) {
 				case *sharev1alpha1.SharedConfigMap:
 					// log recovered deleted obj from tombstone via vv.GetName()
+					client.InvalidateSARCacheForShare(consts.ResourceReferenceTypeConfigMap, vv.Name)
 					c.addSharedConfigMapToQueue(vv, client.DeleteObjectAction)
 				default:
 					// log  error decoding obj tombstone
 				}
 			case *sharev1alpha1.SharedConfigMap:
+				client.InvalidateSARCacheForShare(consts.ResourceReferenceTypeConfigMap, v.Name)
 				c.addSharedConfigMapToQueue(v, client.DeleteObjectAction)
 			default:
 				//log unrecognized type
@@ -468,6 +518,7 @@ func (c *Controller) sharedSecretEventHandler() cache.ResourceEventHandlerFuncs
 		AddFunc: func(o interface{}) {
 			switch v := o.(type) {
 			case *sharev1alpha1.SharedSecret:
+				c.scheduleShareExpiry(consts.ResourceReferenceTypeSecret, v.Name, v.Annotations)
 				c.addSharedSecretToQueue(v, client.AddObjectAction)
 			default:
 				//log unrecognized type
@@ -476,6 +527,10 @@ func (c *Controller) sharedSecretEventHandler() cache.ResourceEventHandlerFuncs
 		UpdateFunc: func(o, n interface{}) {
 			switch v := n.(type) {
 			case *sharev1alpha1.SharedSecret:
+				if old, ok := o.(*sharev1alpha1.SharedSecret); !ok || old.ResourceVersion != v.ResourceVersion {
+					client.InvalidateSARCacheForShare(consts.ResourceReferenceTypeSecret, v.Name)
+				}
+				c.scheduleShareExpiry(consts.ResourceReferenceTypeSecret, v.Name, v.Annotations)
 				c.addSharedSecretToQueue(v, client.UpdateObjectAction)
 			default:
 				//log unrecognized type
@@ -487,11 +542,13 @@ func (c *Controller) sharedSecretEventHandler() cache.ResourceEventHandlerFuncs
 				switch vv := v.Obj.(type) {
 				case *sharev1alpha1.SharedSecret:
 					// log recovered deleted obj from tombstone via vv.GetName()
+					client.InvalidateSARCacheForShare(consts.ResourceReferenceTypeSecret, vv.Name)
 					c.addSharedSecretToQueue(vv, client.DeleteObjectAction)
 				default:
 					// log  error decoding obj tombstone
 				}
 			case *sharev1alpha1.SharedSecret:
+				client.InvalidateSARCacheForShare(consts.ResourceReferenceTypeSecret, v.Name)
 				c.addSharedSecretToQueue(v, client.DeleteObjectAction)
 			default:
 				//log unrecognized type
@@ -586,6 +643,11 @@ func (c *Controller) syncSharedConfigMap(event client.Event) error {
 	default:
 		return fmt.Errorf("unexpected share event action: %s", event.Verb)
 	}
+	if err == nil {
+		c.notifyShareSync(event.Verb, consts.ResourceReferenceTypeConfigMap, share.Name, share.Spec.ConfigMapRef.Namespace,
+			share.Spec.ConfigMapRef.Name, shareVersion(share.Generation, share.Annotations), share.CreationTimestamp.Time)
+		c.acknowledgeShareSync(event.Verb, consts.ResourceReferenceTypeConfigMap, share.Name, share.Annotations)
+	}
 
 	return err
 }
@@ -625,6 +687,11 @@ func (c *Controller) syncSharedSecret(event client.Event) error {
 	default:
 		return fmt.Errorf("unexpected share event action: %s", event.Verb)
 	}
+	if err == nil {
+		c.notifyShareSync(event.Verb, consts.ResourceReferenceTypeSecret, share.Name, share.Spec.SecretRef.Namespace,
+			share.Spec.SecretRef.Name, shareVersion(share.Generation, share.Annotations), share.CreationTimestamp.Time)
+		c.acknowledgeShareSync(event.Verb, consts.ResourceReferenceTypeSecret, share.Name, share.Annotations)
+	}
 
 	return err
 }
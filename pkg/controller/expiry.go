@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	objcache "github.com/openshift/csi-driver-shared-resource/pkg/cache"
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+/*
+Shares can carry an expiry, share wide or per namespace grant, after which pods can no longer consume them.  The share
+update path re-checks the expiry for every volume, but nothing else may trigger it right when the expiry passes, so
+when a share with an upcoming expiry is added or updated, its next expiry is scheduled on the expiry workqueue.  When
+it fires, the share, as currently known by the lister, is pushed through the share update path again.  The sunset of a
+deprecated share is scheduled the same way, as the share behaves as revoked once it passes.
+*/
+
+// shareExpirySlack makes sure the expiry has passed by the time the share update path re-checks it
+const shareExpirySlack = time.Second
+
+type shareExpiryKey struct {
+	kind consts.ResourceReferenceType
+	name string
+}
+
+func (c *Controller) scheduleShareExpiry(kind consts.ResourceReferenceType, name string, annotations map[string]string) {
+	now := time.Now()
+	next, ok := client.NextShareExpiry(annotations, now)
+	if sunset, sunsetOk := client.NextShareSunset(annotations, now); sunsetOk && (!ok || sunset.Before(next)) {
+		next, ok = sunset, true
+	}
+	if !ok {
+		return
+	}
+	delay := time.Until(next) + shareExpirySlack
+	klog.V(4).Infof("scheduling expiry check of %s share %s in %s", kind, name, delay)
+	c.shareExpiryWorkqueue.AddAfter(shareExpiryKey{kind: kind, name: name}, delay)
+}
+
+func (c *Controller) shareExpiryEventProcessor() {
+	for {
+		obj, shutdown := c.shareExpiryWorkqueue.Get()
+		if shutdown {
+			return
+		}
+
+		func() {
+			defer c.shareExpiryWorkqueue.Done(obj)
+
+			key, ok := obj.(shareExpiryKey)
+			if !ok {
+				c.shareExpiryWorkqueue.Forget(obj)
+				return
+			}
+
+			if err := c.syncShareExpiry(key); err != nil {
+				c.shareExpiryWorkqueue.AddRateLimited(obj)
+			} else {
+				c.shareExpiryWorkqueue.Forget(obj)
+			}
+		}()
+	}
+}
+
+func (c *Controller) syncShareExpiry(key shareExpiryKey) error {
+	klog.V(4).Infof("expiry check of %s share %s", key.kind, key.name)
+	switch key.kind {
+	case consts.ResourceReferenceTypeSecret:
+		share := client.GetSharedSecret(key.name)
+		if share == nil {
+			return nil
+		}
+		objcache.UpdateSharedSecret(share)
+		// a namespace grant may expire later than this one
+		c.scheduleShareExpiry(key.kind, key.name, share.Annotations)
+	case consts.ResourceReferenceTypeConfigMap:
+		share := client.GetSharedConfigMap(key.name)
+		if share == nil {
+			return nil
+		}
+		objcache.UpdateSharedConfigMap(share)
+		c.scheduleShareExpiry(key.kind, key.name, share.Annotations)
+	default:
+		return fmt.Errorf("unexpected share expiry kind: %s", key.kind)
+	}
+	return nil
+}
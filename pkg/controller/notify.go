@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+	"github.com/openshift/csi-driver-shared-resource/pkg/notify"
+)
+
+// shareAnnotationPrefix is the prefix of the annotations configuring the behavior of a share
+const shareAnnotationPrefix = "sharedresource.openshift.io/"
+
+// shareVersion identifies the spec and the annotations configuring the behavior of a share; unlike its
+// resourceVersion, it does not change when the status of the share is written
+func shareVersion(generation int64, annotations map[string]string) string {
+	keys := []string{}
+	for key := range annotations {
+		if strings.HasPrefix(key, shareAnnotationPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	version := strconv.FormatInt(generation, 10)
+	for _, key := range keys {
+		version = fmt.Sprintf("%s,%s=%s", version, key, annotations[key])
+	}
+	return version
+}
+
+// notifyShareSync sends the lifecycle notification of a synced share.  The informers replay every share as added
+// when the driver starts, and as updated on every relist, so only the shares created since the driver started are
+// notified as created, and only the shares whose shareVersion changed as updated.
+func (c *Controller) notifyShareSync(verb client.ObjectAction, kind consts.ResourceReferenceType, name, backingNamespace, backingName, version string, created time.Time) {
+	subject := notify.Subject(string(kind), name)
+	data := notify.Data{
+		ShareKind:        string(kind),
+		Share:            name,
+		BackingNamespace: backingNamespace,
+		BackingName:      backingName,
+	}
+	switch verb {
+	case client.DeleteObjectAction:
+		c.notifiedShares.Delete(subject)
+		notify.Notify(notify.TypeShareDeleted, subject, data)
+	case client.AddObjectAction:
+		c.notifiedShares.Store(subject, version)
+		if created.After(c.startTime) {
+			notify.Notify(notify.TypeShareCreated, subject, data)
+		}
+	case client.UpdateObjectAction:
+		previous, ok := c.notifiedShares.Swap(subject, version)
+		if !ok || previous.(string) != version {
+			notify.Notify(notify.TypeShareUpdated, subject, data)
+		}
+	}
+}
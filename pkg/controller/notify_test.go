@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func TestShareVersion(t *testing.T) {
+	annotations := map[string]string{
+		consts.RefreshNowAnnotation: "1",
+		"other.io/annotation":       "a",
+	}
+	version := shareVersion(2, annotations)
+
+	// writing the status of the share, or an unrelated annotation, does not change its version
+	annotations["other.io/annotation"] = "b"
+	if v := shareVersion(2, annotations); v != version {
+		t.Errorf("expected version %s got %s", version, v)
+	}
+	annotations[consts.RefreshNowAnnotation] = "2"
+	if v := shareVersion(2, annotations); v == version {
+		t.Errorf("expected a new version for a new refresh token")
+	}
+	if v := shareVersion(3, map[string]string{consts.RefreshNowAnnotation: "1", "other.io/annotation": "a"}); v == version {
+		t.Errorf("expected a new version for a new generation")
+	}
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+
+	objcache "github.com/openshift/csi-driver-shared-resource/pkg/cache"
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+)
+
+/*
+Roles, RoleBindings, ClusterRoles and ClusterRoleBindings are watched so that changes to who can "use" a share are
+noticed right away, vs. waiting for the share relist.  Only RBAC objects that may grant "use" on sharedsecrets or
+sharedconfigmaps are considered.  The RBAC workqueue keys are the namespace affected by the change, where
+metav1.NamespaceAll means a cluster scoped object changed and so every namespace is affected.  For the affected
+namespace, cached SubjectAccessReview results are dropped and the volumes of pods in that namespace are re-authorized.
+*/
+
+// rbacSyncDelay gives the API server's authorizer a moment to observe the same RBAC change before we re-run
+// SubjectAccessReviews, and coalesces bursts of RBAC changes for the same namespace
+const rbacSyncDelay = 2 * time.Second
+
+var shareResources = map[string]struct{}{
+	"sharedsecrets":    {},
+	"sharedconfigmaps": {},
+	rbacv1.ResourceAll: {},
+}
+
+// policyRulesGrantShareUse returns true if any of the rules can grant "use" on sharedsecrets or sharedconfigmaps
+func policyRulesGrantShareUse(rules []rbacv1.PolicyRule) bool {
+	for _, rule := range rules {
+		if !contains(rule.Verbs, "use", rbacv1.VerbAll) {
+			continue
+		}
+		if !contains(rule.APIGroups, sharev1alpha1.GroupName, rbacv1.APIGroupAll) {
+			continue
+		}
+		for _, resource := range rule.Resources {
+			if _, ok := shareResources[resource]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(list []string, values ...string) bool {
+	for _, l := range list {
+		for _, v := range values {
+			if l == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// roleRefGrantsShareUse looks up the role referenced by a binding; if the role cannot be found we err on the side
+// of caution and treat the binding as relevant
+func (c *Controller) roleRefGrantsShareUse(namespace string, roleRef rbacv1.RoleRef) bool {
+	switch roleRef.Kind {
+	case "ClusterRole":
+		cr, err := c.rbacInformerFactory.Rbac().V1().ClusterRoles().Lister().Get(roleRef.Name)
+		if err != nil {
+			return true
+		}
+		return policyRulesGrantShareUse(cr.Rules)
+	case "Role":
+		r, err := c.rbacInformerFactory.Rbac().V1().Roles().Lister().Roles(namespace).Get(roleRef.Name)
+		if err != nil {
+			return true
+		}
+		return policyRulesGrantShareUse(r.Rules)
+	}
+	return false
+}
+
+// rbacObjectAffectedNamespace returns whether the RBAC object may grant "use" on shares, and if so, which namespace
+// is affected by its change
+func (c *Controller) rbacObjectAffectedNamespace(o interface{}) (string, bool) {
+	switch v := o.(type) {
+	case cache.DeletedFinalStateUnknown:
+		return c.rbacObjectAffectedNamespace(v.Obj)
+	case *rbacv1.Role:
+		return v.Namespace, policyRulesGrantShareUse(v.Rules)
+	case *rbacv1.RoleBinding:
+		return v.Namespace, c.roleRefGrantsShareUse(v.Namespace, v.RoleRef)
+	case *rbacv1.ClusterRole:
+		return metav1.NamespaceAll, policyRulesGrantShareUse(v.Rules)
+	case *rbacv1.ClusterRoleBinding:
+		return metav1.NamespaceAll, c.roleRefGrantsShareUse("", v.RoleRef)
+	default:
+		//log unrecognized type
+	}
+	return "", false
+}
+
+func (c *Controller) addRBACObjectToQueue(o interface{}) {
+	if namespace, relevant := c.rbacObjectAffectedNamespace(o); relevant {
+		c.rbacWorkqueue.AddAfter(namespace, rbacSyncDelay)
+	}
+}
+
+// the initial list of RBAC objects is ignored, as permissions are checked when the volumes are published or
+// loaded from disk; for updates both the old and new object are considered, as a rule granting "use" may have been
+// removed
+func (c *Controller) rbacEventHandler() cache.ResourceEventHandlerDetailedFuncs {
+	return cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(o interface{}, isInInitialList bool) {
+			if isInInitialList {
+				return
+			}
+			c.addRBACObjectToQueue(o)
+		},
+		UpdateFunc: func(o, n interface{}) {
+			if om, ok := o.(metav1.Object); ok {
+				if nm, ok := n.(metav1.Object); ok && om.GetResourceVersion() == nm.GetResourceVersion() {
+					return
+				}
+			}
+			c.addRBACObjectToQueue(o)
+			c.addRBACObjectToQueue(n)
+		},
+		DeleteFunc: func(o interface{}) {
+			c.addRBACObjectToQueue(o)
+		},
+	}
+}
+
+func (c *Controller) rbacEventProcessor() {
+	for {
+		obj, shutdown := c.rbacWorkqueue.Get()
+		if shutdown {
+			return
+		}
+
+		func() {
+			defer c.rbacWorkqueue.Done(obj)
+
+			namespace, ok := obj.(string)
+			if !ok {
+				c.rbacWorkqueue.Forget(obj)
+				return
+			}
+
+			if err := c.syncRBAC(namespace); err != nil {
+				c.rbacWorkqueue.AddRateLimited(obj)
+			} else {
+				c.rbacWorkqueue.Forget(obj)
+			}
+		}()
+	}
+}
+
+func (c *Controller) syncRBAC(namespace string) error {
+	klog.V(4).Infof("rbac change affecting share use in namespace %q", namespace)
+	if namespace == metav1.NamespaceAll {
+		client.InvalidateSARCache()
+	} else {
+		client.InvalidateSARCacheForNamespace(namespace)
+	}
+	objcache.ReauthorizeNamespace(namespace)
+	return nil
+}
+
+func (c *Controller) registerRBACInformers() error {
+	handler := c.rbacEventHandler()
+	for _, informer := range []cache.SharedIndexInformer{
+		c.rbacInformerFactory.Rbac().V1().Roles().Informer(),
+		c.rbacInformerFactory.Rbac().V1().RoleBindings().Informer(),
+		c.rbacInformerFactory.Rbac().V1().ClusterRoles().Informer(),
+		c.rbacInformerFactory.Rbac().V1().ClusterRoleBindings().Informer(),
+	} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return fmt.Errorf("failed to add rbac event handler: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestPolicyRulesGrantShareUse(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		rules    []rbacv1.PolicyRule
+		expected bool
+	}{
+		{
+			name: "use on sharedsecrets",
+			rules: []rbacv1.PolicyRule{{
+				Verbs:     []string{"use"},
+				APIGroups: []string{"sharedresource.openshift.io"},
+				Resources: []string{"sharedsecrets"},
+			}},
+			expected: true,
+		},
+		{
+			name: "wildcards",
+			rules: []rbacv1.PolicyRule{{
+				Verbs:     []string{"*"},
+				APIGroups: []string{"*"},
+				Resources: []string{"*"},
+			}},
+			expected: true,
+		},
+		{
+			name: "get on sharedconfigmaps",
+			rules: []rbacv1.PolicyRule{{
+				Verbs:     []string{"get", "list"},
+				APIGroups: []string{"sharedresource.openshift.io"},
+				Resources: []string{"sharedconfigmaps"},
+			}},
+		},
+		{
+			name: "use on other group",
+			rules: []rbacv1.PolicyRule{{
+				Verbs:     []string{"use"},
+				APIGroups: []string{"security.openshift.io"},
+				Resources: []string{"securitycontextconstraints"},
+			}},
+		},
+		{
+			name: "second rule matches",
+			rules: []rbacv1.PolicyRule{
+				{
+					Verbs:     []string{"get"},
+					APIGroups: []string{""},
+					Resources: []string{"pods"},
+				},
+				{
+					Verbs:     []string{"use"},
+					APIGroups: []string{"sharedresource.openshift.io"},
+					Resources: []string{"sharedconfigmaps"},
+				},
+			},
+			expected: true,
+		},
+	} {
+		if got := policyRulesGrantShareUse(test.rules); got != test.expected {
+			t.Errorf("testcase %s: expected %v got %v", test.name, test.expected, got)
+		}
+	}
+}
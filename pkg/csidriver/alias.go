@@ -0,0 +1,73 @@
+package csidriver
+
+import (
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+/*
+A share is renamed by creating it under its new name, listing the former name in its AliasesAnnotation, and deleting
+the share with the former name.  Volumes referencing the former name are then resolved to the renamed share, which
+their refresh and delete callbacks follow as it is the share the volume records.  The volumes mounted before the
+rename follow the renamed share when the share with the former name is deleted, or, if that happened while the driver
+was down, when the driver loads them from disk.  While the renamed share lists the alias, the RBAC granting the use of
+the former name keeps granting the pods access.
+*/
+
+// authorizeVolume runs the SubjectAccessReview of the volume's pod against its share and, when the volume references
+// the share by one of its aliases, against the alias
+func authorizeVolume(dv *driverVolume, user *authenticationv1.UserInfo) (bool, error) {
+	alias := dv.GetShareAlias()
+	annotations := map[string]string{}
+	if len(alias) > 0 {
+		switch dv.GetSharedDataKind() {
+		case consts.ResourceReferenceTypeSecret:
+			if share := client.GetSharedSecret(dv.GetSharedDataId()); share != nil {
+				annotations = share.Annotations
+			}
+		case consts.ResourceReferenceTypeConfigMap:
+			if share := client.GetSharedConfigMap(dv.GetSharedDataId()); share != nil {
+				annotations = share.Annotations
+			}
+		}
+	}
+	return client.ExecuteSARForShareOrAlias(dv.GetSharedDataId(), alias, annotations, dv.GetPodNamespace(), dv.GetPodName(), user, dv.GetSharedDataKind())
+}
+
+// followAlias moves the volume to the share listing the name of the volume's share among its aliases, when the
+// volume's share no longer exists, returning the name of the renamed share and the share; the name is empty when the
+// volume was not moved
+func followAlias(dv *driverVolume) (string, interface{}) {
+	shareId := dv.GetSharedDataId()
+	var renamed string
+	var share interface{}
+	switch dv.GetSharedDataKind() {
+	case consts.ResourceReferenceTypeSecret:
+		if client.GetSharedSecret(shareId) != nil {
+			return "", nil
+		}
+		ss, err := client.SharedSecretForAlias(shareId)
+		if err != nil || ss == nil {
+			return "", nil
+		}
+		renamed, share = ss.Name, ss
+	case consts.ResourceReferenceTypeConfigMap:
+		if client.GetSharedConfigMap(shareId) != nil {
+			return "", nil
+		}
+		scm, err := client.SharedConfigMapForAlias(shareId)
+		if err != nil || scm == nil {
+			return "", nil
+		}
+		renamed, share = scm.Name, scm
+	default:
+		return "", nil
+	}
+	klog.V(0).Infof("volume %s of pod %s:%s follows share %s, renamed to %s", dv.GetVolID(), dv.GetPodNamespace(), dv.GetPodName(), shareId, renamed)
+	dv.SetSharedDataId(renamed)
+	dv.SetShareAlias(shareId)
+	return renamed, share
+}
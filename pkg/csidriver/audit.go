@@ -0,0 +1,97 @@
+package csidriver
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/audit"
+	objcache "github.com/openshift/csi-driver-shared-resource/pkg/cache"
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// auditDecision maps the error of a permission check to the decision recorded in the audit log
+func auditDecision(err error) audit.Decision {
+	switch {
+	case err == nil:
+		return audit.DecisionAllowed
+	case status.Code(err) == codes.PermissionDenied:
+		return audit.DecisionDenied
+	default:
+		return audit.DecisionError
+	}
+}
+
+// auditReason returns the reason recorded in the audit log for the error of a permission check
+func auditReason(err error) string {
+	if err == nil {
+		return "allowed"
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Message()
+	}
+	return err.Error()
+}
+
+// backingResourceKey returns the namespace/name of the configmap or secret the share currently references, or an
+// empty string if the share cannot be found
+func backingResourceKey(kind consts.ResourceReferenceType, shareId string) string {
+	switch kind {
+	case consts.ResourceReferenceTypeSecret:
+		if share := client.GetSharedSecret(shareId); share != nil {
+			return objcache.BuildKey(share.Spec.SecretRef.Namespace, share.Spec.SecretRef.Name)
+		}
+	case consts.ResourceReferenceTypeConfigMap:
+		if share := client.GetSharedConfigMap(shareId); share != nil {
+			return objcache.BuildKey(share.Spec.ConfigMapRef.Namespace, share.Spec.ConfigMapRef.Name)
+		}
+	}
+	return ""
+}
+
+// auditVolume records an audit log entry for the volume; when backingResource is empty, it is looked up from the share
+func auditVolume(dv *driverVolume, action audit.Action, phase audit.Phase, decision audit.Decision, reason, backingResource string) {
+	if !audit.Enabled() {
+		return
+	}
+	if len(backingResource) == 0 {
+		backingResource = backingResourceKey(dv.GetSharedDataKind(), dv.GetSharedDataId())
+	}
+	audit.Log(audit.Record{
+		Action:          action,
+		Phase:           phase,
+		Decision:        decision,
+		Reason:          reason,
+		VolumeID:        dv.GetVolID(),
+		Namespace:       dv.GetPodNamespace(),
+		Pod:             dv.GetPodName(),
+		PodUID:          dv.GetPodUID(),
+		ServiceAccount:  dv.GetPodSA(),
+		ShareKind:       string(dv.GetSharedDataKind()),
+		Share:           dv.GetSharedDataId(),
+		BackingResource: backingResource,
+	})
+}
+
+// auditPublish records the authorization decision made when publishing the volume of the request
+func auditPublish(req *csi.NodePublishVolumeRequest, kind consts.ResourceReferenceType, shareName string, err error) {
+	if !audit.Enabled() {
+		return
+	}
+	podNamespace, podName, podUID, podSA := getPodDetails(req.GetVolumeContext())
+	audit.Log(audit.Record{
+		Action:          audit.ActionAuthorize,
+		Phase:           audit.PhasePublish,
+		Decision:        auditDecision(err),
+		Reason:          auditReason(err),
+		VolumeID:        req.GetVolumeId(),
+		Namespace:       podNamespace,
+		Pod:             podName,
+		PodUID:          podUID,
+		ServiceAccount:  podSA,
+		ShareKind:       string(kind),
+		Share:           shareName,
+		BackingResource: backingResourceKey(kind, shareName),
+	})
+}
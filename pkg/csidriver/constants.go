@@ -1,5 +1,7 @@
 package csidriver
 
+import "os"
+
 type accessType int
 
 const (
@@ -14,10 +16,15 @@ const (
 	CSIPodNamespace                    = "csi.storage.k8s.io/pod.namespace"
 	CSIPodUID                          = "csi.storage.k8s.io/pod.uid"
 	CSIPodSA                           = "csi.storage.k8s.io/serviceAccount.name"
+	CSIServiceAccountTokens            = "csi.storage.k8s.io/serviceAccount.tokens"
 	CSIEphemeral                       = "csi.storage.k8s.io/ephemeral"
 	SharedConfigMapShareKey            = "sharedConfigMap"
 	SharedSecretShareKey               = "sharedSecret"
 	RefreshResource                    = "refreshResource"
+	FileModeAttribute                  = "fileMode"
 	bindDir                            = "bind-dir"
 	mountAccess             accessType = iota
 )
+
+// DefaultFileMode is the mode of the files of the volumes which do not set the fileMode volume attribute
+const DefaultFileMode os.FileMode = 0644
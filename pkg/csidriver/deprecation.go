@@ -0,0 +1,74 @@
+package csidriver
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+)
+
+/*
+A share deprecated with the DeprecatedAnnotation keeps being served, but the driver records a Warning event on the pods
+consuming it when their volume is mounted, and then again at most every deprecationEventInterval as the share update
+path re-checks the volume, so that the owners of the pods have a chance to move to another share.  Once the sunset
+time of the share passes, every consumer loses access to the share, honoring the revocation grace period.  Volumes
+referencing a share by one of its aliases get the same events, pointing them at the name of the share.
+*/
+
+// deprecationEventInterval is the minimum time between two ShareDeprecated events recorded for the same volume
+const deprecationEventInterval = time.Hour
+
+// deprecationEvents has a key of the CSI volume ID and a value of the time.Time of the latest ShareDeprecated event
+// recorded for the volume
+var deprecationEvents = sync.Map{}
+
+// warnDeprecated records ShareDeprecated events on the pod of the volume when its share is deprecated, or when the
+// volume references the share by one of its aliases; unless forced, the events are not recorded again before
+// deprecationEventInterval passed
+func warnDeprecated(dv *driverVolume, annotations map[string]string, force bool) {
+	volID := dv.GetVolID()
+	alias := dv.GetShareAlias()
+	// an invalid deprecation is rejected by the admission webhook, and does not deprecate the share
+	deprecation, _ := client.ShareDeprecation(annotations)
+	if deprecation == nil && len(alias) == 0 {
+		deprecationEvents.Delete(volID)
+		return
+	}
+	now := time.Now()
+	if last, ok := deprecationEvents.Load(volID); ok && !force && now.Sub(last.(time.Time)) < deprecationEventInterval {
+		return
+	}
+	deprecationEvents.Store(volID, now)
+	if len(alias) > 0 {
+		recordVolumeEvent(dv, corev1.EventTypeWarning, ShareDeprecatedReason, "the volume references %s %s by its deprecated alias %s, reference it by its name instead",
+			dv.GetSharedDataKind(), dv.GetSharedDataId(), alias)
+	}
+	if deprecation != nil {
+		recordVolumeEvent(dv, corev1.EventTypeWarning, ShareDeprecatedReason, "%s %s consumed by the volume is deprecated, %s",
+			dv.GetSharedDataKind(), dv.GetSharedDataId(), deprecation.String())
+	}
+}
+
+// forgetDeprecationEvents drops the time of the latest ShareDeprecated event of a volume which is deleted
+func forgetDeprecationEvents(volID string) {
+	deprecationEvents.Delete(volID)
+}
+
+// checkSunset denies the volume's pod access to a deprecated share whose sunset time passed, recording an event on
+// the pod when the content of the volume is about to be revoked
+func checkSunset(dv *driverVolume, shareId string, annotations map[string]string) error {
+	err := client.ValidateNotSunset(shareId, annotations, time.Now())
+	if err != nil {
+		klog.V(0).Infof("innerShareUpdateRanger pod %s:%s access to share %s sunset: %s",
+			dv.GetPodNamespace(), dv.GetPodName(), shareId, err.Error())
+		// the volume is re-checked on every relist, only report the sunset when there is content left to revoke
+		if volumeHasContent(dv.GetTargetPath()) && !revocationPending(dv.GetVolID()) {
+			recordVolumeEvent(dv, corev1.EventTypeWarning, ShareSunsetReason, "%s %s was sunset, the content of the volume is revoked",
+				dv.GetSharedDataKind(), shareId)
+		}
+	}
+	return err
+}
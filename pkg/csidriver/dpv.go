@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
 	"k8s.io/klog/v2"
 
 	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
@@ -29,6 +31,20 @@ type driverVolume struct {
 	PodUID              string     `json:"podUID"`
 	PodSA               string     `json:"podSA"`
 	Refresh             bool       `json:"refresh"`
+	// PodUser is the pod identity validated from the kubelet supplied service account token; when nil, the
+	// pod's service account is assumed
+	PodUser *authenticationv1.UserInfo `json:"podUser,omitempty"`
+	// LastUpdate is when the content of the backing resource was last written to the volume
+	LastUpdate time.Time `json:"lastUpdate"`
+	// ResourceVersion is the resourceVersion of the backing resource whose content the volume currently holds, empty
+	// when it holds none
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	// FileMode is the mode of the files of the volume, set with the fileMode volume attribute; 0 stands for
+	// DefaultFileMode
+	FileMode os.FileMode `json:"fileMode,omitempty"`
+	// ShareAlias is the former name of the share, listed in its aliases, that the volume references instead of the
+	// name of the share; empty when the volume references the share by its name
+	ShareAlias string `json:"shareAlias,omitempty"`
 	// dpv's can be accessed/modified by both the sharedSecret/SharedConfigMap events and the configmap/secret events; to prevent data races
 	// we serialize access to a given dpv with a per dpv mutex stored in this map; access to dpv fields should not
 	// be done directly, but only by each field's getter and setter.  Getters and setters then leverage the per dpv
@@ -109,11 +125,40 @@ func (dpv *driverVolume) GetPodSA() string {
 	defer dpv.Lock.Unlock()
 	return dpv.PodSA
 }
+func (dpv *driverVolume) GetPodUser() *authenticationv1.UserInfo {
+	dpv.Lock.Lock()
+	defer dpv.Lock.Unlock()
+	return dpv.PodUser
+}
+func (dpv *driverVolume) GetLastUpdate() time.Time {
+	dpv.Lock.Lock()
+	defer dpv.Lock.Unlock()
+	return dpv.LastUpdate
+}
+func (dpv *driverVolume) GetResourceVersion() string {
+	dpv.Lock.Lock()
+	defer dpv.Lock.Unlock()
+	return dpv.ResourceVersion
+}
 func (dpv *driverVolume) IsRefresh() bool {
 	dpv.Lock.Lock()
 	defer dpv.Lock.Unlock()
 	return dpv.Refresh
 }
+func (dpv *driverVolume) GetFileMode() os.FileMode {
+	dpv.Lock.Lock()
+	defer dpv.Lock.Unlock()
+	if dpv.FileMode == 0 {
+		return DefaultFileMode
+	}
+	return dpv.FileMode
+}
+
+func (dpv *driverVolume) GetShareAlias() string {
+	dpv.Lock.Lock()
+	defer dpv.Lock.Unlock()
+	return dpv.ShareAlias
+}
 
 func (dpv *driverVolume) SetVolName(volName string) {
 	dpv.Lock.Lock()
@@ -176,11 +221,37 @@ func (dpv *driverVolume) SetPodSA(sa string) {
 	defer dpv.Lock.Unlock()
 	dpv.PodSA = sa
 }
+func (dpv *driverVolume) SetPodUser(user *authenticationv1.UserInfo) {
+	dpv.Lock.Lock()
+	defer dpv.Lock.Unlock()
+	dpv.PodUser = user
+}
+func (dpv *driverVolume) SetLastUpdate(lastUpdate time.Time) {
+	dpv.Lock.Lock()
+	defer dpv.Lock.Unlock()
+	dpv.LastUpdate = lastUpdate
+}
+func (dpv *driverVolume) SetResourceVersion(resourceVersion string) {
+	dpv.Lock.Lock()
+	defer dpv.Lock.Unlock()
+	dpv.ResourceVersion = resourceVersion
+}
 func (dpv *driverVolume) SetRefresh(refresh bool) {
 	dpv.Lock.Lock()
 	defer dpv.Lock.Unlock()
 	dpv.Refresh = refresh
 }
+func (dpv *driverVolume) SetFileMode(fileMode os.FileMode) {
+	dpv.Lock.Lock()
+	defer dpv.Lock.Unlock()
+	dpv.FileMode = fileMode
+}
+
+func (dpv *driverVolume) SetShareAlias(shareAlias string) {
+	dpv.Lock.Lock()
+	defer dpv.Lock.Unlock()
+	dpv.ShareAlias = shareAlias
+}
 
 func (dpv *driverVolume) StoreToDisk(volMapRoot string) error {
 	dpv.Lock.Lock()
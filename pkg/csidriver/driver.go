@@ -27,6 +27,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -41,10 +42,13 @@ import (
 
 	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
 
+	"github.com/openshift/csi-driver-shared-resource/pkg/audit"
 	objcache "github.com/openshift/csi-driver-shared-resource/pkg/cache"
 	"github.com/openshift/csi-driver-shared-resource/pkg/client"
 	"github.com/openshift/csi-driver-shared-resource/pkg/config"
 	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+	"github.com/openshift/csi-driver-shared-resource/pkg/notify"
+	"github.com/openshift/csi-driver-shared-resource/pkg/usage"
 )
 
 type driver struct {
@@ -112,6 +116,7 @@ type CSIDriver interface {
 	GetRoot() string
 	GetVolMapRoot() string
 	Prune(kubeClient kubernetes.Interface)
+	UsageVolumes() []usage.VolumeUsage
 }
 
 // NewCSIDriver instantiate the CSIDriver with the driver details.  Optionally, a
@@ -265,7 +270,7 @@ func commonUpsertRanger(dv *driverVolume, key, value interface{}) error {
 			klog.V(4).Infof("commonUpsertRanger create/update file %s key %s volid %s share id %s pod name %s", podFilePath, key, dv.GetVolID(), dv.GetSharedDataId(), dv.GetPodName())
 			podFile[dataKey] = atomic.FileProjection{
 				Data: dataValue,
-				Mode: 0644,
+				Mode: int32(dv.GetFileMode()),
 			}
 		}
 	}
@@ -276,15 +281,36 @@ func commonUpsertRanger(dv *driverVolume, key, value interface{}) error {
 			content := []byte(dataValue)
 			podFile[dataKey] = atomic.FileProjection{
 				Data: content,
-				Mode: 0644,
+				Mode: int32(dv.GetFileMode()),
 			}
 		}
 	}
+	// the initial write of the content is covered by the ShareMounted event, only later changes are reported
+	changes := ""
+	hadContent := volumeHasContent(podPath)
+	if hadContent {
+		changes = summarizeKeyChanges(keyChanges(podPath, podFile))
+	}
 	if len(podFile) > 0 {
 		if err = aw.Write(podFile, nil); err != nil {
 			return err
 		}
 	}
+	// the content is re-written on every relist, only record it when it actually changed
+	changed := !hadContent || len(changes) > 0
+	dv.SetResourceVersion(payload.ResourceVersion)
+	if changed {
+		dv.SetLastUpdate(time.Now())
+		auditVolume(dv, audit.ActionUpdateContent, "", "", "", key.(string))
+	}
+	if len(changes) > 0 {
+		recordVolumeEvent(dv, corev1.EventTypeNormal, ShareUpdatedReason, "updated the content of the volume from %s %s: %s",
+			dv.GetSharedDataKind(), dv.GetSharedDataId(), changes)
+		reloadConsumer(dv)
+	}
+	if changed {
+		notifyVolume(dv, notify.TypeContentProjected, key.(string), payload.ResourceVersion, "")
+	}
 	klog.V(4).Infof("common upsert ranger returning key %s", key)
 	return nil
 }
@@ -343,6 +369,11 @@ func commonDeleteRanger(dv *driverVolume, key interface{}) bool {
 	}
 	klog.V(4).Infof("common delete ranger key %s", key)
 	commonOSRemove(dv.GetTargetPath(), fmt.Sprintf("commonDeleteRanger %s", key))
+	dv.SetResourceVersion("")
+	auditVolume(dv, audit.ActionRemoveContent, "", "", "backing resource deleted", key.(string))
+	recordVolumeEvent(dv, corev1.EventTypeWarning, BackingResourceMissingReason,
+		"the backing resource %s of %s %s was deleted, the content of the volume was removed",
+		key, dv.GetSharedDataKind(), dv.GetSharedDataId())
 	klog.V(4).Infof("common delete ranger returning key %s", key)
 	return true
 }
@@ -370,6 +401,11 @@ func (r *innerShareDeleteRanger) Range(key, value interface{}) bool {
 	}
 	if dv.GetVolID() == volID && dv.GetSharedDataId() == r.shareId {
 		klog.V(4).Infof("innerShareDeleteRanger shareid %s kind %s", r.shareId, dv.GetSharedDataKind())
+		if renamed, share := followAlias(dv); len(renamed) > 0 {
+			// the content of the renamed share replaces the one of the deleted share
+			shareUpdateRanger(renamed, share)
+			return true
+		}
 		targetPath = dv.GetTargetPath()
 		volID = dv.GetVolID()
 		if len(volID) > 0 && len(targetPath) > 0 {
@@ -378,6 +414,8 @@ func (r *innerShareDeleteRanger) Range(key, value interface{}) bool {
 				klog.Warningf("innerShareDeleteRanger %s vol %s target path %s delete error %s",
 					r.shareId, volID, targetPath, err.Error())
 			}
+			dv.SetResourceVersion("")
+			auditVolume(dv, audit.ActionRemoveContent, "", "", "share deleted", "")
 			// we just delete the associated data from the previously provisioned volume;
 			// we don't delete the volume in case the share is added back
 		}
@@ -402,6 +440,8 @@ type innerShareUpdateRanger struct {
 	shareId   string
 	secret    bool
 	configmap bool
+	// refresh re-fetches the backing resource from the API server rather than from the listers
+	refresh bool
 
 	oldTargetPath string
 	sharedItemKey string
@@ -428,8 +468,14 @@ func (r *innerShareUpdateRanger) Range(key, value interface{}) bool {
 	}
 	if dv.GetVolID() == volID && dv.GetSharedDataId() == r.shareId {
 		klog.V(4).Infof("innerShareUpdateRanger MATCH inner ranger key %q\n dv vol id %s\n incoming share id %s\n dv share id %s", key, dv.GetVolID(), r.shareId, dv.GetSharedDataId())
-		a, err := client.ExecuteSAR(r.shareId, dv.GetPodNamespace(), dv.GetPodName(), dv.GetPodSA(), dv.GetSharedDataKind())
-		allowed := a && err == nil
+		user := dv.GetPodUser()
+		if user == nil {
+			user = client.ServiceAccountUser(dv.GetPodNamespace(), dv.GetPodSA())
+		}
+		a, authErr := authorizeVolume(dv, user)
+		allowed := a && authErr == nil
+		revokeAll := false
+		var annotations map[string]string
 
 		if allowed {
 			klog.V(0).Infof("innerShareUpdateRanger pod %s:%s has permissions for secretShare %s",
@@ -446,15 +492,42 @@ func (r *innerShareUpdateRanger) Range(key, value interface{}) bool {
 				klog.Warningf("innerShareUpdateRanger unexpected not found on sharedSecret lister refresh: %s", r.shareId)
 				return true
 			}
+			if allowed {
+				authErr = checkConsumer(dv, r.shareId, sharedSecret.Annotations)
+				allowed = authErr == nil
+			}
+			if allowed {
+				authErr = checkExpiry(dv, r.shareId, sharedSecret.Annotations)
+				allowed = authErr == nil
+			}
+			// the revoke-all token also applies to the volumes the checks above already denied, whose content may still
+			// be kept during the grace period
+			if err := checkRevokeAll(dv, r.shareId, sharedSecret.Annotations); err != nil {
+				authErr, allowed, revokeAll = err, false, true
+			}
+			if allowed {
+				authErr = checkSunset(dv, r.shareId, sharedSecret.Annotations)
+				allowed = authErr == nil
+			}
+			annotations = sharedSecret.Annotations
 			r.sharedItemKey = objcache.BuildKey(sharedSecret.Spec.SecretRef.Namespace, sharedSecret.Spec.SecretRef.Name)
-			secretObj, err := client.GetSecret(sharedSecret.Spec.SecretRef.Namespace, sharedSecret.Spec.SecretRef.Name)
+			getSecret := client.GetSecret
+			if r.refresh {
+				getSecret = client.RefetchSecret
+			}
+			secretObj, err := getSecret(sharedSecret.Spec.SecretRef.Namespace, sharedSecret.Spec.SecretRef.Name)
 			if err != nil || secretObj == nil {
 				klog.Warningf("innerShareUpdateRanger share %s could not retrieve shared item %s, error: %v", r.shareId, r.sharedItemKey, err)
 				return true
 			}
+			if allowed {
+				authErr = checkOwnerConsent(dv, r.shareId, secretObj)
+				allowed = authErr == nil
+			}
 			r.sharedItem = Payload{
-				ByteData:   secretObj.Data,
-				StringData: secretObj.StringData,
+				ByteData:        secretObj.Data,
+				StringData:      secretObj.StringData,
+				ResourceVersion: secretObj.ResourceVersion,
 			}
 		case r.configmap:
 			sharedConfigMap := client.GetSharedConfigMap(r.shareId)
@@ -462,54 +535,154 @@ func (r *innerShareUpdateRanger) Range(key, value interface{}) bool {
 				klog.Warningf("innerShareUpdateRanger unexpected not found on sharedConfigMap lister refresh: %s", r.shareId)
 				return true
 			}
+			if allowed {
+				authErr = checkConsumer(dv, r.shareId, sharedConfigMap.Annotations)
+				allowed = authErr == nil
+			}
+			if allowed {
+				authErr = checkExpiry(dv, r.shareId, sharedConfigMap.Annotations)
+				allowed = authErr == nil
+			}
+			// the revoke-all token also applies to the volumes the checks above already denied, whose content may still
+			// be kept during the grace period
+			if err := checkRevokeAll(dv, r.shareId, sharedConfigMap.Annotations); err != nil {
+				authErr, allowed, revokeAll = err, false, true
+			}
+			if allowed {
+				authErr = checkSunset(dv, r.shareId, sharedConfigMap.Annotations)
+				allowed = authErr == nil
+			}
+			annotations = sharedConfigMap.Annotations
 			r.sharedItemKey = objcache.BuildKey(sharedConfigMap.Spec.ConfigMapRef.Namespace, sharedConfigMap.Spec.ConfigMapRef.Name)
-			cmObj, err := client.GetConfigMap(sharedConfigMap.Spec.ConfigMapRef.Namespace, sharedConfigMap.Spec.ConfigMapRef.Name)
+			getConfigMap := client.GetConfigMap
+			if r.refresh {
+				getConfigMap = client.RefetchConfigMap
+			}
+			cmObj, err := getConfigMap(sharedConfigMap.Spec.ConfigMapRef.Namespace, sharedConfigMap.Spec.ConfigMapRef.Name)
 			if err != nil || cmObj == nil {
 				klog.Warningf("innerShareUpdateRanger share %s could not retrieve shared item %s, error: %v", r.shareId, r.sharedItemKey, err)
 				return true
 			}
+			if allowed {
+				authErr = checkOwnerConsent(dv, r.shareId, cmObj)
+				allowed = authErr == nil
+			}
 			r.sharedItem = Payload{
-				StringData: cmObj.Data,
-				ByteData:   cmObj.BinaryData,
+				StringData:      cmObj.Data,
+				ByteData:        cmObj.BinaryData,
+				ResourceVersion: cmObj.ResourceVersion,
 			}
 		}
 
 		r.oldTargetPath = dv.GetTargetPath()
 		r.volID = dv.GetVolID()
+		auditVolume(dv, audit.ActionAuthorize, audit.PhaseRelist, auditDecision(authErr), auditReason(authErr), r.sharedItemKey)
 
 		if !allowed {
-			err := commonOSRemove(r.oldTargetPath, "lostPermissions")
-			if err != nil {
-				klog.Warningf("innerShareUpdateRanger %s target path %s delete error %s",
-					key, r.oldTargetPath, err.Error())
+			grace := config.LoadedConfig.GetRevocationGracePeriod()
+			if revokeAll {
+				// an emergency revocation does not wait for the grace period
+				grace = 0
 			}
-			objcache.UnregisterSecretUpsertCallback(r.volID)
-			objcache.UnregisterSecretDeleteCallback(r.volID)
-			objcache.UnregisterConfigMapDeleteCallback(r.volID)
-			objcache.UnregisterConfigMapUpsertCallback(r.volID)
+			revokeVolumeAccess(dv, grace)
 			return true // Continue the loop for other volumes
 		}
 
+		restoreVolumeAccess(dv)
 		commonUpsertRanger(dv, r.sharedItemKey, r.sharedItem)
+		warnDeprecated(dv, annotations, false)
 
 	}
 	klog.V(4).Infof("innerShareUpdateRanger NO MATCH inner ranger key %q\n dv vol id %s\n incoming share id %s\n dv share id %s", key, dv.GetVolID(), r.shareId, dv.GetSharedDataId())
 	return true
 }
 
+// checkConsumer re-evaluates the consumer selectors of the share against the volume's pod
+func checkConsumer(dv *driverVolume, shareId string, annotations map[string]string) error {
+	err := client.ValidateConsumer(shareId, annotations, dv.GetPodNamespace(), dv.GetPodName(), nil)
+	if err != nil {
+		klog.V(0).Infof("innerShareUpdateRanger pod %s:%s no longer passes the consumer selectors of share %s: %s",
+			dv.GetPodNamespace(), dv.GetPodName(), shareId, err.Error())
+	}
+	return err
+}
+
+// checkExpiry checks the expiry of the share for the volume's pod, recording an event on the pod when the access
+// expired and the content of the volume is about to be revoked
+func checkExpiry(dv *driverVolume, shareId string, annotations map[string]string) error {
+	err := client.ValidateExpiry(shareId, annotations, dv.GetPodNamespace(), time.Now())
+	if err != nil {
+		klog.V(0).Infof("innerShareUpdateRanger pod %s:%s access to share %s expired: %s",
+			dv.GetPodNamespace(), dv.GetPodName(), shareId, err.Error())
+		// the volume is re-checked on every relist, only report the expiry when there is content left to revoke
+		if volumeHasContent(dv.GetTargetPath()) && !revocationPending(dv.GetVolID()) {
+			recordVolumeEvent(dv, corev1.EventTypeWarning, ShareAccessExpiredReason, "access to %s %s expired, the content of the volume is revoked",
+				dv.GetSharedDataKind(), shareId)
+		}
+	}
+	return err
+}
+
+// checkRevokeAll denies the volume's pod access to a share carrying a revoke-all token
+func checkRevokeAll(dv *driverVolume, shareId string, annotations map[string]string) error {
+	err := client.ValidateNotRevoked(shareId, annotations)
+	if err != nil {
+		klog.V(0).Infof("innerShareUpdateRanger pod %s:%s access to share %s revoked for every consumer: %s",
+			dv.GetPodNamespace(), dv.GetPodName(), shareId, err.Error())
+	}
+	return err
+}
+
+// checkOwnerConsent checks the backing resource still consents to be exposed by the share, when the share policy
+// enforces the owner consent
+func checkOwnerConsent(dv *driverVolume, shareId string, backing metav1.Object) error {
+	if !config.LoadedConfig.SharePolicy.EnforcesOwnerConsent() {
+		return nil
+	}
+	err := client.ValidateOwnerConsent(dv.GetSharedDataKind(), shareId, backing)
+	if err != nil {
+		klog.V(0).Infof("innerShareUpdateRanger pod %s:%s share %s lost the consent of its backing resource: %s",
+			dv.GetPodNamespace(), dv.GetPodName(), shareId, err.Error())
+	}
+	return err
+}
+
+// refreshTokens holds the last refresh-now token the node processed for each share
+var refreshTokens = sync.Map{}
+
+// refreshRequested returns whether the share carries a refresh-now token the node has not processed yet; the SAR
+// cache of a share is already invalidated whenever the share is updated
+func refreshRequested(kind consts.ResourceReferenceType, shareId string, annotations map[string]string) bool {
+	key := string(kind) + "/" + shareId
+	token := annotations[consts.RefreshNowAnnotation]
+	if len(token) == 0 {
+		refreshTokens.Delete(key)
+		return false
+	}
+	previous, loaded := refreshTokens.Swap(key, token)
+	return !loaded || previous.(string) != token
+}
+
 func shareUpdateRanger(key, value interface{}) bool {
 	shareId := key.(string)
-	_, sok := value.(*sharev1alpha1.SharedSecret)
-	_, cmok := value.(*sharev1alpha1.SharedConfigMap)
+	sharedSecret, sok := value.(*sharev1alpha1.SharedSecret)
+	sharedConfigMap, cmok := value.(*sharev1alpha1.SharedConfigMap)
 	if !sok && !cmok {
 		klog.Warningf("unknown shareUpdateRanger key %q object %#v", key, value)
 		return false
 	}
 	klog.V(4).Infof("shareUpdateRanger key %s secret %v configmap %v", key, sok, cmok)
+	refresh := false
+	if sok {
+		refresh = refreshRequested(consts.ResourceReferenceTypeSecret, shareId, sharedSecret.Annotations)
+	} else {
+		refresh = refreshRequested(consts.ResourceReferenceTypeConfigMap, shareId, sharedConfigMap.Annotations)
+	}
 	rangerObj := &innerShareUpdateRanger{
 		shareId:   shareId,
 		secret:    sok,
 		configmap: cmok,
+		refresh:   refresh,
 	}
 	volumes.Range(rangerObj.Range)
 
@@ -517,6 +690,23 @@ func shareUpdateRanger(key, value interface{}) bool {
 	return true
 }
 
+// rbacUpdateRanger re-runs the permission check, and so removes or restores the content, for the given volume if
+// its pod is in the namespace affected by an RBAC change
+func rbacUpdateRanger(dv *driverVolume, key interface{}) bool {
+	namespace, _ := key.(string)
+	if namespace != metav1.NamespaceAll && namespace != dv.GetPodNamespace() {
+		return true
+	}
+	klog.V(4).Infof("rbacUpdateRanger namespace %q volume %s share %s", namespace, dv.GetVolID(), dv.GetSharedDataId())
+	ranger := &innerShareUpdateRanger{
+		shareId:   dv.GetSharedDataId(),
+		secret:    dv.GetSharedDataKind() == consts.ResourceReferenceTypeSecret,
+		configmap: dv.GetSharedDataKind() == consts.ResourceReferenceTypeConfigMap,
+	}
+	ranger.Range(dv.GetVolID(), dv)
+	return true
+}
+
 func mapBackingResourceToPod(dv *driverVolume) error {
 	klog.V(4).Infof("mapBackingResourceToPod")
 	switch dv.GetSharedDataKind() {
@@ -524,9 +714,21 @@ func mapBackingResourceToPod(dv *driverVolume) error {
 		klog.V(4).Infof("mapBackingResourceToPod postlock %s configmap", dv.GetVolID())
 		upsertRangerCM := func(key, value interface{}) bool {
 			cm, _ := value.(*corev1.ConfigMap)
+			if !commonRangerProceedFilter(dv, key) {
+				return true
+			}
+			// the pod lost access to the share, it does not get the new content during the revocation grace period
+			if revocationPending(dv.GetVolID()) {
+				return true
+			}
+			if err := checkOwnerConsent(dv, dv.GetSharedDataId(), cm); err != nil {
+				revokeVolumeAccess(dv, config.LoadedConfig.GetRevocationGracePeriod())
+				return true
+			}
 			payload := Payload{
-				StringData: cm.Data,
-				ByteData:   cm.BinaryData,
+				StringData:      cm.Data,
+				ByteData:        cm.BinaryData,
+				ResourceVersion: cm.ResourceVersion,
 			}
 			err := commonUpsertRanger(dv, key, payload)
 			if err != nil {
@@ -552,6 +754,10 @@ func mapBackingResourceToPod(dv *driverVolume) error {
 		comboKey := objcache.BuildKey(cmNamespace, cmName)
 		cm, err := client.GetConfigMap(cmNamespace, cmName)
 		if err != nil {
+			if kerrors.IsNotFound(err) {
+				recordVolumeEvent(dv, corev1.EventTypeWarning, BackingResourceMissingReason,
+					"the backing configmap %s of %s %s does not exist", comboKey, dv.GetSharedDataKind(), dv.GetSharedDataId())
+			}
 			if kerrors.IsForbidden(err) {
 				return status.Errorf(codes.PermissionDenied, "CSI driver is forbidden to access configmap %s/%s: %v", cmNamespace, cmName, err)
 			}
@@ -560,8 +766,9 @@ func mapBackingResourceToPod(dv *driverVolume) error {
 		}
 		if cm != nil {
 			payload := Payload{
-				StringData: cm.Data,
-				ByteData:   cm.BinaryData,
+				StringData:      cm.Data,
+				ByteData:        cm.BinaryData,
+				ResourceVersion: cm.ResourceVersion,
 			}
 
 			upsertError := commonUpsertRanger(dv, comboKey, payload)
@@ -582,8 +789,20 @@ func mapBackingResourceToPod(dv *driverVolume) error {
 		klog.V(4).Infof("mapBackingResourceToPod postlock %s secret", dv.GetVolID())
 		upsertRangerSec := func(key, value interface{}) bool {
 			s, _ := value.(*corev1.Secret)
+			if !commonRangerProceedFilter(dv, key) {
+				return true
+			}
+			// the pod lost access to the share, it does not get the new content during the revocation grace period
+			if revocationPending(dv.GetVolID()) {
+				return true
+			}
+			if err := checkOwnerConsent(dv, dv.GetSharedDataId(), s); err != nil {
+				revokeVolumeAccess(dv, config.LoadedConfig.GetRevocationGracePeriod())
+				return true
+			}
 			payload := Payload{
-				ByteData: s.Data,
+				ByteData:        s.Data,
+				ResourceVersion: s.ResourceVersion,
 			}
 			err := commonUpsertRanger(dv, key, payload)
 			if err != nil {
@@ -604,6 +823,10 @@ func mapBackingResourceToPod(dv *driverVolume) error {
 		comboKey := objcache.BuildKey(sNamespace, sName)
 		s, err := client.GetSecret(sNamespace, sName)
 		if err != nil {
+			if kerrors.IsNotFound(err) {
+				recordVolumeEvent(dv, corev1.EventTypeWarning, BackingResourceMissingReason,
+					"the backing secret %s of %s %s does not exist", comboKey, dv.GetSharedDataKind(), dv.GetSharedDataId())
+			}
 			if kerrors.IsForbidden(err) {
 				// Translate Forbidden to gRPC PermissionDenied
 				return status.Errorf(codes.PermissionDenied, "CSI driver is forbidden to access secret %s/%s: %v", sNamespace, sName, err)
@@ -613,7 +836,8 @@ func mapBackingResourceToPod(dv *driverVolume) error {
 		}
 		if s != nil {
 			payload := Payload{
-				ByteData: s.Data,
+				ByteData:        s.Data,
+				ResourceVersion: s.ResourceVersion,
 			}
 
 			upsertError := commonUpsertRanger(dv, comboKey, payload)
@@ -655,6 +879,10 @@ func (d *driver) registerRangers(dv *driverVolume) {
 	updateRangerShare := func(key, value interface{}) bool {
 		return shareUpdateRanger(key, value)
 	}
+	updateRangerRBAC := func(key, value interface{}) bool {
+		return rbacUpdateRanger(dv, key)
+	}
+	objcache.RegisterRBACUpdateCallback(dv.GetVolID(), updateRangerRBAC)
 	switch dv.GetSharedDataKind() {
 	case consts.ResourceReferenceTypeSecret:
 		objcache.RegisterSharedSecretUpdateCallback(dv.GetVolID(), dv.GetSharedDataId(), updateRangerShare)
@@ -760,6 +988,8 @@ func (d *driver) innerDeleteVolume(top string) {
 // deleteVolume deletes the directory for the csidriver volume.
 func (d *driver) deleteVolume(volID string) error {
 	klog.V(4).Infof("deleting csidriver volume: %s", volID)
+	cancelRevocation(volID)
+	forgetDeprecationEvents(volID)
 
 	if dv := d.getVolume(volID); dv != nil {
 		klog.V(4).Infof("found volume: %s", volID)
@@ -774,6 +1004,7 @@ func (d *driver) deleteVolume(volID string) error {
 	objcache.UnregisterSharedConfigMapUpdateCallback(volID)
 	objcache.UnregisterSharedSecretDeleteCallback(volID)
 	objcache.UnregsiterSharedSecretsUpdateCallback(volID)
+	objcache.UnregisterRBACUpdateCallback(volID)
 	return nil
 }
 
@@ -818,12 +1049,39 @@ func (d *driver) loadVolsFromDisk() error {
 		}
 		klog.V(2).Infof("loadVolsFromDisk storing with key %s dv %#v", dv.GetVolID(), dv)
 		setDPV(dv.GetVolID(), dv)
+		if renamed, _ := followAlias(dv); len(renamed) > 0 {
+			if err := dv.StoreToDisk(d.volMapRoot); err != nil {
+				klog.Warningf("loadVolsFromDisk could not persist vol id %s following share %s: %s", dv.GetVolID(), renamed, err.Error())
+			}
+		}
 		d.registerRangers(dv)
 
 		return nil
 	})
 }
 
+// UsageVolumes lists the volumes of the node for its usage report
+func (d *driver) UsageVolumes() []usage.VolumeUsage {
+	volumeUsages := []usage.VolumeUsage{}
+	volumes.Range(func(key, value interface{}) bool {
+		dv, ok := value.(*driverVolume)
+		if !ok {
+			return true
+		}
+		volumeUsages = append(volumeUsages, usage.VolumeUsage{
+			ShareKind:       dv.GetSharedDataKind(),
+			Share:           dv.GetSharedDataId(),
+			Namespace:       dv.GetPodNamespace(),
+			Pod:             dv.GetPodName(),
+			Refresh:         dv.IsRefresh(),
+			LastUpdate:      dv.GetLastUpdate().UTC(),
+			ResourceVersion: dv.GetResourceVersion(),
+		})
+		return true
+	})
+	return volumeUsages
+}
+
 // Prune inspects all the volumes stored on disk and checks if their associated pods still exists.  If not, the volume
 // file in question is deleted from disk.
 func (d *driver) Prune(kubeClient kubernetes.Interface) {
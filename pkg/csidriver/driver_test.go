@@ -17,6 +17,8 @@ limitations under the License.
 package csidriver
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
@@ -26,6 +28,7 @@ import (
 	"testing"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -34,14 +37,18 @@ import (
 	"k8s.io/client-go/kubernetes"
 	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
 	fakekubetesting "k8s.io/client-go/testing"
+	atomic "k8s.io/kubernetes/pkg/volume/util"
 	"k8s.io/utils/mount"
 
 	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
 	fakeshareclientset "github.com/openshift/client-go/sharedresource/clientset/versioned/fake"
 	shareinformer "github.com/openshift/client-go/sharedresource/informers/externalversions"
 
+	"github.com/openshift/csi-driver-shared-resource/pkg/audit"
 	"github.com/openshift/csi-driver-shared-resource/pkg/cache"
 	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
 )
 
 const (
@@ -448,6 +455,66 @@ func TestDeleteShare(t *testing.T) {
 
 }
 
+func TestShareAlias(t *testing.T) {
+	d, dir1, dir2, err := testDriver(t.Name(), nil)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	k8sClient := fakekubeclientset.NewSimpleClientset()
+	client.SetClient(k8sClient)
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	client.SetShareClient(shareClient)
+	share := &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: t.Name(),
+		},
+		Spec: sharev1alpha1.SharedSecretSpec{
+			SecretRef: sharev1alpha1.SharedSecretReference{
+				Name:      "secret1",
+				Namespace: "namespace",
+			},
+		},
+	}
+	_, searchPath := primeSecretVolume(t, d, targetPath, share, k8sClient, shareClient)
+	foundSecret, _ := findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret not found")
+	}
+
+	// the share is renamed: the new share lists the former name among its aliases, and the former share is deleted
+	renamed := share.DeepCopy()
+	renamed.Name = "renamed"
+	renamed.Annotations = map[string]string{consts.AliasesAnnotation: t.Name()}
+	shareClient.PrependReactor("get", "sharedsecrets", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		if action.(fakekubetesting.GetAction).GetName() == renamed.Name {
+			return true, renamed, nil
+		}
+		return true, nil, kerrors.NewNotFound(sharev1alpha1.Resource("sharedsecrets"), action.(fakekubetesting.GetAction).GetName())
+	})
+	shareClient.PrependReactor("list", "sharedsecrets", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &sharev1alpha1.SharedSecretList{Items: []sharev1alpha1.SharedSecret{*renamed}}, nil
+	})
+	cache.AddSharedSecret(renamed)
+	cache.DelSharedSecret(share)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("the volume should follow the renamed share")
+	}
+	dv := d.getVolume(t.Name())
+	if dv.GetSharedDataId() != renamed.Name || dv.GetShareAlias() != t.Name() {
+		t.Fatalf("unexpected share %s and alias %s of the volume", dv.GetSharedDataId(), dv.GetShareAlias())
+	}
+	// clear out dv for next run
+	d.deleteVolume(t.Name())
+}
+
 func TestDeleteReAddShare(t *testing.T) {
 	d, dir1, dir2, err := testDriver(t.Name(), nil)
 	if err != nil {
@@ -669,6 +736,550 @@ func TestPermChanges(t *testing.T) {
 
 }
 
+func TestRBACReauthorization(t *testing.T) {
+	d, dir1, dir2, err := testDriver(t.Name(), nil)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	k8sClient := fakekubeclientset.NewSimpleClientset()
+	client.SetClient(k8sClient)
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	client.SetShareClient(shareClient)
+	_, searchPath := primeSecretVolume(t, d, targetPath, nil, k8sClient, shareClient)
+	foundSecret, _ := findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret not found")
+	}
+
+	denyReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: false}}, nil
+	}
+	k8sClient.PrependReactor("create", "subjectaccessreviews", denyReactorFunc)
+
+	// an RBAC change in another namespace leaves the volume alone
+	cache.ReauthorizeNamespace("someOtherNamespace")
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret should not have been removed")
+	}
+
+	// an RBAC change in the pod's namespace re-runs the permission check
+	cache.ReauthorizeNamespace("podNamespace")
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if foundSecret {
+		t.Fatalf("secret should have been removed")
+	}
+
+	acceptReactorFunc := func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	}
+	k8sClient.PrependReactor("create", "subjectaccessreviews", acceptReactorFunc)
+
+	// a cluster scoped RBAC change re-runs the permission check for every volume
+	cache.ReauthorizeNamespace(metav1.NamespaceAll)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret should have been found")
+	}
+	// clear out dv for next run
+	d.deleteVolume(t.Name())
+}
+
+func TestShareExpiry(t *testing.T) {
+	d, dir1, dir2, err := testDriver(t.Name(), nil)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	k8sClient := fakekubeclientset.NewSimpleClientset()
+	client.SetClient(k8sClient)
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	client.SetShareClient(shareClient)
+	share := &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: t.Name(),
+			Annotations: map[string]string{
+				consts.ExpiresAtAnnotation: time.Now().Add(time.Hour).Format(time.RFC3339),
+			},
+		},
+		Spec: sharev1alpha1.SharedSecretSpec{
+			SecretRef: sharev1alpha1.SharedSecretReference{
+				Name:      "secret1",
+				Namespace: "namespace",
+			},
+		},
+	}
+	_, searchPath := primeSecretVolume(t, d, targetPath, share, k8sClient, shareClient)
+	foundSecret, _ := findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret not found")
+	}
+
+	// a grant for another namespace expiring does not affect the volume
+	share.Annotations[consts.NamespaceExpiresAtAnnotation] = "someOtherNamespace=" + time.Now().Add(-time.Minute).Format(time.RFC3339)
+	cache.UpdateSharedSecret(share)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret should not have been removed")
+	}
+
+	// the grant for the pod's namespace expiring removes the content
+	share.Annotations[consts.NamespaceExpiresAtAnnotation] = "podNamespace=" + time.Now().Add(-time.Minute).Format(time.RFC3339)
+	cache.UpdateSharedSecret(share)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if foundSecret {
+		t.Fatalf("secret should have been removed")
+	}
+	// clear out dv for next run
+	d.deleteVolume(t.Name())
+}
+
+func TestRevokeAll(t *testing.T) {
+	// an emergency revocation does not wait for the grace period
+	config.LoadedConfig.RevocationGracePeriod = "1h"
+	defer func() { config.LoadedConfig.RevocationGracePeriod = "" }()
+	d, dir1, dir2, err := testDriver(t.Name(), nil)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	k8sClient := fakekubeclientset.NewSimpleClientset()
+	client.SetClient(k8sClient)
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	client.SetShareClient(shareClient)
+	share := &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        t.Name(),
+			Annotations: map[string]string{},
+		},
+		Spec: sharev1alpha1.SharedSecretSpec{
+			SecretRef: sharev1alpha1.SharedSecretReference{
+				Name:      "secret1",
+				Namespace: "namespace",
+			},
+		},
+	}
+	_, searchPath := primeSecretVolume(t, d, targetPath, share, k8sClient, shareClient)
+	foundSecret, _ := findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret not found")
+	}
+
+	share.Annotations[consts.RevokeAllAnnotation] = "incident-1"
+	cache.UpdateSharedSecret(share)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if foundSecret {
+		t.Fatalf("secret should have been removed")
+	}
+	// clear out dv for next run
+	d.deleteVolume(t.Name())
+}
+
+func TestRevokeAllDuringGracePeriod(t *testing.T) {
+	// an emergency revocation removes the content of a volume already in its grace period
+	config.LoadedConfig.RevocationGracePeriod = "1h"
+	defer func() { config.LoadedConfig.RevocationGracePeriod = "" }()
+	d, dir1, dir2, err := testDriver(t.Name(), nil)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	k8sClient := fakekubeclientset.NewSimpleClientset()
+	client.SetClient(k8sClient)
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	client.SetShareClient(shareClient)
+	share := &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        t.Name(),
+			Annotations: map[string]string{},
+		},
+		Spec: sharev1alpha1.SharedSecretSpec{
+			SecretRef: sharev1alpha1.SharedSecretReference{
+				Name:      "secret1",
+				Namespace: "namespace",
+			},
+		},
+	}
+	_, searchPath := primeSecretVolume(t, d, targetPath, share, k8sClient, shareClient)
+	k8sClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: false}}, nil
+	})
+	cache.ReauthorizeNamespace("podNamespace")
+	foundSecret, _ := findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret should not have been removed during the grace period")
+	}
+
+	share.Annotations[consts.RevokeAllAnnotation] = "incident-1"
+	cache.UpdateSharedSecret(share)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if foundSecret {
+		t.Fatalf("secret should have been removed despite the pending revocation")
+	}
+	// clear out dv for next run
+	d.deleteVolume(t.Name())
+}
+
+func TestShareSunset(t *testing.T) {
+	// unlike an emergency revocation, a sunset honors the grace period
+	config.LoadedConfig.RevocationGracePeriod = "1h"
+	defer func() { config.LoadedConfig.RevocationGracePeriod = "" }()
+	d, dir1, dir2, err := testDriver(t.Name(), nil)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	k8sClient := fakekubeclientset.NewSimpleClientset()
+	client.SetClient(k8sClient)
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	client.SetShareClient(shareClient)
+	share := &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        t.Name(),
+			Annotations: map[string]string{},
+		},
+		Spec: sharev1alpha1.SharedSecretSpec{
+			SecretRef: sharev1alpha1.SharedSecretReference{
+				Name:      "secret1",
+				Namespace: "namespace",
+			},
+		},
+	}
+	_, searchPath := primeSecretVolume(t, d, targetPath, share, k8sClient, shareClient)
+	foundSecret, _ := findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret not found")
+	}
+
+	share.Annotations[consts.DeprecatedAnnotation] = "use another share"
+	share.Annotations[consts.SunsetAtAnnotation] = time.Now().Add(time.Hour).Format(time.RFC3339)
+	cache.UpdateSharedSecret(share)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("a deprecated share should be served until its sunset")
+	}
+	if _, ok := deprecationEvents.Load(t.Name()); !ok {
+		t.Fatalf("the deprecation of the share should have been reported")
+	}
+
+	share.Annotations[consts.SunsetAtAnnotation] = time.Now().Add(-time.Hour).Format(time.RFC3339)
+	cache.UpdateSharedSecret(share)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret should not have been removed during the grace period")
+	}
+	if _, err = os.Stat(filepath.Join(searchPath, RevokedMarkerFile)); err != nil {
+		t.Fatalf("expected revoked marker file: %v", err)
+	}
+	// clear out dv for next run
+	d.deleteVolume(t.Name())
+	if _, ok := deprecationEvents.Load(t.Name()); ok {
+		t.Fatalf("the deprecation events of a deleted volume should be forgotten")
+	}
+}
+
+func TestRefreshRequested(t *testing.T) {
+	annotations := map[string]string{consts.RefreshNowAnnotation: "1"}
+	if !refreshRequested(consts.ResourceReferenceTypeSecret, t.Name(), annotations) {
+		t.Fatalf("a new token should request a refresh")
+	}
+	if refreshRequested(consts.ResourceReferenceTypeSecret, t.Name(), annotations) {
+		t.Fatalf("a processed token should not request a refresh again")
+	}
+	annotations[consts.RefreshNowAnnotation] = "2"
+	if !refreshRequested(consts.ResourceReferenceTypeSecret, t.Name(), annotations) {
+		t.Fatalf("a changed token should request a refresh")
+	}
+	if refreshRequested(consts.ResourceReferenceTypeSecret, t.Name(), map[string]string{}) {
+		t.Fatalf("a share without token should not request a refresh")
+	}
+}
+
+func TestOwnerConsent(t *testing.T) {
+	d, dir1, dir2, err := testDriver(t.Name(), nil)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	k8sClient := fakekubeclientset.NewSimpleClientset()
+	client.SetClient(k8sClient)
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	client.SetShareClient(shareClient)
+	secret, searchPath := primeSecretVolume(t, d, targetPath, nil, k8sClient, shareClient)
+
+	config.LoadedConfig.SharePolicy.OwnerConsent = config.OwnerConsentEnforce
+	defer func() { config.LoadedConfig.SharePolicy.OwnerConsent = "" }()
+
+	secret.Labels = map[string]string{consts.ShareableLabel: "true"}
+	cache.UpsertSecret(secret)
+	foundSecret, _ := findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret should not have been removed")
+	}
+
+	// the owner withdrawing the consent revokes the access
+	secret.Labels = nil
+	cache.UpsertSecret(secret)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if foundSecret {
+		t.Fatalf("secret should have been removed")
+	}
+	// clear out dv for next run
+	d.deleteVolume(t.Name())
+}
+
+func TestRevocationGracePeriod(t *testing.T) {
+	config.LoadedConfig.RevocationGracePeriod = "200ms"
+	config.LoadedConfig.RefreshResources = true
+	defer func() {
+		config.LoadedConfig.RevocationGracePeriod = ""
+		config.LoadedConfig.RefreshResources = false
+	}()
+	d, dir1, dir2, err := testDriver(t.Name(), nil)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	k8sClient := fakekubeclientset.NewSimpleClientset()
+	client.SetClient(k8sClient)
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	client.SetShareClient(shareClient)
+	secret, searchPath := primeSecretVolume(t, d, targetPath, nil, k8sClient, shareClient)
+	markerPath := filepath.Join(searchPath, RevokedMarkerFile)
+
+	allowed := false
+	k8sClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}}, nil
+	})
+
+	// the content is kept, with a marker, during the grace period
+	cache.ReauthorizeNamespace("podNamespace")
+	foundSecret, _ := findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret should not have been removed during the grace period")
+	}
+	if _, err = os.Stat(markerPath); err != nil {
+		t.Fatalf("expected revoked marker file: %v", err)
+	}
+
+	// the rotated content of the backing resource is not written during the grace period
+	rotated := secret.DeepCopy()
+	rotated.Data = map[string][]byte{secretkey1: []byte("rotated")}
+	cache.UpsertSecret(rotated)
+	content, err := os.ReadFile(filepath.Join(searchPath, secretkey1))
+	if err != nil || string(content) != secretvalue1 {
+		t.Fatalf("expected the content to be kept as is during the grace period, got %q: %v", string(content), err)
+	}
+
+	// restoring access cancels the revocation
+	allowed = true
+	cache.ReauthorizeNamespace("podNamespace")
+	if _, err = os.Stat(markerPath); !os.IsNotExist(err) {
+		t.Fatalf("expected revoked marker file to be removed: %v", err)
+	}
+	time.Sleep(400 * time.Millisecond)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if !foundSecret {
+		t.Fatalf("secret should not have been removed after access was restored")
+	}
+
+	// the content is removed once the grace period passes
+	allowed = false
+	cache.ReauthorizeNamespace("podNamespace")
+	time.Sleep(400 * time.Millisecond)
+	foundSecret, _ = findSharedItems(t, searchPath)
+	if foundSecret {
+		t.Fatalf("secret should have been removed after the grace period")
+	}
+
+	// once the content is removed, the volume is not revoked again
+	cache.ReauthorizeNamespace("podNamespace")
+	if _, err = os.Stat(markerPath); !os.IsNotExist(err) {
+		t.Fatalf("expected no revoked marker file in the emptied volume: %v", err)
+	}
+	// clear out dv for next run
+	d.deleteVolume(t.Name())
+}
+
+func TestAuditLog(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := audit.NewSink(auditPath, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error opening audit log: %s", err.Error())
+	}
+	audit.Configure(sink, "node1")
+	defer audit.Configure(nil, "")
+	d, dir1, dir2, err := testDriver(t.Name(), nil)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	k8sClient := fakekubeclientset.NewSimpleClientset()
+	client.SetClient(k8sClient)
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	client.SetShareClient(shareClient)
+	primeSecretVolume(t, d, targetPath, nil, k8sClient, shareClient)
+	// a relist which does not change the content is not recorded
+	cache.ReauthorizeNamespace("podNamespace")
+	k8sClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: false}}, nil
+	})
+	cache.ReauthorizeNamespace("podNamespace")
+	d.deleteVolume(t.Name())
+
+	content, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("unexpected error reading audit log: %s", err.Error())
+	}
+	found := map[string]audit.Record{}
+	updates := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		r := audit.Record{}
+		if err = json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("unexpected error parsing audit record %q: %s", line, err.Error())
+		}
+		found[string(r.Action)+"/"+string(r.Decision)] = r
+		if r.Action == audit.ActionUpdateContent {
+			updates++
+		}
+	}
+	if updates != 1 {
+		t.Errorf("expected a single UpdateContent audit record, got %d in %s", updates, string(content))
+	}
+	for _, key := range []string{"UpdateContent/", "Authorize/Denied", "Revoke/", "RemoveContent/"} {
+		r, ok := found[key]
+		if !ok {
+			t.Fatalf("missing %s audit record in %s", key, string(content))
+		}
+		if r.Node != "node1" || r.Namespace != "podNamespace" || r.Share != t.Name() || r.BackingResource != "namespace:secret1" {
+			t.Errorf("unexpected %s audit record %#v", key, r)
+		}
+	}
+	if r := found["Authorize/Denied"]; r.Phase != audit.PhaseRelist || len(r.Reason) == 0 {
+		t.Errorf("unexpected authorize audit record %#v", r)
+	}
+}
+
+func TestUsageVolumes(t *testing.T) {
+	d, dir1, dir2, err := testDriver(t.Name(), nil)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir1)
+	defer os.RemoveAll(dir2)
+	targetPath, err := os.MkdirTemp(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatalf("err on targetPath %s", err.Error())
+	}
+	defer os.RemoveAll(targetPath)
+	k8sClient := fakekubeclientset.NewSimpleClientset()
+	client.SetClient(k8sClient)
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	client.SetShareClient(shareClient)
+	primeSecretVolume(t, d, targetPath, nil, k8sClient, shareClient)
+	lastUpdate := d.getVolume(t.Name()).GetLastUpdate()
+	if lastUpdate.IsZero() {
+		t.Fatalf("expected the initial write to set the last update")
+	}
+
+	// a relist which does not change the content keeps the last update, so that the usage report is unchanged
+	cache.ReauthorizeNamespace("podNamespace")
+	for _, volumeUsage := range d.UsageVolumes() {
+		if volumeUsage.Share == t.Name() && !volumeUsage.LastUpdate.Equal(lastUpdate.UTC()) {
+			t.Errorf("expected last update %s got %s", lastUpdate.UTC(), volumeUsage.LastUpdate)
+		}
+	}
+	// clear out dv for next run
+	d.deleteVolume(t.Name())
+}
+
+func TestKeyChangesSummary(t *testing.T) {
+	targetPath := t.TempDir()
+	aw, err := atomic.NewAtomicWriter(targetPath, t.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if volumeHasContent(targetPath) {
+		t.Fatalf("expected an empty volume to have no content")
+	}
+	if err = aw.Write(map[string]atomic.FileProjection{
+		"kept":    {Data: []byte("same"), Mode: 0644},
+		"changed": {Data: []byte("old"), Mode: 0644},
+		"dropped": {Data: []byte("gone"), Mode: 0644},
+	}, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if !volumeHasContent(targetPath) {
+		t.Fatalf("expected the volume to have content")
+	}
+
+	summary := summarizeKeyChanges(keyChanges(targetPath, map[string]atomic.FileProjection{
+		"kept":    {Data: []byte("same"), Mode: 0644},
+		"changed": {Data: []byte("new"), Mode: 0644},
+		"new":     {Data: []byte("new"), Mode: 0644},
+	}))
+	if summary != "added new; updated changed; removed dropped" {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+	summary = summarizeKeyChanges(keyChanges(targetPath, map[string]atomic.FileProjection{
+		"kept":    {Data: []byte("same"), Mode: 0644},
+		"changed": {Data: []byte("old"), Mode: 0644},
+		"dropped": {Data: []byte("gone"), Mode: 0644},
+	}))
+	if len(summary) > 0 {
+		t.Fatalf("expected no changes, got %q", summary)
+	}
+}
+
 // TestMapVolumeToPodWithKubeClient creates a new CSIDriver with a kubernetes client, which
 // changes the behavior of the component, so instead of directly reading backing-resources from the
 // object-cache, it directly updates the cache before trying to mount the volume.
@@ -957,3 +1568,75 @@ func findSharedItems(t *testing.T, dir string) (bool, bool) {
 	}
 	return foundSecret, foundConfigMap
 }
+
+func TestReloadConsumers(t *testing.T) {
+	controller := true
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "web"}}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "web-1",
+		OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", Controller: &controller}}}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "web-1-abcde",
+		OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-1", Controller: &controller}}}}
+	k8sClient := fakekubeclientset.NewSimpleClientset(deployment, rs, pod)
+	client.SetClient(k8sClient)
+
+	dv := &driverVolume{VolID: t.Name(), SharedDataKind: string(consts.ResourceReferenceTypeSecret), SharedDataId: "share1",
+		PodNamespace: "ns1", PodName: pod.Name, ResourceVersion: "5", Lock: &sync.Mutex{}}
+	volumes.Store(dv.VolID, dv)
+	defer volumes.Delete(dv.VolID)
+
+	kind, name, err := podWorkload(pod)
+	if err != nil || kind != "Deployment" || name != "web" {
+		t.Fatalf("unexpected workload %s %s: %v", kind, name, err)
+	}
+
+	if again := restartWorkload(dv, kind, "ns1", name, pod.Name); again != 0 {
+		t.Fatalf("unexpected retry in %s", again)
+	}
+	restarted, _ := k8sClient.AppsV1().Deployments("ns1").Get(context.TODO(), "web", metav1.GetOptions{})
+	if restarted.Spec.Template.Annotations[consts.RestartedForAnnotation] != "Secret/share1=5" ||
+		len(restarted.Spec.Template.Annotations[consts.RestartedAtAnnotation]) == 0 {
+		t.Fatalf("unexpected pod template annotations %#v", restarted.Spec.Template.Annotations)
+	}
+
+	// another node seeing the same content does not restart the workload again
+	k8sClient.ClearActions()
+	restartWorkload(dv, kind, "ns1", name, pod.Name)
+	for _, action := range k8sClient.Actions() {
+		if action.GetVerb() == "update" {
+			t.Fatalf("unexpected restart %#v", action)
+		}
+	}
+
+	// newer content waits for the cluster wide rate limit
+	dv.SetResourceVersion("6")
+	if again := restartWorkload(dv, kind, "ns1", name, pod.Name); again <= 0 {
+		t.Fatalf("expected the restart to be rate limited")
+	}
+
+	annotatePod(dv, "ns1", pod.Name)
+	annotated, _ := k8sClient.CoreV1().Pods("ns1").Get(context.TODO(), pod.Name, metav1.GetOptions{})
+	if annotated.Annotations[consts.ContentHashAnnotation] != contentHash([]string{"Secret/share1=6"}) {
+		t.Fatalf("unexpected pod annotations %#v", annotated.Annotations)
+	}
+}
+
+func TestThrottleReloadForgets(t *testing.T) {
+	config.LoadedConfig.ReloadMinInterval = "50ms"
+	defer func() { config.LoadedConfig.ReloadMinInterval = "" }()
+	key := "Pod/ns1/" + t.Name()
+	reloaded := make(chan struct{})
+	throttleReload(key, func() time.Duration {
+		close(reloaded)
+		return 0
+	})
+	<-reloaded
+	time.Sleep(10 * time.Millisecond)
+	if _, ok := lastReloads.Load(key); !ok {
+		t.Fatalf("expected the reload to throttle the next one")
+	}
+	// the reload is forgotten once it no longer throttles the next one
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := lastReloads.Load(key); ok {
+		t.Fatalf("expected the reload to be forgotten after the minimum interval")
+	}
+}
@@ -0,0 +1,102 @@
+package csidriver
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	atomic "k8s.io/kubernetes/pkg/volume/util"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+)
+
+// The reasons of the events recorded on the pods consuming shares; the events are recorded in the pod's namespace,
+// and rate limited and aggregated per pod and reason by the event recorder.
+const (
+	ShareMountedReason           = "ShareMounted"
+	ShareUpdatedReason           = "ShareUpdated"
+	ShareAccessRevokedReason     = "ShareAccessRevoked"
+	ShareAccessRestoredReason    = "ShareAccessRestored"
+	ShareAccessExpiredReason     = "ShareAccessExpired"
+	BackingResourceMissingReason = "BackingResourceMissing"
+	ShareReloadedReason          = "ShareReloaded"
+	ShareReloadFailedReason      = "ShareReloadFailed"
+	ShareDeprecatedReason        = "ShareDeprecated"
+	ShareSunsetReason            = "ShareSunset"
+
+	// maxKeysInEvent bounds how many key names are listed per type of change in a ShareUpdated event
+	maxKeysInEvent = 10
+)
+
+// recordVolumeEvent records an event on the pod consuming the volume
+func recordVolumeEvent(dv *driverVolume, eventType, reason, messageFmt string, args ...interface{}) {
+	client.RecordPodEvent(dv.GetPodNamespace(), dv.GetPodName(), dv.GetPodUID(), eventType, reason, messageFmt, args...)
+}
+
+// volumeHasContent returns whether the content of a backing resource was written to the volume, and not removed since
+func volumeHasContent(targetPath string) bool {
+	_, err := os.Lstat(filepath.Join(targetPath, "..data"))
+	return err == nil
+}
+
+// keyChanges compares the keys currently in the volume with the ones about to be written, returning the names of
+// the added, updated and removed keys
+func keyChanges(targetPath string, files map[string]atomic.FileProjection) (added, updated, removed []string) {
+	entries, err := os.ReadDir(targetPath)
+	if err != nil {
+		entries = nil
+	}
+	existing := map[string]bool{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+		existing[entry.Name()] = true
+		file, ok := files[entry.Name()]
+		if !ok {
+			removed = append(removed, entry.Name())
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(targetPath, entry.Name()))
+		if err != nil || !bytes.Equal(content, file.Data) {
+			updated = append(updated, entry.Name())
+		}
+	}
+	for key := range files {
+		if !existing[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(updated)
+	sort.Strings(removed)
+	return added, updated, removed
+}
+
+// summarizeKeyChanges lists the names, never the values, of the changed keys for a ShareUpdated event
+func summarizeKeyChanges(added, updated, removed []string) string {
+	summary := []string{}
+	for _, change := range []struct {
+		verb string
+		keys []string
+	}{
+		{verb: "added", keys: added},
+		{verb: "updated", keys: updated},
+		{verb: "removed", keys: removed},
+	} {
+		if len(change.keys) == 0 {
+			continue
+		}
+		keys := change.keys
+		more := ""
+		if len(keys) > maxKeysInEvent {
+			more = fmt.Sprintf(" and %d more", len(keys)-maxKeysInEvent)
+			keys = keys[:maxKeysInEvent]
+		}
+		summary = append(summary, fmt.Sprintf("%s %s%s", change.verb, strings.Join(keys, ", "), more))
+	}
+	return strings.Join(summary, "; ")
+}
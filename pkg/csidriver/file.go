@@ -11,6 +11,8 @@ import (
 type Payload struct {
 	StringData map[string]string
 	ByteData   map[string][]byte
+	// ResourceVersion is the resourceVersion of the backing resource the data comes from
+	ResourceVersion string
 }
 
 func ProcessFileSystemError(obj runtime.Object, err error) {
@@ -17,17 +17,22 @@ limitations under the License.
 package csidriver
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/mount"
 
@@ -73,15 +78,56 @@ func getPodDetails(volumeContext map[string]string) (string, string, string, str
 
 }
 
-func (ns *nodeServer) validateShare(req *csi.NodePublishVolumeRequest) (*sharev1alpha1.SharedConfigMap, *sharev1alpha1.SharedSecret, error) {
+type serviceAccountToken struct {
+	Token               string `json:"token"`
+	ExpirationTimestamp string `json:"expirationTimestamp"`
+}
+
+// authenticatePod validates the service account token the kubelet supplies when the CSIDriver object has
+// tokenRequests set, returning the pod's validated identity.  A nil user with no error means no token was supplied,
+// in which case the pod's service account is assumed.
+func (ns *nodeServer) authenticatePod(req *csi.NodePublishVolumeRequest) (*authenticationv1.UserInfo, error) {
+	tokensStr, ok := req.GetVolumeContext()[CSIServiceAccountTokens]
+	if !ok || len(strings.TrimSpace(tokensStr)) == 0 {
+		return nil, nil
+	}
+	tokens := map[string]serviceAccountToken{}
+	if err := json.Unmarshal([]byte(tokensStr), &tokens); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"the volumeAttribute %q could not be parsed: %s", CSIServiceAccountTokens, err.Error())
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	audience := config.LoadedConfig.ServiceAccountTokenAudience
+	token, ok := tokens[audience]
+	if !ok {
+		if len(audience) > 0 || len(tokens) > 1 {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"the volumeAttribute %q does not contain a token for audience %q", CSIServiceAccountTokens, audience)
+		}
+		// a single token for an audience we were not configured with, validate it for that audience
+		for aud, t := range tokens {
+			audience, token = aud, t
+		}
+	}
+
+	podNamespace, podName, podUID, podSA := getPodDetails(req.GetVolumeContext())
+	return client.ValidatePodServiceAccountToken(token.Token, audience, podNamespace, podName, podUID, podSA)
+}
+
+// validateShare also returns the alias of the share the volume references, when it references one of the former names
+// of a renamed share rather than its name
+func (ns *nodeServer) validateShare(req *csi.NodePublishVolumeRequest, user *authenticationv1.UserInfo) (*sharev1alpha1.SharedConfigMap, *sharev1alpha1.SharedSecret, string, error) {
 	configMapShareName, cmok := req.GetVolumeContext()[SharedConfigMapShareKey]
 	secretShareName, sok := req.GetVolumeContext()[SharedSecretShareKey]
 	if (!cmok && !sok) || (len(strings.TrimSpace(configMapShareName)) == 0 && len(strings.TrimSpace(secretShareName)) == 0) {
-		return nil, nil, status.Errorf(codes.InvalidArgument,
+		return nil, nil, "", status.Errorf(codes.InvalidArgument,
 			"the csi driver reference is missing the volumeAttribute %q and %q", SharedSecretShareKey, SharedConfigMapShareKey)
 	}
 	if (cmok && sok) || (len(strings.TrimSpace(configMapShareName)) > 0 && len(strings.TrimSpace(secretShareName)) > 0) {
-		return nil, nil, status.Errorf(codes.InvalidArgument,
+		return nil, nil, "", status.Errorf(codes.InvalidArgument,
 			"a single volume cannot support both a SharedConfigMap reference %q and SharedSecret reference %q",
 			configMapShareName, secretShareName)
 	}
@@ -90,33 +136,49 @@ func (ns *nodeServer) validateShare(req *csi.NodePublishVolumeRequest) (*sharev1
 	var sShare *sharev1alpha1.SharedSecret
 	var err error
 	allowed := false
+	alias := ""
 	if len(configMapShareName) > 0 {
 		cmShare, err = client.GetListers().SharedConfigMaps.Get(configMapShareName)
+		if kerrors.IsNotFound(err) {
+			// the volume may reference a former name of a renamed share
+			if aliased, aliasErr := client.SharedConfigMapForAlias(configMapShareName); aliasErr != nil {
+				err = aliasErr
+			} else if aliased != nil {
+				cmShare, alias, configMapShareName, err = aliased, configMapShareName, aliased.Name, nil
+			}
+		}
 		if err != nil {
-			return nil, nil, status.Errorf(codes.InvalidArgument,
+			return nil, nil, "", status.Errorf(codes.InvalidArgument,
 				"the csi driver volumeAttribute %q reference had an error: %s", configMapShareName, err.Error())
 		}
 	}
 	if len(secretShareName) > 0 {
 		sShare, err = client.GetListers().SharedSecrets.Get(secretShareName)
+		if kerrors.IsNotFound(err) {
+			if aliased, aliasErr := client.SharedSecretForAlias(secretShareName); aliasErr != nil {
+				err = aliasErr
+			} else if aliased != nil {
+				sShare, alias, secretShareName, err = aliased, secretShareName, aliased.Name, nil
+			}
+		}
 		if err != nil {
-			return nil, nil, status.Errorf(codes.InvalidArgument,
+			return nil, nil, "", status.Errorf(codes.InvalidArgument,
 				"the csi driver volumeAttribute %q reference had an error: %s", secretShareName, err.Error())
 		}
 	}
 
 	if sShare == nil && cmShare == nil {
-		return nil, nil, status.Errorf(codes.InvalidArgument,
+		return nil, nil, "", status.Errorf(codes.InvalidArgument,
 			"volumeAttributes did not reference a valid SharedSecret or SharedConfigMap")
 	}
 
 	// check reserve name list
 	if cmok && !ns.rn.ValidateSharedConfigMapOpenShiftName(configMapShareName, cmShare.Spec.ConfigMapRef.Namespace, cmShare.Spec.ConfigMapRef.Name) {
-		return nil, nil, status.Errorf(codes.InvalidArgument,
+		return nil, nil, "", status.Errorf(codes.InvalidArgument,
 			"share %s violates the OpenShift reserved name list", configMapShareName)
 	}
 	if sok && !ns.rn.ValidateSharedSecretOpenShiftName(secretShareName, sShare.Spec.SecretRef.Namespace, sShare.Spec.SecretRef.Name) {
-		return nil, nil, status.Errorf(codes.InvalidArgument,
+		return nil, nil, "", status.Errorf(codes.InvalidArgument,
 			"share %s violates the OpenShift reserved name list", secretShareName)
 	}
 
@@ -125,11 +187,11 @@ func (ns *nodeServer) validateShare(req *csi.NodePublishVolumeRequest) (*sharev1
 	kind := consts.ResourceReferenceTypeConfigMap
 	if cmShare != nil {
 		if len(strings.TrimSpace(cmShare.Spec.ConfigMapRef.Namespace)) == 0 {
-			return nil, nil, status.Errorf(codes.InvalidArgument,
+			return nil, nil, "", status.Errorf(codes.InvalidArgument,
 				"the SharedConfigMap %q backing resource namespace needs to be set", configMapShareName)
 		}
 		if len(strings.TrimSpace(cmShare.Spec.ConfigMapRef.Name)) == 0 {
-			return nil, nil, status.Errorf(codes.InvalidArgument,
+			return nil, nil, "", status.Errorf(codes.InvalidArgument,
 				"the SharedConfigMap %q backing resource name needs to be set", configMapShareName)
 		}
 		shareName = configMapShareName
@@ -137,21 +199,69 @@ func (ns *nodeServer) validateShare(req *csi.NodePublishVolumeRequest) (*sharev1
 	if sShare != nil {
 		kind = consts.ResourceReferenceTypeSecret
 		if len(strings.TrimSpace(sShare.Spec.SecretRef.Namespace)) == 0 {
-			return nil, nil, status.Errorf(codes.InvalidArgument,
+			return nil, nil, "", status.Errorf(codes.InvalidArgument,
 				"the SharedSecret %q backing resource namespace needs to be set", secretShareName)
 		}
 		if len(strings.TrimSpace(sShare.Spec.SecretRef.Name)) == 0 {
-			return nil, nil, status.Errorf(codes.InvalidArgument,
+			return nil, nil, "", status.Errorf(codes.InvalidArgument,
 				"the SharedSecret %q backing resource name needs to be set", secretShareName)
 		}
 		shareName = secretShareName
 	}
 
-	allowed, err = client.ExecuteSAR(shareName, podNamespace, podName, podSA, kind)
-	if allowed {
-		return cmShare, sShare, nil
+	backingNamespace, backingName := "", ""
+	if cmShare != nil {
+		backingNamespace, backingName = cmShare.Spec.ConfigMapRef.Namespace, cmShare.Spec.ConfigMapRef.Name
+	}
+	if sShare != nil {
+		backingNamespace, backingName = sShare.Spec.SecretRef.Namespace, sShare.Spec.SecretRef.Name
+	}
+	if err = client.ValidateSharePolicy(config.LoadedConfig.SharePolicy, kind, shareName, backingNamespace, backingName); err != nil {
+		auditPublish(req, kind, shareName, err)
+		return nil, nil, "", err
+	}
+	if config.LoadedConfig.SharePolicy.EnforcesOwnerConsent() {
+		// a missing backing resource is reported when its content is mapped to the volume
+		if err = client.ValidateBackingResourceConsent(kind, shareName, backingNamespace, backingName); err != nil && status.Code(err) != codes.NotFound {
+			auditPublish(req, kind, shareName, err)
+			return nil, nil, "", err
+		}
+	}
+
+	annotations := map[string]string{}
+	if cmShare != nil {
+		annotations = cmShare.Annotations
+	}
+	if sShare != nil {
+		annotations = sShare.Annotations
+	}
+
+	if user == nil {
+		user = client.ServiceAccountUser(podNamespace, podSA)
+	}
+	allowed, err = client.ExecuteSARForShareOrAlias(shareName, alias, annotations, podNamespace, podName, user, kind)
+	if !allowed {
+		auditPublish(req, kind, shareName, err)
+		return nil, nil, "", err
+	}
+	if err = client.ValidateConsumer(shareName, annotations, podNamespace, podName, nil); err != nil {
+		auditPublish(req, kind, shareName, err)
+		return nil, nil, "", err
+	}
+	if err = client.ValidateExpiry(shareName, annotations, podNamespace, time.Now()); err != nil {
+		auditPublish(req, kind, shareName, err)
+		return nil, nil, "", err
+	}
+	if err = client.ValidateNotRevoked(shareName, annotations); err != nil {
+		auditPublish(req, kind, shareName, err)
+		return nil, nil, "", err
 	}
-	return nil, nil, err
+	if err = client.ValidateNotSunset(shareName, annotations, time.Now()); err != nil {
+		auditPublish(req, kind, shareName, err)
+		return nil, nil, "", err
+	}
+	auditPublish(req, kind, shareName, nil)
+	return cmShare, sShare, alias, nil
 }
 
 // validateVolumeContext return values:
@@ -182,6 +292,21 @@ func (ns *nodeServer) validateVolumeContext(req *csi.NodePublishVolumeRequest) e
 	return nil
 }
 
+// parseFileMode returns the mode the fileMode volume attribute, an octal number like "0440", sets for the files of
+// the volume, 0 when the attribute is not set
+func parseFileMode(attrib map[string]string) (os.FileMode, error) {
+	fileModeStr, ok := attrib[FileModeAttribute]
+	if !ok {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(fileModeStr, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return 0, status.Errorf(codes.InvalidArgument,
+			"the csi driver volumeAttribute %q has to be a file mode between 0001 and 0777, not %q", FileModeAttribute, fileModeStr)
+	}
+	return os.FileMode(mode), nil
+}
+
 func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
 	var kubeletTargetPath string
 
@@ -204,7 +329,12 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		return nil, err
 	}
 
-	cmShare, sShare, err := ns.validateShare(req)
+	user, err := ns.authenticatePod(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cmShare, sShare, alias, err := ns.validateShare(req, user)
 	if err != nil {
 		return nil, err
 	}
@@ -222,12 +352,19 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 			refresh = r
 		}
 	}
+	fileMode, err := parseFileMode(attrib)
+	if err != nil {
+		return nil, err
+	}
 
 	vol, err := ns.d.createVolume(req.GetVolumeId(), kubeletTargetPath, refresh, req.GetVolumeContext(), cmShare, sShare, maxStorageCapacity, mountAccess)
 	if err != nil && !os.IsExist(err) {
 		klog.Error("ephemeral mode failed to create volume: ", err)
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	vol.SetPodUser(user)
+	vol.SetFileMode(fileMode)
+	vol.SetShareAlias(alias)
 	klog.V(4).Infof("NodePublishVolume created volume: %s", kubeletTargetPath)
 
 	notMnt, err := mount.IsNotMountPoint(ns.mounter, kubeletTargetPath)
@@ -258,8 +395,16 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	volumeId := req.GetVolumeId()
 	mountFlags := req.GetVolumeCapability().GetMount().GetMountFlags()
 
+	// do not log the pod's service account tokens
+	loggedAttrib := map[string]string{}
+	for k, v := range attrib {
+		if k == CSIServiceAccountTokens {
+			v = "<redacted>"
+		}
+		loggedAttrib[k] = v
+	}
 	klog.V(4).Infof("NodePublishVolume %v\nfstype %v\ndevice %v\nvolumeId %v\nattributes %v\nmountflags %v\n",
-		kubeletTargetPath, fsType, deviceId, volumeId, attrib, mountFlags)
+		kubeletTargetPath, fsType, deviceId, volumeId, loggedAttrib, mountFlags)
 
 	mountIDString, bindDir := ns.d.getVolumePath(req.GetVolumeId(), req.GetVolumeContext())
 	if err := ns.readWriteMounter.makeFSMounts(mountIDString, bindDir, kubeletTargetPath, ns.mounter); err != nil {
@@ -282,6 +427,14 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	}
 
 	metrics.IncMountCounters(true)
+	recordVolumeEvent(vol, corev1.EventTypeNormal, ShareMountedReason, "mounted %s %s backed by %s",
+		vol.GetSharedDataKind(), vol.GetSharedDataId(), backingResourceKey(vol.GetSharedDataKind(), vol.GetSharedDataId()))
+	if cmShare != nil {
+		warnDeprecated(vol, cmShare.Annotations, true)
+	}
+	if sShare != nil {
+		warnDeprecated(vol, sShare.Annotations, true)
+	}
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"golang.org/x/net/context"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
 	authorizationv1 "k8s.io/api/authorization/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -617,6 +618,31 @@ func TestNodePublishVolume(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "invalid file mode",
+			secretShare: validSharedSecret,
+			reactor:     acceptReactorFunc,
+			expectedMsg: "has to be a file mode",
+			nodePublishVolReq: csi.NodePublishVolumeRequest{
+				VolumeId:   "testvolid1",
+				Readonly:   true,
+				TargetPath: getTestTargetPath(t),
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+				},
+				VolumeContext: map[string]string{
+					CSIEphemeral:         "true",
+					CSIPodName:           "name1",
+					CSIPodNamespace:      "namespace1",
+					CSIPodUID:            "uid1",
+					CSIPodSA:             "sa1",
+					SharedSecretShareKey: "share1",
+					FileModeAttribute:    "0999",
+				},
+			},
+		},
 	}
 	for ti := range tests {
 		test := &tests[ti]
@@ -667,3 +693,155 @@ func TestNodePublishVolume(t *testing.T) {
 		})
 	}
 }
+
+func TestNodePublishVolumeServiceAccountToken(t *testing.T) {
+	validSharedSecret := &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "share1",
+		},
+		Spec: sharev1alpha1.SharedSecretSpec{
+			SecretRef: sharev1alpha1.SharedSecretReference{
+				Name:      "cool-secret",
+				Namespace: "cool-secret-namespace",
+			},
+		},
+	}
+	tokenReviewReactor := func(user authenticationv1.UserInfo, authenticated bool) fakekubetesting.ReactionFunc {
+		return func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+			tr := action.(fakekubetesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+			if tr.Spec.Token != "token1" {
+				return true, &authenticationv1.TokenReview{}, nil
+			}
+			return true, &authenticationv1.TokenReview{Status: authenticationv1.TokenReviewStatus{Authenticated: authenticated, User: user}}, nil
+		}
+	}
+	podUser := authenticationv1.UserInfo{
+		Username: "system:serviceaccount:namespace1:sa1",
+		Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:namespace1", "system:authenticated"},
+		Extra: map[string]authenticationv1.ExtraValue{
+			"authentication.kubernetes.io/pod-uid":  {"uid1"},
+			"authentication.kubernetes.io/pod-name": {"name1"},
+		},
+	}
+	otherPodUser := *podUser.DeepCopy()
+	otherPodUser.Extra["authentication.kubernetes.io/pod-uid"] = authenticationv1.ExtraValue{"uid2"}
+	otherSAUser := *podUser.DeepCopy()
+	otherSAUser.Username = "system:serviceaccount:namespace1:sa2"
+
+	tests := []struct {
+		name          string
+		tokens        string
+		tokenReactor  fakekubetesting.ReactionFunc
+		expectedMsg   string
+		expectedUser  string
+		expectedExtra bool
+	}{
+		{
+			name:         "no tokens falls back to the service account",
+			expectedMsg:  "PermissionDenied",
+			expectedUser: "system:serviceaccount:namespace1:sa1",
+		},
+		{
+			name:          "valid token",
+			tokens:        `{"": {"token": "token1", "expirationTimestamp": "2021-01-01T00:00:00Z"}}`,
+			tokenReactor:  tokenReviewReactor(podUser, true),
+			expectedMsg:   "PermissionDenied",
+			expectedUser:  "system:serviceaccount:namespace1:sa1",
+			expectedExtra: true,
+		},
+		{
+			name:         "token not authenticated",
+			tokens:       `{"": {"token": "token1"}}`,
+			tokenReactor: tokenReviewReactor(podUser, false),
+			expectedMsg:  "Unauthenticated",
+		},
+		{
+			name:         "token bound to another pod",
+			tokens:       `{"": {"token": "token1"}}`,
+			tokenReactor: tokenReviewReactor(otherPodUser, true),
+			expectedMsg:  "Unauthenticated",
+		},
+		{
+			name:         "token for another service account",
+			tokens:       `{"": {"token": "token1"}}`,
+			tokenReactor: tokenReviewReactor(otherSAUser, true),
+			expectedMsg:  "Unauthenticated",
+		},
+		{
+			name:        "malformed tokens",
+			tokens:      `not json`,
+			expectedMsg: "InvalidArgument",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			targetPath := getTestTargetPath(t)
+			defer os.RemoveAll(targetPath)
+			ns, tmpDir, volPath, err := testNodeServer(t.Name())
+			if err != nil {
+				t.Fatalf("unexpected err %s", err.Error())
+			}
+			defer os.RemoveAll(tmpDir)
+			defer os.RemoveAll(volPath)
+			client.SetSharedSecretsLister(&fakeSharedSecretLister{sShare: validSharedSecret})
+			client.SetSharedConfigMapsLister(&fakeSharedConfigMapLister{})
+
+			var sarSpec *authorizationv1.SubjectAccessReviewSpec
+			kubeClient := fakekubeclientset.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+				sar := action.(fakekubetesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				sarSpec = &sar.Spec
+				return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: false}}, nil
+			})
+			if test.tokenReactor != nil {
+				kubeClient.PrependReactor("create", "tokenreviews", test.tokenReactor)
+			}
+			client.SetClient(kubeClient)
+
+			req := &csi.NodePublishVolumeRequest{
+				VolumeId:   "testvolid1",
+				Readonly:   true,
+				TargetPath: targetPath,
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{},
+					},
+				},
+				VolumeContext: map[string]string{
+					CSIEphemeral:         "true",
+					CSIPodName:           "name1",
+					CSIPodNamespace:      "namespace1",
+					CSIPodUID:            "uid1",
+					CSIPodSA:             "sa1",
+					SharedSecretShareKey: "share1",
+				},
+			}
+			if len(test.tokens) > 0 {
+				req.VolumeContext[CSIServiceAccountTokens] = test.tokens
+			}
+
+			_, err = ns.NodePublishVolume(context.TODO(), req)
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Fatalf("expected err msg containing %s, got: %+v", test.expectedMsg, err)
+			}
+			if len(test.expectedUser) == 0 {
+				if sarSpec != nil {
+					t.Fatalf("unexpected subjectaccessreview %#v", sarSpec)
+				}
+				return
+			}
+			if sarSpec == nil {
+				t.Fatalf("expected a subjectaccessreview")
+			}
+			if sarSpec.User != test.expectedUser {
+				t.Fatalf("expected subjectaccessreview user %s, got %s", test.expectedUser, sarSpec.User)
+			}
+			if test.expectedExtra && (len(sarSpec.Groups) != 3 || sarSpec.Extra["authentication.kubernetes.io/pod-uid"][0] != "uid1") {
+				t.Fatalf("expected subjectaccessreview with the token's groups and extra, got %#v", sarSpec)
+			}
+			if !test.expectedExtra && len(sarSpec.Extra) != 0 {
+				t.Fatalf("unexpected subjectaccessreview extra %#v", sarSpec.Extra)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package csidriver
+
+import (
+	objcache "github.com/openshift/csi-driver-shared-resource/pkg/cache"
+	"github.com/openshift/csi-driver-shared-resource/pkg/notify"
+)
+
+// notifyVolume sends a notification about the volume; backingResource is the namespace/name key of the configmap or
+// secret, looked up from the share when empty
+func notifyVolume(dv *driverVolume, eventType, backingResource, resourceVersion, reason string) {
+	if len(backingResource) == 0 {
+		backingResource = backingResourceKey(dv.GetSharedDataKind(), dv.GetSharedDataId())
+	}
+	backingNamespace, backingName, _ := objcache.SplitKey(backingResource)
+	notify.Notify(eventType, notify.Subject(string(dv.GetSharedDataKind()), dv.GetSharedDataId()), notify.Data{
+		ShareKind:        string(dv.GetSharedDataKind()),
+		Share:            dv.GetSharedDataId(),
+		BackingNamespace: backingNamespace,
+		BackingName:      backingName,
+		ResourceVersion:  resourceVersion,
+		Namespace:        dv.GetPodNamespace(),
+		Pod:              dv.GetPodName(),
+		Reason:           reason,
+	})
+}
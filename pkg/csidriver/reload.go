@@ -0,0 +1,307 @@
+package csidriver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+/*
+Applications which only read their configuration at startup do not pick up refreshed share content.  Pods can opt in,
+or be opted in by the share, with the ReloadAnnotation, to a reload whenever the driver writes new content into one of
+their volumes:
+
+- "annotate" patches the ContentHashAnnotation of the pod, with a hash of the content versions of all its share
+  volumes, for applications or sidecars watching their own annotations through the downward API
+- "restart" restarts the Deployment, StatefulSet or DaemonSet owning the pod, the way "oc rollout restart" does
+
+Reloads are rate limited per pod or workload to one per ReloadMinInterval; changes arriving in the meantime are
+coalesced into the next reload.  A workload's pods usually run on several nodes, whose driver instances all see the
+same change, so restarts are de-duplicated through the RestartedForAnnotation of the pod template: a node only
+restarts the workload for content versions it was not restarted for yet, and the RestartedAtAnnotation extends the
+rate limit to the whole cluster.
+*/
+
+const (
+	// reloadConflictRetry is how soon a reload that lost an update race with another node is evaluated again
+	reloadConflictRetry = time.Second
+)
+
+var (
+	// pendingReloads has a key of the reloaded pod or workload, and a value of struct{} while a reload is scheduled
+	pendingReloads = sync.Map{}
+	// lastReloads has a key of the reloaded pod or workload, and a value of the time.Time of its latest reload, kept
+	// for ReloadMinInterval
+	lastReloads = sync.Map{}
+)
+
+// reloadConsumer reloads, as requested by the pod's or the share's ReloadAnnotation, the pod whose volume got new
+// content
+func reloadConsumer(dv *driverVolume) {
+	mode := ""
+	switch dv.GetSharedDataKind() {
+	case consts.ResourceReferenceTypeSecret:
+		if share := client.GetSharedSecret(dv.GetSharedDataId()); share != nil {
+			mode = share.Annotations[consts.ReloadAnnotation]
+		}
+	case consts.ResourceReferenceTypeConfigMap:
+		if share := client.GetSharedConfigMap(dv.GetSharedDataId()); share != nil {
+			mode = share.Annotations[consts.ReloadAnnotation]
+		}
+	}
+	// the pod is retrieved off the informer's goroutine, which holds the lock of the volume
+	go func() {
+		namespace, name := dv.GetPodNamespace(), dv.GetPodName()
+		pod, err := client.GetPod(namespace, name)
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				klog.Warningf("reloadConsumer unable to get pod %s:%s: %s", namespace, name, err.Error())
+			}
+			return
+		}
+		if podMode, ok := pod.Annotations[consts.ReloadAnnotation]; ok {
+			mode = podMode
+		}
+		switch mode {
+		case "", consts.ReloadNone:
+		case consts.ReloadAnnotate:
+			throttleReload("Pod/"+namespace+"/"+name, func() time.Duration {
+				return annotatePod(dv, namespace, name)
+			})
+		case consts.ReloadRestart:
+			kind, workload, err := podWorkload(pod)
+			if err != nil {
+				recordVolumeEvent(dv, corev1.EventTypeWarning, ShareReloadFailedReason, "unable to restart the workload of the pod: %s", err.Error())
+				return
+			}
+			throttleReload(kind+"/"+namespace+"/"+workload, func() time.Duration {
+				return restartWorkload(dv, kind, namespace, workload, name)
+			})
+		default:
+			recordVolumeEvent(dv, corev1.EventTypeWarning, ShareReloadFailedReason, "invalid %s annotation %q, expected %q, %q or %q",
+				consts.ReloadAnnotation, mode, consts.ReloadAnnotate, consts.ReloadRestart, consts.ReloadNone)
+		}
+	}()
+}
+
+// throttleReload runs the reload of the pod or workload with the given key, at most once per ReloadMinInterval;
+// reload returns how long to wait before evaluating it again, 0 when it is done
+func throttleReload(key string, reload func() time.Duration) {
+	if _, pending := pendingReloads.LoadOrStore(key, struct{}{}); pending {
+		// the pending reload picks up the latest content when it runs
+		return
+	}
+	delay := time.Duration(0)
+	if last, ok := lastReloads.Load(key); ok {
+		delay = config.LoadedConfig.GetReloadMinInterval() - time.Since(last.(time.Time))
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	time.AfterFunc(delay, func() {
+		pendingReloads.Delete(key)
+		if again := reload(); again > 0 {
+			time.AfterFunc(again, func() {
+				throttleReload(key, reload)
+			})
+			return
+		}
+		now := time.Now()
+		lastReloads.Store(key, now)
+		// the latest reload only throttles the next one during ReloadMinInterval, forget it afterwards so that the
+		// map does not grow with every pod the node ever reloaded
+		time.AfterFunc(config.LoadedConfig.GetReloadMinInterval(), func() {
+			lastReloads.CompareAndDelete(key, now)
+		})
+	})
+}
+
+// podContentVersions returns the sorted "kind/share=resourceVersion" content versions of the share volumes of the
+// pod on this node
+func podContentVersions(namespace, name string) []string {
+	versions := []string{}
+	volumes.Range(func(key, value interface{}) bool {
+		dv, ok := value.(*driverVolume)
+		if !ok || dv.GetPodNamespace() != namespace || dv.GetPodName() != name {
+			return true
+		}
+		if resourceVersion := dv.GetResourceVersion(); len(resourceVersion) > 0 {
+			versions = append(versions, string(dv.GetSharedDataKind())+"/"+dv.GetSharedDataId()+"="+resourceVersion)
+		}
+		return true
+	})
+	sort.Strings(versions)
+	return versions
+}
+
+// contentHash hashes the content versions, so that the pods of a workload have the same hash for the same content
+func contentHash(versions []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(versions, ",")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// annotatePod patches the ContentHashAnnotation of the pod with the current content versions of its volumes
+func annotatePod(dv *driverVolume, namespace, name string) time.Duration {
+	versions := podContentVersions(namespace, name)
+	if len(versions) == 0 {
+		return 0
+	}
+	hash := contentHash(versions)
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{consts.ContentHashAnnotation: hash},
+		},
+	})
+	if err != nil {
+		return 0
+	}
+	_, err = client.GetClient().CoreV1().Pods(namespace).Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+	switch {
+	case kerrors.IsNotFound(err):
+		return 0
+	case err != nil:
+		recordVolumeEvent(dv, corev1.EventTypeWarning, ShareReloadFailedReason, "unable to annotate the pod with the content hash %s: %s", hash, err.Error())
+		return 0
+	}
+	recordVolumeEvent(dv, corev1.EventTypeNormal, ShareReloadedReason, "annotated the pod with the content hash %s", hash)
+	return 0
+}
+
+// podWorkload returns the kind and name of the Deployment, StatefulSet or DaemonSet owning the pod
+func podWorkload(pod *corev1.Pod) (string, string, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "", "", fmt.Errorf("the pod has no owner")
+	}
+	switch owner.Kind {
+	case "StatefulSet", "DaemonSet":
+		return owner.Kind, owner.Name, nil
+	case "ReplicaSet":
+		rs, err := client.GetClient().AppsV1().ReplicaSets(pod.Namespace).Get(context.TODO(), owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", "", err
+		}
+		if rsOwner := metav1.GetControllerOf(rs); rsOwner != nil && rsOwner.Kind == "Deployment" {
+			return rsOwner.Kind, rsOwner.Name, nil
+		}
+		return "", "", fmt.Errorf("no Deployment owns the ReplicaSet %s", rs.Name)
+	}
+	return "", "", fmt.Errorf("the pod is owned by %s %s, not by a Deployment, StatefulSet or DaemonSet", owner.Kind, owner.Name)
+}
+
+// parseRestartedFor parses the RestartedForAnnotation into a map of "kind/share" to resourceVersion
+func parseRestartedFor(value string) map[string]string {
+	restartedFor := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		if share, resourceVersion, ok := strings.Cut(entry, "="); ok {
+			restartedFor[share] = resourceVersion
+		}
+	}
+	return restartedFor
+}
+
+// restartTemplate records, in the annotations of the pod template, a restart for the given content versions; it
+// returns whether the template changed, or how long to wait for the cluster wide rate limit
+func restartTemplate(template *corev1.PodTemplateSpec, versions []string, now time.Time) (bool, time.Duration) {
+	restartedFor := parseRestartedFor(template.Annotations[consts.RestartedForAnnotation])
+	changed := false
+	for _, version := range versions {
+		share, resourceVersion, _ := strings.Cut(version, "=")
+		if restartedFor[share] != resourceVersion {
+			restartedFor[share] = resourceVersion
+			changed = true
+		}
+	}
+	if !changed {
+		// another node already restarted the workload for this content
+		return false, 0
+	}
+	if restartedAt, err := time.Parse(time.RFC3339, template.Annotations[consts.RestartedAtAnnotation]); err == nil {
+		if wait := restartedAt.Add(config.LoadedConfig.GetReloadMinInterval()).Sub(now); wait > 0 {
+			return false, wait
+		}
+	}
+	entries := []string{}
+	for share, resourceVersion := range restartedFor {
+		entries = append(entries, share+"="+resourceVersion)
+	}
+	sort.Strings(entries)
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[consts.RestartedForAnnotation] = strings.Join(entries, ",")
+	template.Annotations[consts.RestartedAtAnnotation] = now.UTC().Format(time.RFC3339)
+	return true, 0
+}
+
+// restartWorkload restarts the workload owning the pod for the current content versions of the pod's volumes, unless
+// another node already did
+func restartWorkload(dv *driverVolume, kind, namespace, name, podName string) time.Duration {
+	versions := podContentVersions(namespace, podName)
+	if len(versions) == 0 {
+		// the pod is gone, most likely replaced by an earlier restart
+		return 0
+	}
+	apps := client.GetClient().AppsV1()
+	now := time.Now()
+	changed, wait := false, time.Duration(0)
+	var err error
+	switch kind {
+	case "Deployment":
+		obj, getErr := apps.Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			obj = obj.DeepCopy()
+			if changed, wait = restartTemplate(&obj.Spec.Template, versions, now); changed {
+				_, err = apps.Deployments(namespace).Update(context.TODO(), obj, metav1.UpdateOptions{})
+			}
+		}
+	case "StatefulSet":
+		obj, getErr := apps.StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			obj = obj.DeepCopy()
+			if changed, wait = restartTemplate(&obj.Spec.Template, versions, now); changed {
+				_, err = apps.StatefulSets(namespace).Update(context.TODO(), obj, metav1.UpdateOptions{})
+			}
+		}
+	case "DaemonSet":
+		obj, getErr := apps.DaemonSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			obj = obj.DeepCopy()
+			if changed, wait = restartTemplate(&obj.Spec.Template, versions, now); changed {
+				_, err = apps.DaemonSets(namespace).Update(context.TODO(), obj, metav1.UpdateOptions{})
+			}
+		}
+	}
+	switch {
+	case kerrors.IsConflict(err):
+		// another node updated the workload in the meantime, it may have restarted it for this content already
+		return reloadConflictRetry
+	case kerrors.IsNotFound(err):
+		return 0
+	case err != nil:
+		recordVolumeEvent(dv, corev1.EventTypeWarning, ShareReloadFailedReason, "unable to restart %s %s: %s", kind, name, err.Error())
+		return 0
+	case wait > 0:
+		return wait
+	case changed:
+		recordVolumeEvent(dv, corev1.EventTypeNormal, ShareReloadedReason, "restarted %s %s for the new content of %s",
+			kind, name, strings.Join(versions, ", "))
+	}
+	return 0
+}
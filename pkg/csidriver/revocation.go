@@ -0,0 +1,129 @@
+package csidriver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/audit"
+	objcache "github.com/openshift/csi-driver-shared-resource/pkg/cache"
+	"github.com/openshift/csi-driver-shared-resource/pkg/notify"
+)
+
+/*
+When a pod loses access to a share, removing the content right away can crash applications that still read the files.
+With a revocation grace period configured, the driver instead writes a marker file into the volume, records a Warning
+event on the pod, and only removes the content once the grace period has passed.  If access is restored before then,
+the pending revocation is cancelled and the marker removed.  While the revocation is pending, updates of the backing
+resource are no longer written into the volume.
+
+Pending revocations are only kept in memory; after a driver restart, the next permission check of the volume starts a
+new grace period.
+*/
+
+// RevokedMarkerFile is written into a volume whose share access was revoked, while the content is kept during the
+// revocation grace period
+const RevokedMarkerFile = "..revoked"
+
+var (
+	// pendingRevocations has a key of the CSI volume ID and a value of the *time.Timer that removes the content of the
+	// volume when the revocation grace period ends
+	pendingRevocations = sync.Map{}
+)
+
+// revokeVolumeAccess removes the content of the volume whose pod lost access to the share, right away or once the
+// given revocation grace period ends
+func revokeVolumeAccess(dv *driverVolume, grace time.Duration) {
+	volID := dv.GetVolID()
+	if grace <= 0 {
+		cancelRevocation(volID)
+		// the volume is re-checked on every relist, only report the revocation when there was content to remove
+		if volumeHasContent(dv.GetTargetPath()) {
+			auditVolume(dv, audit.ActionRevoke, "", "", "access revoked", "")
+			notifyVolume(dv, notify.TypeAccessRevoked, "", "", "access revoked")
+			recordVolumeEvent(dv, corev1.EventTypeWarning, ShareAccessRevokedReason,
+				"access to %s %s was revoked, the content of the volume was removed", dv.GetSharedDataKind(), dv.GetSharedDataId())
+		}
+		removeRevokedContent(dv)
+		return
+	}
+	// nothing is left to revoke once the content was removed, be it by an earlier revocation
+	if revocationPending(volID) || !volumeHasContent(dv.GetTargetPath()) {
+		return
+	}
+	auditVolume(dv, audit.ActionRevoke, "", "", fmt.Sprintf("access revoked with a grace period of %s", grace), "")
+	notifyVolume(dv, notify.TypeAccessRevoked, "", "", fmt.Sprintf("access revoked with a grace period of %s", grace))
+
+	deadline := time.Now().Add(grace)
+	targetPath := dv.GetTargetPath()
+	marker := fmt.Sprintf("access to %s %s was revoked, the content of this volume will be removed at %s\n",
+		dv.GetSharedDataKind(), dv.GetSharedDataId(), deadline.Format(time.RFC3339))
+	if err := os.WriteFile(filepath.Join(targetPath, RevokedMarkerFile), []byte(marker), 0644); err != nil {
+		klog.Warningf("revokeVolumeAccess volume %s could not write marker file in %s: %s", volID, targetPath, err.Error())
+	}
+	recordVolumeEvent(dv, corev1.EventTypeWarning, ShareAccessRevokedReason, "access to %s %s was revoked, the content of the volume will be removed at %s",
+		dv.GetSharedDataKind(), dv.GetSharedDataId(), deadline.Format(time.RFC3339))
+	klog.V(0).Infof("revokeVolumeAccess pod %s:%s volume %s content will be removed in %s",
+		dv.GetPodNamespace(), dv.GetPodName(), volID, grace)
+
+	var timer *time.Timer
+	timer = time.AfterFunc(grace, func() {
+		// only remove the content if the revocation was not cancelled in the meantime
+		if obj, ok := pendingRevocations.Load(volID); !ok || obj.(*time.Timer) != timer {
+			return
+		}
+		pendingRevocations.Delete(volID)
+		removeRevokedContent(dv)
+	})
+	pendingRevocations.Store(volID, timer)
+}
+
+// restoreVolumeAccess cancels the pending revocation, if any, of the volume whose pod has access to the share again
+func restoreVolumeAccess(dv *driverVolume) {
+	if !cancelRevocation(dv.GetVolID()) {
+		return
+	}
+	targetPath := dv.GetTargetPath()
+	if err := os.Remove(filepath.Join(targetPath, RevokedMarkerFile)); err != nil && !os.IsNotExist(err) {
+		klog.Warningf("restoreVolumeAccess volume %s could not remove marker file in %s: %s", dv.GetVolID(), targetPath, err.Error())
+	}
+	recordVolumeEvent(dv, corev1.EventTypeNormal, ShareAccessRestoredReason, "access to %s %s was restored before the content of the volume was removed",
+		dv.GetSharedDataKind(), dv.GetSharedDataId())
+	klog.V(0).Infof("restoreVolumeAccess pod %s:%s volume %s revocation cancelled",
+		dv.GetPodNamespace(), dv.GetPodName(), dv.GetVolID())
+}
+
+// revocationPending returns whether the volume's content is kept until the end of the grace period of a revocation
+func revocationPending(volID string) bool {
+	_, pending := pendingRevocations.Load(volID)
+	return pending
+}
+
+// cancelRevocation stops the pending revocation of the volume, returning whether there was one
+func cancelRevocation(volID string) bool {
+	obj, ok := pendingRevocations.LoadAndDelete(volID)
+	if !ok {
+		return false
+	}
+	obj.(*time.Timer).Stop()
+	return true
+}
+
+func removeRevokedContent(dv *driverVolume) {
+	volID := dv.GetVolID()
+	targetPath := dv.GetTargetPath()
+	if err := commonOSRemove(targetPath, "lostPermissions"); err != nil {
+		klog.Warningf("innerShareUpdateRanger %s target path %s delete error %s", volID, targetPath, err.Error())
+	}
+	dv.SetResourceVersion("")
+	auditVolume(dv, audit.ActionRemoveContent, "", "", "access revoked", "")
+	objcache.UnregisterSecretUpsertCallback(volID)
+	objcache.UnregisterSecretDeleteCallback(volID)
+	objcache.UnregisterConfigMapDeleteCallback(volID)
+	objcache.UnregisterConfigMapUpsertCallback(volID)
+}
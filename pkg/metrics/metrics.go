@@ -13,11 +13,29 @@ const (
 	mountCountName        = sharesSubsystem + separator + mount + separator + "requests_total"
 	mountFailureCountName = sharesSubsystem + separator + mount + separator + "failures_total"
 
+	sar                  = "sar"
+	sarCacheHitsName     = sharesSubsystem + separator + sar + separator + "cache_hits_total"
+	sarCacheMissesName   = sharesSubsystem + separator + sar + separator + "cache_misses_total"
+	sarRequestsCountName = sharesSubsystem + separator + sar + separator + "requests_total"
+
+	propagation               = "propagation"
+	propagationConsumersName  = sharesSubsystem + separator + propagation + separator + "consumers"
+	propagationStuckNodesName = sharesSubsystem + separator + propagation + separator + "stuck_nodes"
+
+	deprecation              = "deprecation"
+	deprecationConsumersName = sharesSubsystem + separator + deprecation + separator + "consumers"
+
 	MetricsPort = 6000
 )
 
 var (
 	mountCounter, failedMountCounter = createMountCounters()
+
+	sarCacheHitCounter, sarCacheMissCounter, sarRequestCounter = createSARCounters()
+
+	propagationConsumersGauge, propagationStuckNodesGauge = createPropagationGauges()
+
+	deprecationConsumersGauge = createDeprecationGauge()
 )
 
 func createMountCounters() (prometheus.Counter, prometheus.Counter) {
@@ -31,9 +49,48 @@ func createMountCounters() (prometheus.Counter, prometheus.Counter) {
 		})
 }
 
+func createSARCounters() (prometheus.Counter, prometheus.Counter, *prometheus.CounterVec) {
+	return prometheus.NewCounter(prometheus.CounterOpts{
+			Name: sarCacheHitsName,
+			Help: "Counts share permission checks answered from the node local SubjectAccessReview cache.",
+		}),
+		prometheus.NewCounter(prometheus.CounterOpts{
+			Name: sarCacheMissesName,
+			Help: "Counts share permission checks not found in the node local SubjectAccessReview cache.",
+		}),
+		prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: sarRequestsCountName,
+			Help: "Counts SubjectAccessReviews sent to the API server, by result.",
+		}, []string{"result"})
+}
+
+func createPropagationGauges() (*prometheus.GaugeVec, *prometheus.GaugeVec) {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: propagationConsumersName,
+			Help: "Number of refreshing share volumes holding the current (state=\"current\") or an older (state=\"behind\") content of the backing resource, as published by the share status controller.",
+		}, []string{"kind", "share", "state"}),
+		prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: propagationStuckNodesName,
+			Help: "Number of nodes stuck on an older content of the backing resource of the share, as published by the share status controller.",
+		}, []string{"kind", "share"})
+}
+
+func createDeprecationGauge() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: deprecationConsumersName,
+		Help: "Number of running pods still consuming a deprecated share, as published by the share status controller.",
+	}, []string{"kind", "share"})
+}
+
 func init() {
 	prometheus.MustRegister(mountCounter)
 	prometheus.MustRegister(failedMountCounter)
+	prometheus.MustRegister(sarCacheHitCounter)
+	prometheus.MustRegister(sarCacheMissCounter)
+	prometheus.MustRegister(sarRequestCounter)
+	prometheus.MustRegister(propagationConsumersGauge)
+	prometheus.MustRegister(propagationStuckNodesGauge)
+	prometheus.MustRegister(deprecationConsumersGauge)
 }
 
 func IncMountCounters(succeeded bool) {
@@ -42,3 +99,52 @@ func IncMountCounters(succeeded bool) {
 	}
 	mountCounter.Inc()
 }
+
+func IncSARCacheCounters(hit bool) {
+	if hit {
+		sarCacheHitCounter.Inc()
+		return
+	}
+	sarCacheMissCounter.Inc()
+}
+
+// IncSARRequestCounter counts a SubjectAccessReview API call, where result is one of "allowed", "denied"
+// or "error"
+func IncSARRequestCounter(result string) {
+	sarRequestCounter.WithLabelValues(result).Inc()
+}
+
+// SetSharePropagation publishes how far the content of the backing resource of a share has propagated
+func SetSharePropagation(kind, share string, current, behind, stuckNodes int) {
+	propagationConsumersGauge.WithLabelValues(kind, share, "current").Set(float64(current))
+	propagationConsumersGauge.WithLabelValues(kind, share, "behind").Set(float64(behind))
+	propagationStuckNodesGauge.WithLabelValues(kind, share).Set(float64(stuckNodes))
+}
+
+// DeleteSharePropagation stops publishing the propagation of a share
+func DeleteSharePropagation(kind, share string) {
+	propagationConsumersGauge.DeletePartialMatch(prometheus.Labels{"kind": kind, "share": share})
+	propagationStuckNodesGauge.DeletePartialMatch(prometheus.Labels{"kind": kind, "share": share})
+}
+
+// ResetSharePropagation stops publishing the propagation of every share, when the share status controller stops
+func ResetSharePropagation() {
+	propagationConsumersGauge.Reset()
+	propagationStuckNodesGauge.Reset()
+}
+
+// SetDeprecatedShareConsumers publishes how many pods still consume a deprecated share
+func SetDeprecatedShareConsumers(kind, share string, consumers int) {
+	deprecationConsumersGauge.WithLabelValues(kind, share).Set(float64(consumers))
+}
+
+// DeleteDeprecatedShareConsumers stops publishing the consumers of a share which is no longer deprecated, or deleted
+func DeleteDeprecatedShareConsumers(kind, share string) {
+	deprecationConsumersGauge.DeleteLabelValues(kind, share)
+}
+
+// ResetDeprecatedShareConsumers stops publishing the consumers of every deprecated share, when the share status
+// controller stops
+func ResetDeprecatedShareConsumers() {
+	deprecationConsumersGauge.Reset()
+}
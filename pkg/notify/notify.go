@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/klog/v2"
+)
+
+/*
+The notifier sends CloudEvents, in the HTTP binary content mode, to the endpoints configured on the node, so that
+platform tooling can react to the lifecycle of shares without polling:
+
+- TypeShareCreated, TypeShareUpdated and TypeShareDeleted when the driver's controller syncs a share
+- TypeContentProjected when the content of a backing resource is written to a volume
+- TypeAccessRevoked when a pod loses access to a share
+
+Every endpoint has its own bounded queue, so that an unavailable endpoint does not delay the others; events are
+dropped when the queue of an endpoint is full, and delivered with retries and an exponential backoff otherwise.
+
+Until Configure is called with a notifier, events are dropped.
+*/
+
+// The CloudEvents types of the notifications
+const (
+	TypeShareCreated     = "io.openshift.sharedresource.share.created"
+	TypeShareUpdated     = "io.openshift.sharedresource.share.updated"
+	TypeShareDeleted     = "io.openshift.sharedresource.share.deleted"
+	TypeContentProjected = "io.openshift.sharedresource.content.projected"
+	TypeAccessRevoked    = "io.openshift.sharedresource.access.revoked"
+
+	// sourcePrefix prefixes the node name in the CloudEvents source of the notifications
+	sourcePrefix = "/csi-driver-shared-resource/"
+
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+	requestTimeout = 10 * time.Second
+)
+
+// Data is the JSON payload of a notification
+type Data struct {
+	ShareKind        string `json:"shareKind"`
+	Share            string `json:"share"`
+	BackingNamespace string `json:"backingNamespace,omitempty"`
+	BackingName      string `json:"backingName,omitempty"`
+	ResourceVersion  string `json:"resourceVersion,omitempty"`
+	Namespace        string `json:"namespace,omitempty"`
+	Pod              string `json:"pod,omitempty"`
+	Reason           string `json:"reason,omitempty"`
+}
+
+// Event is a CloudEvent about a share
+type Event struct {
+	ID      string
+	Source  string
+	Type    string
+	Subject string
+	Time    time.Time
+	Data    Data
+}
+
+type endpoint struct {
+	url   string
+	queue chan Event
+}
+
+// Notifier delivers the events to its endpoints
+type Notifier struct {
+	source     string
+	endpoints  []*endpoint
+	maxRetries int
+	client     *http.Client
+	now        func() time.Time
+	backoff    time.Duration
+}
+
+// NewNotifier returns a notifier for the given node, delivering the events to the endpoints with up to maxRetries
+// retries, and queueing up to queueSize events per endpoint
+func NewNotifier(node string, urls []string, queueSize, maxRetries int) *Notifier {
+	n := &Notifier{
+		source:     sourcePrefix + node,
+		maxRetries: maxRetries,
+		client:     &http.Client{Timeout: requestTimeout},
+		now:        time.Now,
+		backoff:    initialBackoff,
+	}
+	for _, url := range urls {
+		n.endpoints = append(n.endpoints, &endpoint{url: url, queue: make(chan Event, queueSize)})
+	}
+	return n
+}
+
+// Run delivers the queued events until stopCh is closed
+func (n *Notifier) Run(stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	wg := sync.WaitGroup{}
+	for _, e := range n.endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-e.queue:
+					n.deliver(ctx, e.url, event)
+				}
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+// Notify queues the event for every endpoint, dropping it for the endpoints whose queue is full
+func (n *Notifier) Notify(eventType, subject string, data Data) {
+	event := Event{
+		ID:      string(uuid.NewUUID()),
+		Source:  n.source,
+		Type:    eventType,
+		Subject: subject,
+		Time:    n.now().UTC(),
+		Data:    data,
+	}
+	for _, e := range n.endpoints {
+		select {
+		case e.queue <- event:
+		default:
+			klog.Warningf("dropping the %s notification of %s for %s, its queue is full", eventType, subject, e.url)
+		}
+	}
+}
+
+// deliver posts the event, retrying network errors, 429 and 5xx responses
+func (n *Notifier) deliver(ctx context.Context, url string, event Event) {
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, url, event)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= n.maxRetries {
+			klog.Warningf("unable to deliver the %s notification of %s to %s: %s", event.Type, event.Subject, url, err.Error())
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// post sends the event in the CloudEvents HTTP binary content mode, returning whether a failure can be retried
+func (n *Notifier) post(ctx context.Context, url string, event Event) (bool, error) {
+	body, err := json.Marshal(event.Data)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("ce-specversion", "1.0")
+	req.Header.Set("ce-id", event.ID)
+	req.Header.Set("ce-source", event.Source)
+	req.Header.Set("ce-type", event.Type)
+	req.Header.Set("ce-subject", event.Subject)
+	req.Header.Set("ce-time", event.Time.Format(time.RFC3339Nano))
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return false, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+var (
+	lock     sync.Mutex
+	notifier *Notifier
+)
+
+// Configure sets the notifier the events are sent with; a nil notifier disables the notifications
+func Configure(n *Notifier) {
+	lock.Lock()
+	defer lock.Unlock()
+	notifier = n
+}
+
+// Notify queues the event with the configured notifier, if any; subject identifies the share as "kind/name"
+func Notify(eventType, subject string, data Data) {
+	lock.Lock()
+	n := notifier
+	lock.Unlock()
+	if n == nil {
+		return
+	}
+	n.Notify(eventType, subject, data)
+}
+
+// Subject returns the CloudEvents subject of the notifications about a share
+func Subject(kind, share string) string {
+	return kind + "/" + share
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotifier(t *testing.T) {
+	received := make(chan *http.Request, 10)
+	bodies := make(chan Data, 10)
+	failures := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		data := Data{}
+		json.NewDecoder(r.Body).Decode(&data)
+		received <- r
+		bodies <- data
+	}))
+	defer server.Close()
+
+	n := NewNotifier("node1", []string{server.URL}, 10, 3)
+	n.backoff = time.Millisecond
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go n.Run(stopCh)
+	Configure(n)
+	defer Configure(nil)
+
+	Notify(TypeAccessRevoked, Subject("Secret", "share1"), Data{ShareKind: "Secret", Share: "share1", Namespace: "ns1", Pod: "pod1"})
+	select {
+	case r := <-received:
+		if r.Header.Get("ce-specversion") != "1.0" || r.Header.Get("ce-type") != TypeAccessRevoked ||
+			r.Header.Get("ce-source") != "/csi-driver-shared-resource/node1" || r.Header.Get("ce-subject") != "Secret/share1" ||
+			len(r.Header.Get("ce-id")) == 0 || r.Header.Get("Content-Type") != "application/json" {
+			t.Fatalf("unexpected headers %#v", r.Header)
+		}
+		if data := <-bodies; data.Pod != "pod1" || data.Share != "share1" {
+			t.Fatalf("unexpected data %#v", data)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the notification was not delivered after retries")
+	}
+}
+
+func TestNotifierBoundedQueue(t *testing.T) {
+	n := NewNotifier("node1", []string{"http://127.0.0.1:0"}, 2, 0)
+	for i := 0; i < 5; i++ {
+		n.Notify(TypeShareUpdated, Subject("Secret", "share1"), Data{})
+	}
+	if len(n.endpoints[0].queue) != 2 {
+		t.Fatalf("expected the queue to be bounded, got %d events", len(n.endpoints[0].queue))
+	}
+}
@@ -0,0 +1,157 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func backingResource(kind consts.ResourceReferenceType) string {
+	if kind == consts.ResourceReferenceTypeSecret {
+		return "secrets"
+	}
+	return "configmaps"
+}
+
+// backingResourceAvailable also returns the resourceVersion of the backing resource, empty when it could not be
+// retrieved
+func (c *Controller) backingResourceAvailable(kind consts.ResourceReferenceType, namespace, name string) (metav1.Condition, string) {
+	condition := metav1.Condition{Type: consts.BackingResourceAvailableCondition}
+	var obj metav1.Object
+	var err error
+	switch kind {
+	case consts.ResourceReferenceTypeSecret:
+		obj, err = c.kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	case consts.ResourceReferenceTypeConfigMap:
+		obj, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	resourceVersion := ""
+	switch {
+	case err == nil:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Found"
+		condition.Message = fmt.Sprintf("%s %s/%s exists", kind, namespace, name)
+		resourceVersion = obj.GetResourceVersion()
+	case kerrors.IsNotFound(err):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NotFound"
+		condition.Message = fmt.Sprintf("%s %s/%s does not exist", kind, namespace, name)
+	case kerrors.IsForbidden(err):
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "Forbidden"
+		condition.Message = fmt.Sprintf("the driver is not allowed to get %s %s/%s", kind, namespace, name)
+	default:
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "Error"
+		condition.Message = fmt.Sprintf("unable to get %s %s/%s: %s", kind, namespace, name, err.Error())
+	}
+	return condition, resourceVersion
+}
+
+// driverCanRead checks, with SelfSubjectAccessReviews as the controller runs with the driver's service account, that
+// the driver can get the backing resource, and list and watch the namespace for its refreshes
+func (c *Controller) driverCanRead(kind consts.ResourceReferenceType, namespace, name string) metav1.Condition {
+	condition := metav1.Condition{Type: consts.DriverCanReadCondition}
+	denied := []string{}
+	for _, verb := range []string{"get", "list", "watch"} {
+		attributes := &authorizationv1.ResourceAttributes{
+			Namespace: namespace,
+			Verb:      verb,
+			Resource:  backingResource(kind),
+		}
+		if verb == "get" {
+			attributes.Name = name
+		}
+		ssar := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attributes},
+		}
+		resp, err := c.kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(context.TODO(), ssar, metav1.CreateOptions{})
+		if err != nil {
+			condition.Status = metav1.ConditionUnknown
+			condition.Reason = "Error"
+			condition.Message = fmt.Sprintf("unable to check whether the driver can %s %s in namespace %s: %s", verb, backingResource(kind), namespace, err.Error())
+			return condition
+		}
+		if !resp.Status.Allowed {
+			denied = append(denied, verb)
+		}
+	}
+	if len(denied) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Forbidden"
+		condition.Message = fmt.Sprintf("the driver is not allowed to %s %s in namespace %s", strings.Join(denied, ", "), backingResource(kind), namespace)
+		return condition
+	}
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "Allowed"
+	condition.Message = fmt.Sprintf("the driver can read %s %s/%s", kind, namespace, name)
+	return condition
+}
+
+func (c *Controller) reservedNameValid(key shareKey, namespace, name string) metav1.Condition {
+	condition := metav1.Condition{Type: consts.ReservedNameValidCondition}
+	valid := true
+	switch key.kind {
+	case consts.ResourceReferenceTypeSecret:
+		valid = c.rn.ValidateSharedSecretOpenShiftName(key.name, namespace, name)
+	case consts.ResourceReferenceTypeConfigMap:
+		valid = c.rn.ValidateSharedConfigMapOpenShiftName(key.name, namespace, name)
+	}
+	if !valid {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ReservedName"
+		condition.Message = fmt.Sprintf("share %s violates the OpenShift reserved name list", key.name)
+		return condition
+	}
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "Valid"
+	condition.Message = fmt.Sprintf("share %s respects the OpenShift reserved name list", key.name)
+	return condition
+}
+
+// inUse counts the scheduled and not terminated pods consuming the share, by its name or one of its aliases, and
+// the nodes they run on, also returning the number of pods
+func (c *Controller) inUse(key shareKey, annotations map[string]string) (metav1.Condition, int) {
+	condition := metav1.Condition{Type: consts.InUseCondition}
+	objs := []interface{}{}
+	for _, name := range append([]string{key.name}, client.ShareAliases(annotations)...) {
+		named, err := c.podInformer.GetIndexer().ByIndex(shareIndex, shareKey{kind: key.kind, name: name}.String())
+		if err != nil {
+			condition.Status = metav1.ConditionUnknown
+			condition.Reason = "Error"
+			condition.Message = err.Error()
+			return condition, 0
+		}
+		objs = append(objs, named...)
+	}
+	// a pod referencing the share by several names is counted once
+	consuming := map[string]struct{}{}
+	nodes := map[string]struct{}{}
+	for _, obj := range objs {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || len(pod.Spec.NodeName) == 0 || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		consuming[pod.Namespace+"/"+pod.Name] = struct{}{}
+		nodes[pod.Spec.NodeName] = struct{}{}
+	}
+	pods := len(consuming)
+	if pods == 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NotConsumed"
+		condition.Message = "no pod consumes the share"
+		return condition, 0
+	}
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "Consumed"
+	condition.Message = fmt.Sprintf("%d pods on %d nodes consume the share", pods, len(nodes))
+	return condition, pods
+}
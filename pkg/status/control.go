@@ -0,0 +1,103 @@
+package status
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+	"github.com/openshift/csi-driver-shared-resource/pkg/control"
+)
+
+// controlAcknowledged counts the nodes which acknowledged the control tokens of the share; it returns false when the
+// share carries no token, and so has no ControlAcknowledged condition
+func (c *Controller) controlAcknowledged(key shareKey, tokens control.Tokens) (metav1.Condition, bool) {
+	if tokens == (control.Tokens{}) {
+		return metav1.Condition{}, false
+	}
+	condition := metav1.Condition{Type: consts.ControlAcknowledgedCondition}
+	cms, err := c.ackLister.ConfigMaps(c.namespace).List(labels.Everything())
+	if err != nil {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "Error"
+		condition.Message = err.Error()
+		return condition, true
+	}
+	progress := control.Aggregate(key.kind, key.name, tokens, cms)
+	acks := []string{}
+	if len(tokens.RefreshNow) > 0 {
+		acks = append(acks, fmt.Sprintf("refresh-now token %q acknowledged by %d/%d nodes", tokens.RefreshNow, progress.RefreshNowAcked, progress.Nodes))
+	}
+	if len(tokens.RevokeAll) > 0 {
+		acks = append(acks, fmt.Sprintf("revoke-all token %q acknowledged by %d/%d nodes", tokens.RevokeAll, progress.RevokeAllAcked, progress.Nodes))
+	}
+	condition.Message = strings.Join(acks, ", ")
+	if progress.Done() {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Acknowledged"
+		return condition, true
+	}
+	condition.Status = metav1.ConditionFalse
+	condition.Reason = "Pending"
+	condition.Message = fmt.Sprintf("%s, pending on nodes: %s", condition.Message, strings.Join(progress.PendingNodeNames, ", "))
+	return condition, true
+}
+
+// ackEventHandler queues the shares whose acknowledgements changed on a node
+func (c *Controller) ackEventHandler() cache.ResourceEventHandlerFuncs {
+	queue := func(o, n interface{}) {
+		oldData, newData := map[string]string{}, map[string]string{}
+		if cm, ok := o.(*corev1.ConfigMap); ok {
+			oldData = cm.Data
+		}
+		if cm, ok := n.(*corev1.ConfigMap); ok {
+			newData = cm.Data
+		}
+		for _, data := range []map[string]string{oldData, newData} {
+			for ackKey := range data {
+				if oldData[ackKey] == newData[ackKey] {
+					continue
+				}
+				if kind, name, ok := control.ParseAckKey(ackKey); ok {
+					c.shareWorkqueue.Add(shareKey{kind: kind, name: name})
+				}
+			}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(o interface{}) {
+			// a new node has yet to acknowledge the tokens of every share
+			c.queueSharesWithTokens()
+			queue(nil, o)
+		},
+		UpdateFunc: queue,
+		DeleteFunc: func(o interface{}) {
+			if tombstone, ok := o.(cache.DeletedFinalStateUnknown); ok {
+				o = tombstone.Obj
+			}
+			// a removed node no longer counts for the tokens of any share
+			c.queueSharesWithTokens()
+			queue(o, nil)
+		},
+	}
+}
+
+// queueSharesWithTokens queues the shares carrying control tokens
+func (c *Controller) queueSharesWithTokens() {
+	sharedSecrets, _ := c.sharedSecretLister.List(labels.Everything())
+	for _, share := range sharedSecrets {
+		if control.ShareTokens(share.Annotations) != (control.Tokens{}) {
+			c.shareWorkqueue.Add(shareKey{kind: consts.ResourceReferenceTypeSecret, name: share.Name})
+		}
+	}
+	sharedConfigMaps, _ := c.sharedConfigMapLister.List(labels.Everything())
+	for _, share := range sharedConfigMaps {
+		if control.ShareTokens(share.Annotations) != (control.Tokens{}) {
+			c.shareWorkqueue.Add(shareKey{kind: consts.ResourceReferenceTypeConfigMap, name: share.Name})
+		}
+	}
+}
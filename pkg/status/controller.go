@@ -0,0 +1,414 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	sharev1clientset "github.com/openshift/client-go/sharedresource/clientset/versioned"
+	shareinformer "github.com/openshift/client-go/sharedresource/informers/externalversions"
+	sharelisters "github.com/openshift/client-go/sharedresource/listers/sharedresource/v1alpha1"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+	"github.com/openshift/csi-driver-shared-resource/pkg/control"
+	"github.com/openshift/csi-driver-shared-resource/pkg/metrics"
+	"github.com/openshift/csi-driver-shared-resource/pkg/usage"
+)
+
+/*
+The status controller maintains the conditions of every SharedSecret and SharedConfigMap, so that administrators can
+spot broken shares with "oc get sharedsecrets" or "oc get sharedconfigmaps":
+
+- BackingResourceAvailable, whether the referenced Secret or ConfigMap exists
+- DriverCanRead, whether the driver's service account can get the backing resource, and list and watch its namespace
+- ReservedNameValid, whether the share respects the OpenShift reserved names
+- InUse, how many pods consume the share, and on how many nodes
+- ContentPropagated, how many of the volumes refreshing the content of the share hold the current version of the
+  backing resource, as published in the node usage reports, and which nodes are stuck on older content
+- ControlAcknowledged, only while the share carries control tokens, how many nodes acknowledged them, see the control
+  package
+
+It also aggregates the usage reports the driver instances publish for their node into the cluster wide usage
+report, see the usage package.
+
+Only one instance runs at a time in the cluster, see RunLeaderElected.  A share is re-evaluated when it changes, when a
+pod consuming it changes, and on every relist, which is when changes to the backing resource or to the driver's
+permissions are picked up.
+*/
+
+const (
+	sharedConfigMapShareKey = "sharedConfigMap"
+	sharedSecretShareKey    = "sharedSecret"
+
+	// shareIndex indexes the pods by the shares their volumes reference
+	shareIndex = "share"
+)
+
+type shareKey struct {
+	kind consts.ResourceReferenceType
+	name string
+}
+
+func (k shareKey) String() string {
+	return string(k.kind) + "/" + k.name
+}
+
+// usageReportKey is queued when the cluster wide usage report has to be aggregated again
+type usageReportKey struct{}
+
+type Controller struct {
+	kubeClient  kubernetes.Interface
+	shareClient sharev1clientset.Interface
+	rn          *config.ReservedNames
+	namespace   string
+
+	shareWorkqueue workqueue.TypedRateLimitingInterface[any]
+
+	shareInformerFactory shareinformer.SharedInformerFactory
+	podInformerFactory   informers.SharedInformerFactory
+	usageInformerFactory informers.SharedInformerFactory
+	ackInformerFactory   informers.SharedInformerFactory
+
+	sharedConfigMapInformer cache.SharedIndexInformer
+	sharedSecretInformer    cache.SharedIndexInformer
+	podInformer             cache.SharedIndexInformer
+	usageInformer           cache.SharedIndexInformer
+	ackInformer             cache.SharedIndexInformer
+
+	sharedConfigMapLister sharelisters.SharedConfigMapLister
+	sharedSecretLister    sharelisters.SharedSecretLister
+	usageLister           corelisters.ConfigMapLister
+	ackLister             corelisters.ConfigMapLister
+
+	// the following are only accessed by the single worker of the workqueue
+	parsedNodeReports map[string]parsedNodeReport
+	consumerVersions  map[shareKey]string
+	behindSince       map[shareKey]map[string]time.Time
+}
+
+// NewController instantiates a share status controller which re-evaluates every share at the given relist interval,
+// and aggregates the usage reports in the given namespace
+func NewController(kubeClient kubernetes.Interface, shareClient sharev1clientset.Interface, rn *config.ReservedNames, shareRelist time.Duration, namespace string) (*Controller, error) {
+	shareInformerFactory := shareinformer.NewSharedInformerFactoryWithOptions(shareClient, shareRelist)
+	podInformerFactory := informers.NewSharedInformerFactory(kubeClient, shareRelist)
+	usageInformerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, shareRelist,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = usage.ReportLabel + "=" + usage.ReportLabelNode
+		}))
+	ackInformerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, shareRelist,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = control.AckLabel + "=" + control.AckLabelNode
+		}))
+	c := &Controller{
+		kubeClient:  kubeClient,
+		shareClient: shareClient,
+		rn:          rn,
+		namespace:   namespace,
+		shareWorkqueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[any](),
+			"shared-resource-status"),
+		shareInformerFactory:    shareInformerFactory,
+		podInformerFactory:      podInformerFactory,
+		usageInformerFactory:    usageInformerFactory,
+		ackInformerFactory:      ackInformerFactory,
+		sharedConfigMapInformer: shareInformerFactory.Sharedresource().V1alpha1().SharedConfigMaps().Informer(),
+		sharedSecretInformer:    shareInformerFactory.Sharedresource().V1alpha1().SharedSecrets().Informer(),
+		podInformer:             podInformerFactory.Core().V1().Pods().Informer(),
+		usageInformer:           usageInformerFactory.Core().V1().ConfigMaps().Informer(),
+		ackInformer:             ackInformerFactory.Core().V1().ConfigMaps().Informer(),
+		sharedConfigMapLister:   shareInformerFactory.Sharedresource().V1alpha1().SharedConfigMaps().Lister(),
+		sharedSecretLister:      shareInformerFactory.Sharedresource().V1alpha1().SharedSecrets().Lister(),
+		usageLister:             usageInformerFactory.Core().V1().ConfigMaps().Lister(),
+		ackLister:               ackInformerFactory.Core().V1().ConfigMaps().Lister(),
+		parsedNodeReports:       map[string]parsedNodeReport{},
+		consumerVersions:        map[shareKey]string{},
+		behindSince:             map[shareKey]map[string]time.Time{},
+	}
+	if err := c.podInformer.AddIndexers(cache.Indexers{shareIndex: podShareIndexFunc}); err != nil {
+		return nil, err
+	}
+	// the shares are indexed by their aliases so that the pods still referencing a former name count for the share
+	if err := c.sharedConfigMapInformer.AddIndexers(cache.Indexers{client.AliasIndex: client.SharedConfigMapAliasIndexFunc}); err != nil {
+		return nil, err
+	}
+	if err := c.sharedSecretInformer.AddIndexers(cache.Indexers{client.AliasIndex: client.SharedSecretAliasIndexFunc}); err != nil {
+		return nil, err
+	}
+	c.sharedConfigMapInformer.AddEventHandler(c.shareEventHandler())
+	c.sharedSecretInformer.AddEventHandler(c.shareEventHandler())
+	c.podInformer.AddEventHandler(c.podEventHandler())
+	c.usageInformer.AddEventHandler(c.usageEventHandler())
+	c.ackInformer.AddEventHandler(c.ackEventHandler())
+	return c, nil
+}
+
+func (c *Controller) Run(stopCh <-chan struct{}) error {
+	defer c.shareWorkqueue.ShutDown()
+	// the next leader publishes the propagation and deprecation metrics
+	defer metrics.ResetSharePropagation()
+	defer metrics.ResetDeprecatedShareConsumers()
+
+	c.shareInformerFactory.Start(stopCh)
+	c.podInformerFactory.Start(stopCh)
+	c.usageInformerFactory.Start(stopCh)
+	c.ackInformerFactory.Start(stopCh)
+
+	if !cache.WaitForCacheSync(stopCh, c.sharedConfigMapInformer.HasSynced, c.sharedSecretInformer.HasSynced) {
+		return fmt.Errorf("failed to wait for share caches to sync")
+	}
+	if !cache.WaitForCacheSync(stopCh, c.podInformer.HasSynced) {
+		return fmt.Errorf("failed to wait for pod caches to sync")
+	}
+	if !cache.WaitForCacheSync(stopCh, c.usageInformer.HasSynced) {
+		return fmt.Errorf("failed to wait for usage report caches to sync")
+	}
+	if !cache.WaitForCacheSync(stopCh, c.ackInformer.HasSynced) {
+		return fmt.Errorf("failed to wait for acknowledgement caches to sync")
+	}
+
+	klog.Info("Starting the share status controller")
+	go wait.Until(c.shareEventProcessor, time.Second, stopCh)
+
+	<-stopCh
+	return nil
+}
+
+// shareKeys returns the shares referenced by the volumes of our driver in the pod
+func shareKeys(pod *corev1.Pod) []shareKey {
+	keys := []shareKey{}
+	for _, volume := range pod.Spec.Volumes {
+		csi := volume.VolumeSource.CSI
+		if csi == nil || csi.Driver != string(operatorv1.SharedResourcesCSIDriver) {
+			continue
+		}
+		if name := csi.VolumeAttributes[sharedConfigMapShareKey]; len(name) > 0 {
+			keys = append(keys, shareKey{kind: consts.ResourceReferenceTypeConfigMap, name: name})
+		}
+		if name := csi.VolumeAttributes[sharedSecretShareKey]; len(name) > 0 {
+			keys = append(keys, shareKey{kind: consts.ResourceReferenceTypeSecret, name: name})
+		}
+	}
+	return keys
+}
+
+func podShareIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return []string{}, nil
+	}
+	indexKeys := []string{}
+	for _, key := range shareKeys(pod) {
+		indexKeys = append(indexKeys, key.String())
+	}
+	return indexKeys, nil
+}
+
+func (c *Controller) shareEventHandler() cache.ResourceEventHandlerFuncs {
+	add := func(o interface{}) {
+		switch v := o.(type) {
+		case *sharev1alpha1.SharedConfigMap:
+			c.shareWorkqueue.Add(shareKey{kind: consts.ResourceReferenceTypeConfigMap, name: v.Name})
+		case *sharev1alpha1.SharedSecret:
+			c.shareWorkqueue.Add(shareKey{kind: consts.ResourceReferenceTypeSecret, name: v.Name})
+		default:
+			//log unrecognized type
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(o interface{}) {
+			add(o)
+			c.shareWorkqueue.Add(usageReportKey{})
+		},
+		UpdateFunc: func(o, n interface{}) {
+			add(n)
+		},
+		DeleteFunc: func(o interface{}) {
+			c.shareWorkqueue.Add(usageReportKey{})
+		},
+	}
+}
+
+func (c *Controller) usageEventHandler() cache.ResourceEventHandlerFuncs {
+	add := func(o interface{}) {
+		c.shareWorkqueue.Add(usageReportKey{})
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: add,
+		UpdateFunc: func(o, n interface{}) {
+			add(n)
+		},
+		DeleteFunc: add,
+	}
+}
+
+// renamedShareKeys returns the shares listing the name of the given share among their aliases
+func (c *Controller) renamedShareKeys(key shareKey) []shareKey {
+	indexer := c.sharedSecretInformer.GetIndexer()
+	if key.kind == consts.ResourceReferenceTypeConfigMap {
+		indexer = c.sharedConfigMapInformer.GetIndexer()
+	}
+	objs, err := indexer.ByIndex(client.AliasIndex, key.name)
+	if err != nil {
+		return nil
+	}
+	keys := []shareKey{}
+	for _, obj := range objs {
+		if share, ok := obj.(metav1.Object); ok {
+			keys = append(keys, shareKey{kind: key.kind, name: share.GetName()})
+		}
+	}
+	return keys
+}
+
+func (c *Controller) podEventHandler() cache.ResourceEventHandlerFuncs {
+	add := func(o interface{}) {
+		pod, ok := o.(*corev1.Pod)
+		if !ok {
+			return
+		}
+		for _, key := range shareKeys(pod) {
+			c.shareWorkqueue.Add(key)
+			for _, renamed := range c.renamedShareKeys(key) {
+				c.shareWorkqueue.Add(renamed)
+			}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: add,
+		UpdateFunc: func(o, n interface{}) {
+			oldPod, _ := o.(*corev1.Pod)
+			newPod, _ := n.(*corev1.Pod)
+			// only scheduling and termination change the consumer counts
+			if oldPod != nil && newPod != nil && oldPod.Spec.NodeName == newPod.Spec.NodeName && oldPod.Status.Phase == newPod.Status.Phase {
+				return
+			}
+			add(n)
+		},
+		DeleteFunc: func(o interface{}) {
+			switch v := o.(type) {
+			case cache.DeletedFinalStateUnknown:
+				add(v.Obj)
+			default:
+				add(o)
+			}
+		},
+	}
+}
+
+func (c *Controller) shareEventProcessor() {
+	for {
+		obj, shutdown := c.shareWorkqueue.Get()
+		if shutdown {
+			return
+		}
+
+		func() {
+			defer c.shareWorkqueue.Done(obj)
+
+			var err error
+			switch key := obj.(type) {
+			case shareKey:
+				if err = c.syncShareStatus(key); err != nil {
+					klog.Warningf("unable to update the status of %s share %s: %s", key.kind, key.name, err.Error())
+				}
+			case usageReportKey:
+				if err = c.syncUsageReport(); err != nil {
+					klog.Warningf("unable to aggregate the usage report: %s", err.Error())
+				}
+			}
+
+			if err != nil {
+				c.shareWorkqueue.AddRateLimited(obj)
+			} else {
+				c.shareWorkqueue.Forget(obj)
+			}
+		}()
+	}
+}
+
+func (c *Controller) syncShareStatus(key shareKey) error {
+	klog.V(4).Infof("status check of %s share %s", key.kind, key.name)
+	switch key.kind {
+	case consts.ResourceReferenceTypeSecret:
+		share, err := c.sharedSecretLister.Get(key.name)
+		if kerrors.IsNotFound(err) {
+			c.forgetPropagation(key)
+			metrics.DeleteDeprecatedShareConsumers(string(key.kind), key.name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		conditions := append([]metav1.Condition{}, share.Status.Conditions...)
+		if !c.setConditions(&conditions, key, share.Spec.SecretRef.Namespace, share.Spec.SecretRef.Name, share.Annotations, share.Generation) {
+			return nil
+		}
+		share = share.DeepCopy()
+		share.Status.Conditions = conditions
+		_, err = c.shareClient.SharedresourceV1alpha1().SharedSecrets().UpdateStatus(context.TODO(), share, metav1.UpdateOptions{})
+		return err
+	case consts.ResourceReferenceTypeConfigMap:
+		share, err := c.sharedConfigMapLister.Get(key.name)
+		if kerrors.IsNotFound(err) {
+			c.forgetPropagation(key)
+			metrics.DeleteDeprecatedShareConsumers(string(key.kind), key.name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		conditions := append([]metav1.Condition{}, share.Status.Conditions...)
+		if !c.setConditions(&conditions, key, share.Spec.ConfigMapRef.Namespace, share.Spec.ConfigMapRef.Name, share.Annotations, share.Generation) {
+			return nil
+		}
+		share = share.DeepCopy()
+		share.Status.Conditions = conditions
+		_, err = c.shareClient.SharedresourceV1alpha1().SharedConfigMaps().UpdateStatus(context.TODO(), share, metav1.UpdateOptions{})
+		return err
+	}
+	return nil
+}
+
+// setConditions evaluates the conditions of the share, returning whether any of them changed
+func (c *Controller) setConditions(conditions *[]metav1.Condition, key shareKey, namespace, name string, annotations map[string]string, generation int64) bool {
+	changed := false
+	backingResourceAvailable, resourceVersion := c.backingResourceAvailable(key.kind, namespace, name)
+	inUse, consumers := c.inUse(key, annotations)
+	evaluated := []metav1.Condition{
+		backingResourceAvailable,
+		c.driverCanRead(key.kind, namespace, name),
+		c.reservedNameValid(key, namespace, name),
+		inUse,
+		c.contentPropagated(key, resourceVersion),
+	}
+	publishDeprecation(key, annotations, consumers)
+	if condition, ok := c.controlAcknowledged(key, control.ShareTokens(annotations)); ok {
+		evaluated = append(evaluated, condition)
+	} else if meta.RemoveStatusCondition(conditions, consts.ControlAcknowledgedCondition) {
+		changed = true
+	}
+	for _, condition := range evaluated {
+		condition.ObservedGeneration = generation
+		if meta.SetStatusCondition(conditions, condition) {
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,267 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	fakekubetesting "k8s.io/client-go/testing"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	fakeshareclientset "github.com/openshift/client-go/sharedresource/clientset/versioned/fake"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+	"github.com/openshift/csi-driver-shared-resource/pkg/control"
+	"github.com/openshift/csi-driver-shared-resource/pkg/usage"
+)
+
+func consumingPod(name, node string, phase corev1.PodPhase, shareName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: name},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+			Volumes: []corev1.Volume{{
+				Name: "share",
+				VolumeSource: corev1.VolumeSource{CSI: &corev1.CSIVolumeSource{
+					Driver:           string(operatorv1.SharedResourcesCSIDriver),
+					VolumeAttributes: map[string]string{sharedSecretShareKey: shareName},
+				}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestSyncShareStatus(t *testing.T) {
+	share := &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: "share1", Generation: 2},
+		Spec: sharev1alpha1.SharedSecretSpec{
+			SecretRef: sharev1alpha1.SharedSecretReference{Namespace: "ns2", Name: "secret1"},
+		},
+	}
+	kubeClient := fakekubeclientset.NewSimpleClientset()
+	listAllowed := false
+	kubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		ssar := action.(fakekubetesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		allowed := ssar.Spec.ResourceAttributes.Verb == "get" || listAllowed
+		return true, &authorizationv1.SelfSubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}}, nil
+	})
+	shareClient := fakeshareclientset.NewSimpleClientset(share)
+	c, err := NewController(kubeClient, shareClient, config.SetupNameReservation(), time.Minute, "driver-namespace")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	c.sharedSecretInformer.GetIndexer().Add(share)
+	c.podInformer.GetIndexer().Add(consumingPod("pod1", "node1", corev1.PodRunning, "share1"))
+	c.podInformer.GetIndexer().Add(consumingPod("pod2", "node2", corev1.PodRunning, "share1"))
+	c.podInformer.GetIndexer().Add(consumingPod("pod3", "node2", corev1.PodSucceeded, "share1"))
+	c.podInformer.GetIndexer().Add(consumingPod("pod4", "", corev1.PodPending, "share1"))
+	c.podInformer.GetIndexer().Add(consumingPod("pod5", "node1", corev1.PodRunning, "share2"))
+
+	key := shareKey{kind: consts.ResourceReferenceTypeSecret, name: "share1"}
+	if err = c.syncShareStatus(key); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	updated, err := shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), "share1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	for _, expected := range []struct {
+		conditionType string
+		status        metav1.ConditionStatus
+		reason        string
+		message       string
+	}{
+		{conditionType: consts.BackingResourceAvailableCondition, status: metav1.ConditionFalse, reason: "NotFound"},
+		{conditionType: consts.DriverCanReadCondition, status: metav1.ConditionFalse, reason: "Forbidden",
+			message: "the driver is not allowed to list, watch secrets in namespace ns2"},
+		{conditionType: consts.ReservedNameValidCondition, status: metav1.ConditionTrue, reason: "Valid"},
+		{conditionType: consts.InUseCondition, status: metav1.ConditionTrue, reason: "Consumed",
+			message: "2 pods on 2 nodes consume the share"},
+		{conditionType: consts.ContentPropagatedCondition, status: metav1.ConditionUnknown, reason: "BackingResourceUnavailable"},
+	} {
+		condition := meta.FindStatusCondition(updated.Status.Conditions, expected.conditionType)
+		if condition == nil {
+			t.Fatalf("missing condition %s in %#v", expected.conditionType, updated.Status.Conditions)
+		}
+		if condition.Status != expected.status || condition.Reason != expected.reason || condition.ObservedGeneration != 2 ||
+			(len(expected.message) > 0 && condition.Message != expected.message) {
+			t.Errorf("unexpected condition %#v", condition)
+		}
+	}
+
+	// the backing secret is created and the driver is granted access
+	kubeClient.CoreV1().Secrets("ns2").Create(context.TODO(), &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "secret1"}}, metav1.CreateOptions{})
+	listAllowed = true
+	c.sharedSecretInformer.GetIndexer().Update(updated)
+	if err = c.syncShareStatus(key); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	updated, _ = shareClient.SharedresourceV1alpha1().SharedSecrets().Get(context.TODO(), "share1", metav1.GetOptions{})
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, consts.BackingResourceAvailableCondition) ||
+		!meta.IsStatusConditionTrue(updated.Status.Conditions, consts.DriverCanReadCondition) {
+		t.Fatalf("unexpected conditions %#v", updated.Status.Conditions)
+	}
+
+	// an unchanged status is not written again
+	c.sharedSecretInformer.GetIndexer().Update(updated)
+	shareClient.ClearActions()
+	if err = c.syncShareStatus(key); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(shareClient.Actions()) > 0 {
+		t.Fatalf("unexpected actions %#v", shareClient.Actions())
+	}
+}
+
+func TestInUseAliases(t *testing.T) {
+	share := &sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: "share-2027", Annotations: map[string]string{consts.AliasesAnnotation: "share"}},
+	}
+	c, err := NewController(fakekubeclientset.NewSimpleClientset(), fakeshareclientset.NewSimpleClientset(share), config.SetupNameReservation(), time.Minute, "driver-namespace")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	c.sharedSecretInformer.GetIndexer().Add(share)
+	c.podInformer.GetIndexer().Add(consumingPod("pod1", "node1", corev1.PodRunning, "share-2027"))
+	c.podInformer.GetIndexer().Add(consumingPod("pod2", "node2", corev1.PodRunning, "share"))
+
+	key := shareKey{kind: consts.ResourceReferenceTypeSecret, name: "share-2027"}
+	condition, consumers := c.inUse(key, share.Annotations)
+	if condition.Status != metav1.ConditionTrue || consumers != 2 || condition.Message != "2 pods on 2 nodes consume the share" {
+		t.Fatalf("unexpected condition %#v with %d consumers", condition, consumers)
+	}
+	// the pods referencing the former name update the renamed share
+	renamed := c.renamedShareKeys(shareKey{kind: consts.ResourceReferenceTypeSecret, name: "share"})
+	if len(renamed) != 1 || renamed[0] != key {
+		t.Fatalf("unexpected renamed shares %v", renamed)
+	}
+}
+
+func TestContentPropagated(t *testing.T) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "secret1", ResourceVersion: "2"}}
+	kubeClient := fakekubeclientset.NewSimpleClientset(secret)
+	c, err := NewController(kubeClient, fakeshareclientset.NewSimpleClientset(), config.SetupNameReservation(), time.Minute, "driver-namespace")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	setNodeReport := func(node string, resourceVersions ...string) {
+		nodeReport := usage.NodeReport{Node: node}
+		for i, resourceVersion := range resourceVersions {
+			nodeReport.Volumes = append(nodeReport.Volumes, usage.VolumeUsage{ShareKind: consts.ResourceReferenceTypeSecret,
+				Share: "share1", Namespace: "ns1", Pod: fmt.Sprintf("%s-pod%d", node, i), Refresh: true, ResourceVersion: resourceVersion})
+		}
+		data, _ := json.Marshal(nodeReport)
+		c.usageInformer.GetIndexer().Update(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "driver-namespace", Name: usage.NodeReportPrefix + node, ResourceVersion: strings.Join(resourceVersions, ",")},
+			Data:       map[string]string{usage.ReportDataKey: string(data)},
+		})
+	}
+	key := shareKey{kind: consts.ResourceReferenceTypeSecret, name: "share1"}
+	check := func(status metav1.ConditionStatus, reason, message string) {
+		t.Helper()
+		_, resourceVersion := c.backingResourceAvailable(key.kind, "ns2", "secret1")
+		condition := c.contentPropagated(key, resourceVersion)
+		if condition.Status != status || condition.Reason != reason || condition.Message != message {
+			t.Fatalf("unexpected condition %#v", condition)
+		}
+	}
+
+	setNodeReport("node1", "2", "2")
+	setNodeReport("node2", "1")
+	check(metav1.ConditionFalse, "Propagating", "2/3 consumers at version 2")
+
+	// node2 stays behind for too long
+	c.behindSince[key]["node2"] = time.Now().Add(-stuckAfter)
+	check(metav1.ConditionFalse, "Stuck", "2/3 consumers at version 2, nodes stuck on older content: node2")
+
+	setNodeReport("node2", "2")
+	check(metav1.ConditionTrue, "Propagated", "3/3 consumers at version 2")
+	if len(c.behindSince) > 0 {
+		t.Fatalf("unexpected nodes behind %#v", c.behindSince)
+	}
+}
+
+func TestControlAcknowledged(t *testing.T) {
+	c, err := NewController(fakekubeclientset.NewSimpleClientset(), fakeshareclientset.NewSimpleClientset(), config.SetupNameReservation(), time.Minute, "driver-namespace")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	key := shareKey{kind: consts.ResourceReferenceTypeSecret, name: "share1"}
+	setAck := func(node string, tokens control.Tokens) {
+		data, _ := json.Marshal(control.Acknowledgement{Tokens: tokens})
+		c.ackInformer.GetIndexer().Update(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "driver-namespace", Name: control.AckPrefix + node},
+			Data:       map[string]string{control.AckKey(key.kind, key.name): string(data)},
+		})
+	}
+	tokens := control.Tokens{RefreshNow: "1", RevokeAll: "incident-1"}
+	check := func(status metav1.ConditionStatus, reason, message string) {
+		t.Helper()
+		condition, ok := c.controlAcknowledged(key, tokens)
+		if !ok || condition.Status != status || condition.Reason != reason || condition.Message != message {
+			t.Fatalf("unexpected condition %#v", condition)
+		}
+	}
+
+	if _, ok := c.controlAcknowledged(key, control.Tokens{}); ok {
+		t.Fatalf("a share without tokens should not have the condition")
+	}
+	setAck("node1", tokens)
+	setAck("node2", control.Tokens{RefreshNow: "1"})
+	check(metav1.ConditionFalse, "Pending", `refresh-now token "1" acknowledged by 2/2 nodes, revoke-all token "incident-1" acknowledged by 1/2 nodes, pending on nodes: node2`)
+
+	setAck("node2", tokens)
+	check(metav1.ConditionTrue, "Acknowledged", `refresh-now token "1" acknowledged by 2/2 nodes, revoke-all token "incident-1" acknowledged by 2/2 nodes`)
+}
+
+func TestSyncUsageReport(t *testing.T) {
+	kubeClient := fakekubeclientset.NewSimpleClientset()
+	shareClient := fakeshareclientset.NewSimpleClientset()
+	c, err := NewController(kubeClient, shareClient, config.SetupNameReservation(), time.Minute, "driver-namespace")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	c.sharedSecretInformer.GetIndexer().Add(&sharev1alpha1.SharedSecret{ObjectMeta: metav1.ObjectMeta{Name: "used"}})
+	c.sharedSecretInformer.GetIndexer().Add(&sharev1alpha1.SharedSecret{ObjectMeta: metav1.ObjectMeta{Name: "unused"}})
+	nodeReport, _ := json.Marshal(usage.NodeReport{Node: "node1", Volumes: []usage.VolumeUsage{
+		{ShareKind: consts.ResourceReferenceTypeSecret, Share: "used", Namespace: "ns1", Pod: "pod1"},
+	}})
+	c.usageInformer.GetIndexer().Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "driver-namespace", Name: usage.NodeReportPrefix + "node1"},
+		Data:       map[string]string{usage.ReportDataKey: string(nodeReport)},
+	})
+
+	for i := 0; i < 2; i++ {
+		kubeClient.ClearActions()
+		if err = c.syncUsageReport(); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+	// the unchanged report is only read the second time
+	if len(kubeClient.Actions()) != 1 || kubeClient.Actions()[0].GetVerb() != "get" {
+		t.Fatalf("unexpected actions %#v", kubeClient.Actions())
+	}
+	cm, err := kubeClient.CoreV1().ConfigMaps("driver-namespace").Get(context.TODO(), usage.ReportName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	report := usage.Report{}
+	if err = json.Unmarshal([]byte(cm.Data[usage.ReportDataKey]), &report); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(report.Shares) != 1 || report.Shares[0].Name != "used" || len(report.UnusedShares) != 1 || report.UnusedShares[0].Name != "unused" {
+		t.Fatalf("unexpected report %#v", report)
+	}
+}
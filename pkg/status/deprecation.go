@@ -0,0 +1,16 @@
+package status
+
+import (
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/metrics"
+)
+
+// publishDeprecation publishes the number of pods still consuming the share while it is deprecated, so that its owner
+// can follow the migration of the consumers before the sunset
+func publishDeprecation(key shareKey, annotations map[string]string, consumers int) {
+	if deprecation, err := client.ShareDeprecation(annotations); err != nil || deprecation == nil {
+		metrics.DeleteDeprecatedShareConsumers(string(key.kind), key.name)
+		return
+	}
+	metrics.SetDeprecatedShareConsumers(string(key.kind), key.name, consumers)
+}
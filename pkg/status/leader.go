@@ -0,0 +1,75 @@
+package status
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+
+	sharev1clientset "github.com/openshift/client-go/sharedresource/clientset/versioned"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+)
+
+const (
+	// LeaseName is the name of the Lease, in the driver's namespace, that elects the driver instance running the
+	// share status controller
+	LeaseName = "csi-driver-shared-resource-status"
+
+	leaseDuration = 137 * time.Second
+	renewDeadline = 107 * time.Second
+	retryPeriod   = 26 * time.Second
+)
+
+// RunLeaderElected competes, as identity, for the leadership of the share status controller, and runs a new
+// controller for every term it leads, until stopCh is closed
+func RunLeaderElected(kubeClient kubernetes.Interface, shareClient sharev1clientset.Interface, rn *config.ReservedNames, shareRelist time.Duration, namespace, identity string, stopCh <-chan struct{}) error {
+	lock, err := resourcelock.New(resourcelock.LeasesResourceLock, namespace, LeaseName, kubeClient.CoreV1(),
+		kubeClient.CoordinationV1(), resourcelock.ResourceLockConfig{Identity: identity})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	// a lost leadership only stops the controller, the driver keeps serving volumes and competes again
+	for ctx.Err() == nil {
+		// the term ends when the controller stops, so that the lease is released for another instance to take over
+		// rather than being renewed by an instance no longer running the controller
+		termCtx, termCancel := context.WithCancel(ctx)
+		leaderelection.RunOrDie(termCtx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   leaseDuration,
+			RenewDeadline:   renewDeadline,
+			RetryPeriod:     retryPeriod,
+			ReleaseOnCancel: true,
+			Name:            LeaseName,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(leaderCtx context.Context) {
+					defer termCancel()
+					klog.Infof("%s leads the share status controller", identity)
+					c, err := NewController(kubeClient, shareClient, rn, shareRelist, namespace)
+					if err != nil {
+						klog.Errorf("unable to set up the share status controller: %s", err.Error())
+						return
+					}
+					if err = c.Run(leaderCtx.Done()); err != nil {
+						klog.Errorf("share status controller exited: %s", err.Error())
+					}
+				},
+				OnStoppedLeading: func() {
+					klog.Infof("%s no longer leads the share status controller", identity)
+				},
+			},
+		})
+		termCancel()
+	}
+	return nil
+}
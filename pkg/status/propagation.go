@@ -0,0 +1,96 @@
+package status
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+	"github.com/openshift/csi-driver-shared-resource/pkg/metrics"
+	"github.com/openshift/csi-driver-shared-resource/pkg/usage"
+)
+
+// stuckAfter is how long a node can hold an older content of a backing resource before it is flagged as stuck; it
+// covers the time the driver instance of the node takes to pick up the change and to publish its usage report
+const stuckAfter = 5 * time.Minute
+
+// contentPropagated compares the resourceVersion of the backing resource with the ones the node usage reports
+// publish for the volumes consuming the share, flagging the nodes which have been behind for longer than stuckAfter
+func (c *Controller) contentPropagated(key shareKey, resourceVersion string) metav1.Condition {
+	condition := metav1.Condition{Type: consts.ContentPropagatedCondition}
+	if len(resourceVersion) == 0 {
+		c.forgetPropagation(key)
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "BackingResourceUnavailable"
+		condition.Message = "the propagation of the content is unknown while the backing resource is unavailable"
+		return condition
+	}
+	nodeReports, err := c.nodeReports()
+	if err != nil {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "Error"
+		condition.Message = err.Error()
+		return condition
+	}
+	propagation := usage.Propagate(nodeReports, usage.ShareReference{Kind: key.kind, Name: key.name}, resourceVersion)
+
+	// only the nodes continuously behind since stuckAfter are stuck, a change of the content in the meantime does
+	// not reset their clock
+	now := time.Now()
+	behindSince := map[string]time.Time{}
+	stuck := []string{}
+	var recheck time.Duration
+	for node := range propagation.Behind {
+		since, ok := c.behindSince[key][node]
+		if !ok {
+			since = now
+		}
+		behindSince[node] = since
+		if elapsed := now.Sub(since); elapsed >= stuckAfter {
+			stuck = append(stuck, node)
+		} else if recheck == 0 || stuckAfter-elapsed < recheck {
+			recheck = stuckAfter - elapsed
+		}
+	}
+	sort.Strings(stuck)
+	if len(behindSince) > 0 {
+		c.behindSince[key] = behindSince
+	} else {
+		delete(c.behindSince, key)
+	}
+	if recheck > 0 {
+		c.shareWorkqueue.AddAfter(key, recheck)
+	}
+	metrics.SetSharePropagation(string(key.kind), key.name, propagation.Current, propagation.Total-propagation.Current, len(stuck))
+
+	progress := fmt.Sprintf("%d/%d consumers at version %s", propagation.Current, propagation.Total, resourceVersion)
+	switch {
+	case propagation.Total == 0:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "NoRefreshingConsumers"
+		condition.Message = "no volume refreshing the content consumes the share"
+	case propagation.Current == propagation.Total:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Propagated"
+		condition.Message = progress
+	case len(stuck) > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Stuck"
+		condition.Message = fmt.Sprintf("%s, nodes stuck on older content: %s", progress, strings.Join(stuck, ", "))
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Propagating"
+		condition.Message = progress
+	}
+	return condition
+}
+
+// forgetPropagation drops the propagation tracking of a share which is deleted, or whose backing resource is
+// unavailable
+func (c *Controller) forgetPropagation(key shareKey) {
+	delete(c.behindSince, key)
+	metrics.DeleteSharePropagation(string(key.kind), key.name)
+}
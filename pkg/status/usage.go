@@ -0,0 +1,128 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+	"github.com/openshift/csi-driver-shared-resource/pkg/usage"
+)
+
+// parsedNodeReport caches a node usage report as of the resourceVersion of its ConfigMap
+type parsedNodeReport struct {
+	resourceVersion string
+	report          usage.NodeReport
+}
+
+// nodeReports lists the node usage reports, only parsing the ConfigMaps which changed since the previous call
+func (c *Controller) nodeReports() ([]usage.NodeReport, error) {
+	cms, err := c.usageLister.ConfigMaps(c.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	nodeReports := []usage.NodeReport{}
+	parsed := map[string]parsedNodeReport{}
+	for _, cm := range cms {
+		cached, ok := c.parsedNodeReports[cm.Name]
+		if !ok || cached.resourceVersion != cm.ResourceVersion {
+			cached = parsedNodeReport{resourceVersion: cm.ResourceVersion}
+			if err := json.Unmarshal([]byte(cm.Data[usage.ReportDataKey]), &cached.report); err != nil {
+				klog.Warningf("ignoring the invalid usage report %s: %s", cm.Name, err.Error())
+				continue
+			}
+		}
+		parsed[cm.Name] = cached
+		nodeReports = append(nodeReports, cached.report)
+	}
+	c.parsedNodeReports = parsed
+	return nodeReports, nil
+}
+
+// queueChangedPropagations queues the shares whose consumers moved to another content version since the previous
+// call, so that their ContentPropagated condition follows the node usage reports
+func (c *Controller) queueChangedPropagations(nodeReports []usage.NodeReport) {
+	versions := map[shareKey][]string{}
+	for _, nodeReport := range nodeReports {
+		for _, volume := range nodeReport.Volumes {
+			key := shareKey{kind: volume.ShareKind, name: volume.Share}
+			versions[key] = append(versions[key], nodeReport.Node+"/"+volume.Namespace+"/"+volume.Pod+"="+volume.ResourceVersion)
+		}
+	}
+	consumerVersions := map[shareKey]string{}
+	for key, v := range versions {
+		sort.Strings(v)
+		consumerVersions[key] = strings.Join(v, ",")
+	}
+	for key, v := range consumerVersions {
+		if c.consumerVersions[key] != v {
+			c.shareWorkqueue.Add(key)
+		}
+	}
+	for key := range c.consumerVersions {
+		if _, ok := consumerVersions[key]; !ok {
+			c.shareWorkqueue.Add(key)
+		}
+	}
+	c.consumerVersions = consumerVersions
+}
+
+// syncUsageReport aggregates the node usage reports into the cluster wide usage report, only writing it when it
+// changed
+func (c *Controller) syncUsageReport() error {
+	nodeReports, err := c.nodeReports()
+	if err != nil {
+		return err
+	}
+	c.queueChangedPropagations(nodeReports)
+
+	shares := []usage.ShareReference{}
+	sharedSecrets, err := c.sharedSecretLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, share := range sharedSecrets {
+		shares = append(shares, usage.ShareReference{Kind: consts.ResourceReferenceTypeSecret, Name: share.Name})
+	}
+	sharedConfigMaps, err := c.sharedConfigMapLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, share := range sharedConfigMaps {
+		shares = append(shares, usage.ShareReference{Kind: consts.ResourceReferenceTypeConfigMap, Name: share.Name})
+	}
+
+	data, err := json.Marshal(usage.Aggregate(nodeReports, shares))
+	if err != nil {
+		return err
+	}
+	report, err := c.kubeClient.CoreV1().ConfigMaps(c.namespace).Get(context.TODO(), usage.ReportName, metav1.GetOptions{})
+	switch {
+	case kerrors.IsNotFound(err):
+		report = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: c.namespace,
+				Name:      usage.ReportName,
+				Labels:    map[string]string{usage.ReportLabel: usage.ReportLabelCluster},
+			},
+			Data: map[string]string{usage.ReportDataKey: string(data)},
+		}
+		_, err = c.kubeClient.CoreV1().ConfigMaps(c.namespace).Create(context.TODO(), report, metav1.CreateOptions{})
+		return err
+	case err != nil:
+		return err
+	case report.Data[usage.ReportDataKey] == string(data):
+		return nil
+	}
+	report = report.DeepCopy()
+	report.Data = map[string]string{usage.ReportDataKey: string(data)}
+	_, err = c.kubeClient.CoreV1().ConfigMaps(c.namespace).Update(context.TODO(), report, metav1.UpdateOptions{})
+	return err
+}
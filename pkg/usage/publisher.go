@@ -0,0 +1,87 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Publisher periodically publishes the usage report of the volumes of a node, only writing the report ConfigMap
+// when the set of volumes changed since the last publication
+type Publisher struct {
+	kubeClient kubernetes.Interface
+	namespace  string
+	node       string
+	volumes    func() []VolumeUsage
+	now        func() time.Time
+
+	published []VolumeUsage
+}
+
+// NewPublisher returns a publisher of the volumes, as listed by the volumes function, of the given node
+func NewPublisher(kubeClient kubernetes.Interface, namespace, node string, volumes func() []VolumeUsage) *Publisher {
+	return &Publisher{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		node:       node,
+		volumes:    volumes,
+		now:        time.Now,
+	}
+}
+
+// Run publishes the node usage report at every interval until stopCh is closed
+func (p *Publisher) Run(interval time.Duration, stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if err := p.Publish(); err != nil {
+			klog.Warningf("unable to publish the usage report of node %s: %s", p.node, err.Error())
+		}
+	}, interval, stopCh)
+}
+
+// Publish writes the node usage report if the volumes changed since the last publication
+func (p *Publisher) Publish() error {
+	report := NodeReport{Node: p.node, Time: p.now().UTC(), Volumes: p.volumes()}
+	report.Sort()
+	if p.published != nil && reflect.DeepEqual(p.published, report.Volumes) {
+		return nil
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: p.namespace,
+			Name:      NodeReportPrefix + p.node,
+			Labels:    map[string]string{ReportLabel: ReportLabelNode},
+		},
+		Data: map[string]string{ReportDataKey: string(data)},
+	}
+	// the report is garbage collected along with its node
+	if node, err := p.kubeClient.CoreV1().Nodes().Get(context.TODO(), p.node, metav1.GetOptions{}); err == nil {
+		cm.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "v1",
+			Kind:       "Node",
+			Name:       node.Name,
+			UID:        node.UID,
+		}}
+	}
+	_, err = p.kubeClient.CoreV1().ConfigMaps(p.namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	if kerrors.IsNotFound(err) {
+		_, err = p.kubeClient.CoreV1().ConfigMaps(p.namespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	klog.V(4).Infof("published the usage report of node %s with %d volumes", p.node, len(report.Volumes))
+	p.published = report.Volumes
+	return nil
+}
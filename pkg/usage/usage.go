@@ -0,0 +1,191 @@
+package usage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+/*
+Each driver instance publishes the volumes it serves on its node as a node usage report, a ConfigMap in the driver's
+namespace labelled with ReportLabel, so that "who uses this share?" can be answered without going through the volume
+map of every node.  The share status controller aggregates the node reports into the ConfigMap named ReportName,
+which lists the consumers of every share, and the shares no pod consumes.
+*/
+
+const (
+	// ReportName is the name of the ConfigMap, in the driver's namespace, holding the cluster wide usage report
+	ReportName = "csi-driver-shared-resource-usage"
+	// NodeReportPrefix prefixes the node name in the name of the ConfigMap holding a node usage report
+	NodeReportPrefix = ReportName + "-"
+	// ReportDataKey is the ConfigMap key holding the JSON report
+	ReportDataKey = "usage.json"
+	// ReportLabel labels the usage report ConfigMaps with either ReportLabelNode or ReportLabelCluster
+	ReportLabel        = "sharedresource.openshift.io/usage-report"
+	ReportLabelNode    = "node"
+	ReportLabelCluster = "cluster"
+)
+
+// VolumeUsage is a volume of a pod consuming a share
+type VolumeUsage struct {
+	ShareKind consts.ResourceReferenceType `json:"shareKind"`
+	Share     string                       `json:"share"`
+	Namespace string                       `json:"namespace"`
+	Pod       string                       `json:"pod"`
+	// Refresh is whether the volume picks up the changes of the backing resource
+	Refresh    bool      `json:"refresh"`
+	LastUpdate time.Time `json:"lastUpdate"`
+	// ResourceVersion is the resourceVersion of the backing resource whose content the volume holds, empty when it
+	// holds none
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// NodeReport is the usage report published by the driver instance of a node
+type NodeReport struct {
+	Node    string        `json:"node"`
+	Time    time.Time     `json:"time"`
+	Volumes []VolumeUsage `json:"volumes"`
+}
+
+// Consumer is a pod consuming a share in the cluster wide report
+type Consumer struct {
+	Node            string    `json:"node"`
+	Namespace       string    `json:"namespace"`
+	Pod             string    `json:"pod"`
+	LastUpdate      time.Time `json:"lastUpdate"`
+	ResourceVersion string    `json:"resourceVersion,omitempty"`
+}
+
+// ShareReference identifies a share in the cluster wide report
+type ShareReference struct {
+	Kind consts.ResourceReferenceType `json:"kind"`
+	Name string                       `json:"name"`
+}
+
+// ShareUsage lists the consumers of a share
+type ShareUsage struct {
+	ShareReference
+	Consumers []Consumer `json:"consumers"`
+}
+
+// Report is the cluster wide usage report, aggregated per share
+type Report struct {
+	// Time is the time of the latest node report
+	Time time.Time `json:"time"`
+	// Nodes are the nodes whose report was aggregated
+	Nodes []string `json:"nodes"`
+	// Shares are the shares with at least one consumer
+	Shares []ShareUsage `json:"shares"`
+	// UnusedShares are the shares no pod consumes
+	UnusedShares []ShareReference `json:"unusedShares"`
+}
+
+// Sort orders the volumes of the node report, so that an unchanged set of volumes results in the same report
+func (r *NodeReport) Sort() {
+	sort.Slice(r.Volumes, func(i, j int) bool {
+		a, b := r.Volumes[i], r.Volumes[j]
+		if a.ShareKind != b.ShareKind {
+			return a.ShareKind < b.ShareKind
+		}
+		if a.Share != b.Share {
+			return a.Share < b.Share
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Pod < b.Pod
+	})
+}
+
+// Aggregate builds the cluster wide report from the node reports and the existing shares; the report is as of the
+// latest node report, so that aggregating unchanged node reports results in the same report
+func Aggregate(nodeReports []NodeReport, shares []ShareReference) Report {
+	report := Report{
+		Nodes:        []string{},
+		Shares:       []ShareUsage{},
+		UnusedShares: []ShareReference{},
+	}
+	consumers := map[ShareReference][]Consumer{}
+	for _, nodeReport := range nodeReports {
+		report.Nodes = append(report.Nodes, nodeReport.Node)
+		if nodeReport.Time.After(report.Time) {
+			report.Time = nodeReport.Time
+		}
+		for _, volume := range nodeReport.Volumes {
+			ref := ShareReference{Kind: volume.ShareKind, Name: volume.Share}
+			consumers[ref] = append(consumers[ref], Consumer{
+				Node:            nodeReport.Node,
+				Namespace:       volume.Namespace,
+				Pod:             volume.Pod,
+				LastUpdate:      volume.LastUpdate,
+				ResourceVersion: volume.ResourceVersion,
+			})
+		}
+	}
+	sort.Strings(report.Nodes)
+	for _, ref := range shares {
+		if _, ok := consumers[ref]; !ok {
+			report.UnusedShares = append(report.UnusedShares, ref)
+		}
+	}
+	// shares that were deleted while pods still consume them are reported too
+	for ref, c := range consumers {
+		sort.Slice(c, func(i, j int) bool {
+			if c[i].Namespace != c[j].Namespace {
+				return c[i].Namespace < c[j].Namespace
+			}
+			if c[i].Pod != c[j].Pod {
+				return c[i].Pod < c[j].Pod
+			}
+			return c[i].Node < c[j].Node
+		})
+		report.Shares = append(report.Shares, ShareUsage{ShareReference: ref, Consumers: c})
+	}
+	sort.Slice(report.Shares, func(i, j int) bool {
+		return lessShareReference(report.Shares[i].ShareReference, report.Shares[j].ShareReference)
+	})
+	sort.Slice(report.UnusedShares, func(i, j int) bool {
+		return lessShareReference(report.UnusedShares[i], report.UnusedShares[j])
+	})
+	return report
+}
+
+// Propagation is how far the content of a backing resource has propagated to the volumes consuming its share
+type Propagation struct {
+	// Current is the number of volumes holding the content at the resourceVersion
+	Current int
+	// Total is the number of volumes expected to hold the content at the resourceVersion, that is the volumes
+	// refreshing their content which hold some; volumes with refresh disabled, or whose content was removed, are not
+	// expected to pick up the changes
+	Total int
+	// Behind maps the nodes holding older content to how many of their volumes do
+	Behind map[string]int
+}
+
+// Propagate computes the propagation, as of the node reports, of the content of the share's backing resource at the
+// given resourceVersion
+func Propagate(nodeReports []NodeReport, ref ShareReference, resourceVersion string) Propagation {
+	propagation := Propagation{Behind: map[string]int{}}
+	for _, nodeReport := range nodeReports {
+		for _, volume := range nodeReport.Volumes {
+			if volume.ShareKind != ref.Kind || volume.Share != ref.Name || !volume.Refresh || len(volume.ResourceVersion) == 0 {
+				continue
+			}
+			propagation.Total++
+			if volume.ResourceVersion == resourceVersion {
+				propagation.Current++
+				continue
+			}
+			propagation.Behind[nodeReport.Node]++
+		}
+	}
+	return propagation
+}
+
+func lessShareReference(a, b ShareReference) bool {
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	return a.Name < b.Name
+}
@@ -0,0 +1,132 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+func TestAggregate(t *testing.T) {
+	t1 := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+	report := Aggregate([]NodeReport{
+		{Node: "node2", Time: t2, Volumes: []VolumeUsage{
+			{ShareKind: consts.ResourceReferenceTypeSecret, Share: "share1", Namespace: "ns1", Pod: "pod2", LastUpdate: t1},
+		}},
+		{Node: "node1", Time: t1, Volumes: []VolumeUsage{
+			{ShareKind: consts.ResourceReferenceTypeSecret, Share: "share1", Namespace: "ns1", Pod: "pod1", LastUpdate: t1},
+			{ShareKind: consts.ResourceReferenceTypeConfigMap, Share: "deleted", Namespace: "ns2", Pod: "pod3", LastUpdate: t1},
+		}},
+	}, []ShareReference{
+		{Kind: consts.ResourceReferenceTypeSecret, Name: "share1"},
+		{Kind: consts.ResourceReferenceTypeSecret, Name: "unused"},
+		{Kind: consts.ResourceReferenceTypeConfigMap, Name: "share1"},
+	})
+
+	expected := Report{
+		Time:  t2,
+		Nodes: []string{"node1", "node2"},
+		Shares: []ShareUsage{
+			{
+				ShareReference: ShareReference{Kind: consts.ResourceReferenceTypeConfigMap, Name: "deleted"},
+				Consumers:      []Consumer{{Node: "node1", Namespace: "ns2", Pod: "pod3", LastUpdate: t1}},
+			},
+			{
+				ShareReference: ShareReference{Kind: consts.ResourceReferenceTypeSecret, Name: "share1"},
+				Consumers: []Consumer{
+					{Node: "node1", Namespace: "ns1", Pod: "pod1", LastUpdate: t1},
+					{Node: "node2", Namespace: "ns1", Pod: "pod2", LastUpdate: t1},
+				},
+			},
+		},
+		UnusedShares: []ShareReference{
+			{Kind: consts.ResourceReferenceTypeConfigMap, Name: "share1"},
+			{Kind: consts.ResourceReferenceTypeSecret, Name: "unused"},
+		},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Fatalf("unexpected report\n%#v\nexpected\n%#v", report, expected)
+	}
+}
+
+func TestPublisher(t *testing.T) {
+	kubeClient := fakekubeclientset.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", UID: "node1-uid"}})
+	volumes := []VolumeUsage{
+		{ShareKind: consts.ResourceReferenceTypeSecret, Share: "share2", Namespace: "ns1", Pod: "pod1"},
+		{ShareKind: consts.ResourceReferenceTypeSecret, Share: "share1", Namespace: "ns1", Pod: "pod1"},
+	}
+	p := NewPublisher(kubeClient, "driver-namespace", "node1", func() []VolumeUsage {
+		return append([]VolumeUsage{}, volumes...)
+	})
+
+	if err := p.Publish(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	cm, err := kubeClient.CoreV1().ConfigMaps("driver-namespace").Get(context.TODO(), NodeReportPrefix+"node1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if cm.Labels[ReportLabel] != ReportLabelNode || len(cm.OwnerReferences) != 1 || cm.OwnerReferences[0].UID != "node1-uid" {
+		t.Fatalf("unexpected node report metadata %#v", cm.ObjectMeta)
+	}
+	report := NodeReport{}
+	if err = json.Unmarshal([]byte(cm.Data[ReportDataKey]), &report); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if report.Node != "node1" || len(report.Volumes) != 2 || report.Volumes[0].Share != "share1" {
+		t.Fatalf("unexpected node report %#v", report)
+	}
+
+	// an unchanged set of volumes, in whatever order, is not published again
+	volumes[0], volumes[1] = volumes[1], volumes[0]
+	kubeClient.ClearActions()
+	if err = p.Publish(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(kubeClient.Actions()) > 0 {
+		t.Fatalf("unexpected actions %#v", kubeClient.Actions())
+	}
+
+	volumes = volumes[:1]
+	if err = p.Publish(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	cm, _ = kubeClient.CoreV1().ConfigMaps("driver-namespace").Get(context.TODO(), NodeReportPrefix+"node1", metav1.GetOptions{})
+	json.Unmarshal([]byte(cm.Data[ReportDataKey]), &report)
+	if len(report.Volumes) != 1 {
+		t.Fatalf("unexpected node report %#v", report)
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	volume := func(share, pod string, refresh bool, resourceVersion string) VolumeUsage {
+		return VolumeUsage{ShareKind: consts.ResourceReferenceTypeSecret, Share: share, Namespace: "ns1", Pod: pod,
+			Refresh: refresh, ResourceVersion: resourceVersion}
+	}
+	propagation := Propagate([]NodeReport{
+		{Node: "node1", Volumes: []VolumeUsage{
+			volume("share1", "pod1", true, "2"),
+			volume("share1", "pod2", true, "1"),
+			volume("share1", "pod3", false, "1"),
+			volume("share2", "pod4", true, "1"),
+		}},
+		{Node: "node2", Volumes: []VolumeUsage{
+			volume("share1", "pod5", true, "2"),
+			// revoked volumes hold no content
+			volume("share1", "pod6", true, ""),
+		}},
+	}, ShareReference{Kind: consts.ResourceReferenceTypeSecret, Name: "share1"}, "2")
+
+	expected := Propagation{Current: 2, Total: 3, Behind: map[string]int{"node1": 1}}
+	if !reflect.DeepEqual(propagation, expected) {
+		t.Fatalf("unexpected propagation %#v, expected %#v", propagation, expected)
+	}
+}
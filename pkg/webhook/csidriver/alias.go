@@ -0,0 +1,51 @@
+package csidriver
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// validateShareAliases checks the aliases of a share are valid share names which no other share claims, as the
+// driver cannot pick the share an ambiguous alias resolves to; an alias still naming an existing share is only
+// warned about, as the alias resolves once that share is deleted, which is how a share is renamed
+func validateShareAliases(kind consts.ResourceReferenceType, shareName string, annotations map[string]string) ([]string, error) {
+	warnings := []string{}
+	for _, alias := range client.ShareAliases(annotations) {
+		if errs := validation.IsDNS1123Subdomain(alias); len(errs) > 0 {
+			return nil, fmt.Errorf("its alias %q is not a valid share name: %s", alias, strings.Join(errs, ", "))
+		}
+		if alias == shareName {
+			return nil, fmt.Errorf("it lists its own name among its aliases")
+		}
+		claimedBy, exists := "", false
+		switch kind {
+		case consts.ResourceReferenceTypeSecret:
+			if ss, err := client.SharedSecretForAlias(alias); err != nil {
+				return nil, fmt.Errorf("its %s", err.Error())
+			} else if ss != nil {
+				claimedBy = ss.Name
+			}
+			exists = client.GetSharedSecret(alias) != nil
+		case consts.ResourceReferenceTypeConfigMap:
+			if scm, err := client.SharedConfigMapForAlias(alias); err != nil {
+				return nil, fmt.Errorf("its %s", err.Error())
+			} else if scm != nil {
+				claimedBy = scm.Name
+			}
+			exists = client.GetSharedConfigMap(alias) != nil
+		}
+		if len(claimedBy) > 0 && claimedBy != shareName {
+			return nil, fmt.Errorf("its alias %q is already claimed by share %q", alias, claimedBy)
+		}
+		if exists {
+			warnings = append(warnings, fmt.Sprintf("the alias %q names an existing share, volumes only resolve it to share %q once that share is deleted",
+				alias, shareName))
+		}
+	}
+	return warnings, nil
+}
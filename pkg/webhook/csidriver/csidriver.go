@@ -3,16 +3,26 @@ package csidriver
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
 	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
 
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
 	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
 )
 
 // VolumeSourceType represents a volume source type
@@ -22,6 +32,15 @@ const (
 	URI                 string           = "/resource-validation"
 	WebhookName         string           = "sharedresourcecsidriver"
 	VolumeSourceTypeCSI VolumeSourceType = "CSI"
+
+	sharedConfigMapShareKey = "sharedConfigMap"
+	sharedSecretShareKey    = "sharedSecret"
+	refreshResourceKey      = "refreshResource"
+	fileModeKey             = "fileMode"
+
+	// ExpiryWarningWindow is how close to the expiry of a share's access a pod has to be admitted for the webhook
+	// to warn about it
+	ExpiryWarningWindow = 24 * time.Hour
 )
 
 // Webhook interface
@@ -38,12 +57,13 @@ type Webhook interface {
 
 // SharedResourcesCSIDriverWebhook validates a Shared Resources CSI Driver change
 type SharedResourcesCSIDriverWebhook struct {
-	rn *config.ReservedNames
+	rn       *config.ReservedNames
+	policies *Policies
 }
 
 // NewWebhook creates a new webhook
-func NewWebhook(rn *config.ReservedNames) *SharedResourcesCSIDriverWebhook {
-	return &SharedResourcesCSIDriverWebhook{rn: rn}
+func NewWebhook(rn *config.ReservedNames, policies *Policies) *SharedResourcesCSIDriverWebhook {
+	return &SharedResourcesCSIDriverWebhook{rn: rn, policies: policies}
 }
 
 // GetURI implements Webhook interface
@@ -54,7 +74,7 @@ func (s *SharedResourcesCSIDriverWebhook) Name() string { return WebhookName }
 
 // Validate if the incoming request even valid
 func (s *SharedResourcesCSIDriverWebhook) Validate(req admissionctl.Request) bool {
-	return req.Kind.Kind == "Pod" || req.Kind.Kind == "SharedSecret" || req.Kind.Kind == "SharedConfigMap"
+	return req.Kind.Kind == "Pod" || req.Kind.Kind == "SharedSecret" || req.Kind.Kind == "SharedConfigMap" || isWorkload(req.Kind.Kind)
 }
 
 // Authorized implements Webhook interface
@@ -64,6 +84,9 @@ func (s *SharedResourcesCSIDriverWebhook) Authorized(request admissionctl.Reques
 	var sc *sharev1alpha1.SharedConfigMap
 	var err error
 
+	if isWorkload(request.Kind.Kind) {
+		return s.authorizeWorkload(request)
+	}
 	if pod, err = s.renderPod(request); err == nil {
 		return s.authorizePod(request, pod)
 	}
@@ -73,35 +96,376 @@ func (s *SharedResourcesCSIDriverWebhook) Authorized(request admissionctl.Reques
 	if sc, err = s.renderSharedConfigMap(request); err == nil {
 		return s.authorizeSharedConfigMap(request, sc)
 	}
-	return admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("Could not render a Pod, SharedSecret, nor SharedConfigMap from %s", request.Kind.String()))
+	return admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("Could not render a Pod, SharedSecret, SharedConfigMap, nor workload from %s", request.Kind.String()))
 }
 
 func (s *SharedResourcesCSIDriverWebhook) authorizePod(request admissionctl.Request, pod *corev1.Pod) admissionctl.Response {
 	klog.V(2).Info("admitting pod with SharedResourceCSIVolume")
 	var ret admissionctl.Response
+	if request.Operation == admissionv1.Delete {
+		ret = admissionctl.Allowed("Allowed to delete Pod")
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	if request.Operation != admissionv1.Create {
+		// the volumes of a pod cannot change once it is created, and a share which expired, was revoked, or is no
+		// longer usable by the pod must not keep the pod's metadata, like its finalizers, from being updated
+		if err := validateReadOnlyVolumes(pod); err != nil {
+			ret = admissionctl.Denied(fmt.Sprintf("Not allowed to schedule a pod with %s", err.Error()))
+			ret.UID = request.AdmissionRequest.UID
+			return ret
+		}
+		ret = admissionctl.Allowed("Allowed to update Pod")
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	namespace := pod.Namespace
+	if len(namespace) == 0 {
+		namespace = request.Namespace
+	}
+	warnings, err := s.validatePodVolumes(request, pod, namespace)
+	if err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to schedule a pod with %s", err.Error()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	// Hereafter, all requests are controlled
+	ret = admissionctl.Allowed("Allowed to create Pod").WithWarnings(warnings...)
+	ret.UID = request.AdmissionRequest.UID
+	return ret
+}
 
+// validatePodVolumes checks the shared resource volumes of the pod, returning the warnings about them, or an error
+// naming the volume to deny the pod with
+func (s *SharedResourcesCSIDriverWebhook) validatePodVolumes(request admissionctl.Request, pod *corev1.Pod, namespace string) ([]string, error) {
+	if err := validateReadOnlyVolumes(pod); err != nil {
+		return nil, err
+	}
+	warnings := []string{}
 	for _, volume := range pod.Spec.Volumes {
 		if volume.VolumeSource.CSI != nil &&
 			volume.VolumeSource.CSI.Driver == string(operatorv1.SharedResourcesCSIDriver) {
-			if volume.VolumeSource.CSI.ReadOnly == nil || !*volume.VolumeSource.CSI.ReadOnly {
-				ret = admissionctl.Denied("Not allowed to schedule a pod with ReadOnly false SharedResourceCSIVolume")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+			volumeWarnings, err := s.validatePodVolume(request, pod, namespace, volume)
+			if err != nil {
+				return nil, fmt.Errorf("SharedResourceCSIVolume %q: %s", volume.Name, err.Error())
 			}
+			warnings = append(warnings, volumeWarnings...)
 		}
 	}
-	// Hereafter, all requests are controlled
-	ret = admissionctl.Allowed("Allowed to create Pod")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	return warnings, nil
+}
+
+// validateFileMode checks the fileMode volume attribute is a file mode the driver accepts
+func validateFileMode(fileMode string) error {
+	if mode, err := strconv.ParseUint(fileMode, 8, 32); err != nil || mode == 0 || mode > 0777 {
+		return fmt.Errorf("the volumeAttribute %q has to be a file mode between 0001 and 0777, not %q", fileModeKey, fileMode)
+	}
+	return nil
+}
+
+// validateReadOnlyVolumes checks the shared resource volumes of the pod are read only
+func validateReadOnlyVolumes(pod *corev1.Pod) error {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.VolumeSource.CSI != nil &&
+			volume.VolumeSource.CSI.Driver == string(operatorv1.SharedResourcesCSIDriver) &&
+			(volume.VolumeSource.CSI.ReadOnly == nil || !*volume.VolumeSource.CSI.ReadOnly) {
+			return fmt.Errorf("ReadOnly false SharedResourceCSIVolume")
+		}
+	}
+	return nil
+}
+
+// validatePodVolume runs the checks the driver runs when mounting the shared resource volume of the pod, so that a
+// pod which cannot mount it is denied rather than left in ContainerCreating; what may still resolve itself, like a
+// share which does not exist yet, is only warned about
+func (s *SharedResourcesCSIDriverWebhook) validatePodVolume(request admissionctl.Request, pod *corev1.Pod, namespace string, volume corev1.Volume) ([]string, error) {
+	csi := volume.VolumeSource.CSI
+	warnings := []string{}
+	configMapShareName := strings.TrimSpace(csi.VolumeAttributes[sharedConfigMapShareKey])
+	secretShareName := strings.TrimSpace(csi.VolumeAttributes[sharedSecretShareKey])
+	if len(configMapShareName) == 0 && len(secretShareName) == 0 {
+		return nil, fmt.Errorf("the volumeAttribute %q or %q has to be set", sharedSecretShareKey, sharedConfigMapShareKey)
+	}
+	if len(configMapShareName) > 0 && len(secretShareName) > 0 {
+		return nil, fmt.Errorf("a single volume cannot support both a SharedConfigMap reference %q and SharedSecret reference %q",
+			configMapShareName, secretShareName)
+	}
+	if refresh, ok := csi.VolumeAttributes[refreshResourceKey]; ok {
+		if _, err := strconv.ParseBool(refresh); err != nil {
+			warnings = append(warnings, fmt.Sprintf("the volumeAttribute %q of SharedResourceCSIVolume %q, %q, is not a boolean, the volume will be refreshed",
+				refreshResourceKey, volume.Name, refresh))
+		}
+	}
+	if fileMode, ok := csi.VolumeAttributes[fileModeKey]; ok {
+		if err := validateFileMode(fileMode); err != nil {
+			return nil, err
+		}
+	}
+
+	shareName, alias, share, annotations, err := s.shareForVolume(csi)
+	if err != nil {
+		return nil, err
+	}
+	policyWarnings, err := s.policies.evaluatePodVolume(request, pod, volume, share)
+	if err != nil {
+		return nil, err
+	}
+	warnings = append(warnings, policyWarnings...)
+	if share == nil {
+		// without a share client, shares cannot be looked up at all
+		if client.GetShareClient() != nil {
+			warnings = append(warnings, fmt.Sprintf("share %q of SharedResourceCSIVolume %q does not exist, the pod will not start until it is created",
+				shareName, volume.Name))
+		}
+		return warnings, nil
+	}
+
+	var kind consts.ResourceReferenceType
+	var backingNamespace, backingName string
+	reserved := false
+	switch share := share.(type) {
+	case *sharev1alpha1.SharedSecret:
+		kind, backingNamespace, backingName = consts.ResourceReferenceTypeSecret, share.Spec.SecretRef.Namespace, share.Spec.SecretRef.Name
+		reserved = s.rn != nil && !s.rn.ValidateSharedSecretOpenShiftName(shareName, backingNamespace, backingName)
+	case *sharev1alpha1.SharedConfigMap:
+		kind, backingNamespace, backingName = consts.ResourceReferenceTypeConfigMap, share.Spec.ConfigMapRef.Namespace, share.Spec.ConfigMapRef.Name
+		reserved = s.rn != nil && !s.rn.ValidateSharedConfigMapOpenShiftName(shareName, backingNamespace, backingName)
+	}
+	if reserved {
+		return nil, fmt.Errorf("share %s violates the OpenShift reserved name list", shareName)
+	}
+	if len(strings.TrimSpace(backingNamespace)) == 0 || len(strings.TrimSpace(backingName)) == 0 {
+		return nil, fmt.Errorf("the backing resource namespace and name of share %q need to be set", shareName)
+	}
+	if err := client.ValidateSharePolicy(config.LoadedConfig.SharePolicy, kind, shareName, backingNamespace, backingName); err != nil {
+		return nil, fmt.Errorf("%s", status.Convert(err).Message())
+	}
+	if config.LoadedConfig.SharePolicy.EnforcesOwnerConsent() {
+		// a missing backing resource is reported when its content is mapped to the volume
+		if err := client.ValidateBackingResourceConsent(kind, shareName, backingNamespace, backingName); err != nil && status.Code(err) != codes.NotFound {
+			if status.Code(err) == codes.PermissionDenied {
+				return nil, fmt.Errorf("%s", status.Convert(err).Message())
+			}
+			warnings = append(warnings, status.Convert(err).Message())
+		}
+	}
+
+	serviceAccount := pod.Spec.ServiceAccountName
+	if len(serviceAccount) == 0 {
+		serviceAccount = "default"
+	}
+	if allowed, err := client.ExecuteSARForShareOrAlias(shareName, alias, annotations, namespace, pod.Name, client.ServiceAccountUser(namespace, serviceAccount), kind); !allowed {
+		if status.Code(err) == codes.PermissionDenied {
+			return nil, fmt.Errorf("service account %q is not allowed to use share %q", serviceAccount, shareName)
+		}
+		warnings = append(warnings, fmt.Sprintf("could not check service account %q can use share %q: %s",
+			serviceAccount, shareName, status.Convert(err).Message()))
+	}
+
+	if err := client.ValidateConsumer(shareName, annotations, namespace, pod.Name, pod); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := client.ValidateExpiry(shareName, annotations, namespace, now); err != nil {
+		return nil, err
+	}
+	if err := client.ValidateNotRevoked(shareName, annotations); err != nil {
+		return nil, err
+	}
+	if err := client.ValidateNotSunset(shareName, annotations, now); err != nil {
+		return nil, err
+	}
+	if expiry, ok, _ := client.ShareExpiry(annotations, namespace); ok && expiry.Sub(now) < ExpiryWarningWindow {
+		warnings = append(warnings, fmt.Sprintf("access to share %q of SharedResourceCSIVolume %q expires at %s",
+			shareName, volume.Name, expiry.Format(time.RFC3339)))
+	}
+	if len(alias) > 0 {
+		warnings = append(warnings, fmt.Sprintf("SharedResourceCSIVolume %q references share %q by its deprecated alias %q, reference it by its name instead",
+			volume.Name, shareName, alias))
+	}
+	if deprecation, _ := client.ShareDeprecation(annotations); deprecation != nil {
+		warnings = append(warnings, fmt.Sprintf("share %q of SharedResourceCSIVolume %q is deprecated, %s",
+			shareName, volume.Name, deprecation.String()))
+	}
+	return warnings, nil
+}
+
+// shareForVolume returns the name, the alias the volume references the share by, if any, the share and the
+// annotations of the share the volume references; shares that cannot be found, for which the share is nil, are left
+// for the driver to reject at mount time, while failing to look the share up is returned as an error, as none of the
+// checks of the share can be run
+func (s *SharedResourcesCSIDriverWebhook) shareForVolume(csi *corev1.CSIVolumeSource) (string, string, runtime.Object, map[string]string, error) {
+	var shareName, alias string
+	var share runtime.Object
+	var annotations map[string]string
+	if name, ok := csi.VolumeAttributes[sharedConfigMapShareKey]; ok && len(name) > 0 {
+		shareName = name
+		scm, err := client.FetchSharedConfigMap(name)
+		if err == nil && scm == nil {
+			if scm, err = client.SharedConfigMapForAlias(name); scm != nil {
+				shareName, alias = scm.Name, name
+			}
+		}
+		if err != nil {
+			return shareName, "", nil, nil, fmt.Errorf("could not look up share %q: %s", name, err.Error())
+		}
+		if scm != nil {
+			share = scm
+			annotations = scm.Annotations
+		}
+	}
+	if name, ok := csi.VolumeAttributes[sharedSecretShareKey]; ok && len(name) > 0 {
+		shareName = name
+		ss, err := client.FetchSharedSecret(name)
+		if err == nil && ss == nil {
+			if ss, err = client.SharedSecretForAlias(name); ss != nil {
+				shareName, alias = ss.Name, name
+			}
+		}
+		if err != nil {
+			return shareName, "", nil, nil, fmt.Errorf("could not look up share %q: %s", name, err.Error())
+		}
+		if ss != nil {
+			share = ss
+			annotations = ss.Annotations
+		}
+	}
+	return shareName, alias, share, annotations, nil
+}
+
+// validateShareAnnotations checks the consumer selector, expiry and deprecation annotations of a share can be parsed
+func validateShareAnnotations(annotations map[string]string) error {
+	if _, _, err := client.ConsumerSelectors(annotations); err != nil {
+		return fmt.Errorf("its consumer selector is invalid: %s", err.Error())
+	}
+	if _, _, err := client.ShareExpirations(annotations); err != nil {
+		return fmt.Errorf("its expiry is invalid: %s", err.Error())
+	}
+	if _, err := client.ShareDeprecation(annotations); err != nil {
+		return fmt.Errorf("its deprecation is invalid: %s", err.Error())
+	}
+	return nil
+}
+
+// ownerConsent checks the consent of the owner of the backing resource when the share policy requires it, returning
+// an error to deny the share with when it is enforced, and warnings otherwise
+func ownerConsent(kind consts.ResourceReferenceType, shareName, backingNamespace, backingName string) ([]string, error) {
+	policy := config.LoadedConfig.SharePolicy
+	if len(policy.OwnerConsent) == 0 {
+		return nil, nil
+	}
+	err := client.ValidateBackingResourceConsent(kind, shareName, backingNamespace, backingName)
+	if err == nil {
+		return nil, nil
+	}
+	if policy.EnforcesOwnerConsent() && status.Code(err) == codes.PermissionDenied {
+		return nil, err
+	}
+	return []string{status.Convert(err).Message()}, nil
+}
+
+// creatorCanGet checks the user creating the share, or changing its backing resource from the one of the previous
+// share, can get the backing resource, unless they belong to one of the groups the share policy exempts from the check
+func creatorCanGet(request admissionctl.Request, previous runtime.Object, kind consts.ResourceReferenceType, shareName, backingNamespace, backingName string) error {
+	switch request.Operation {
+	case admissionv1.Create:
+	case admissionv1.Update:
+		if backing, ok := shareBackingResource(previous); ok && backing == (types.NamespacedName{Namespace: backingNamespace, Name: backingName}) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	user := request.UserInfo
+	for _, group := range user.Groups {
+		for _, bypass := range config.LoadedConfig.SharePolicy.CreatorCheckBypassGroups {
+			if group == bypass {
+				klog.Infof("%s of %s share %s by user %s allowed without checking its access to %s/%s, as a member of group %s",
+					request.Operation, kind, shareName, user.Username, backingNamespace, backingName, group)
+				return nil
+			}
+		}
+	}
+	if err := client.ValidateUserCanGet(&user, kind, shareName, backingNamespace, backingName); err != nil {
+		klog.Infof("%s of %s share %s by user %s denied: %s", request.Operation, kind, shareName, user.Username, status.Convert(err).Message())
+		return err
+	}
+	klog.Infof("%s of %s share %s by user %s allowed, they can get %s/%s", request.Operation, kind, shareName, user.Username, backingNamespace, backingName)
+	return nil
+}
+
+// shareBackingResource returns the backing resource of the share, if any
+func shareBackingResource(share runtime.Object) (types.NamespacedName, bool) {
+	switch share := share.(type) {
+	case *sharev1alpha1.SharedSecret:
+		return types.NamespacedName{Namespace: share.Spec.SecretRef.Namespace, Name: share.Spec.SecretRef.Name}, true
+	case *sharev1alpha1.SharedConfigMap:
+		return types.NamespacedName{Namespace: share.Spec.ConfigMapRef.Namespace, Name: share.Spec.ConfigMapRef.Name}, true
+	default:
+		return types.NamespacedName{}, false
+	}
+}
+
+// previousShare decodes the share before the update, returning nil when the request holds none
+func previousShare(request admissionctl.Request, kind consts.ResourceReferenceType) runtime.Object {
+	if request.Operation != admissionv1.Update || len(request.OldObject.Raw) == 0 {
+		return nil
+	}
+	var previous runtime.Object = &sharev1alpha1.SharedConfigMap{}
+	if kind == consts.ResourceReferenceTypeSecret {
+		previous = &sharev1alpha1.SharedSecret{}
+	}
+	if err := admissionctl.NewDecoder(scheme).DecodeRaw(request.OldObject, previous); err != nil {
+		return nil
+	}
+	return previous
 }
 
 func (s *SharedResourcesCSIDriverWebhook) authorizeSharedSecret(request admissionctl.Request, ss *sharev1alpha1.SharedSecret) admissionctl.Response {
 	klog.V(2).Info("admitting shared secret with SharedResourceCSIVolume")
 	var ret admissionctl.Response
+	if request.Operation == admissionv1.Delete {
+		return s.authorizeShareDeletion(request, kindSharedSecret, ss.Name, ss.Annotations)
+	}
 
+	if err := validateShareAnnotations(ss.Annotations); err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedSecret with name %q as %s", ss.Name, err.Error()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	aliasWarnings, err := validateShareAliases(consts.ResourceReferenceTypeSecret, ss.Name, ss.Annotations)
+	if err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedSecret with name %q as %s", ss.Name, err.Error()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	if err := client.ValidateSharePolicy(config.LoadedConfig.SharePolicy, consts.ResourceReferenceTypeSecret, ss.Name, ss.Spec.SecretRef.Namespace, ss.Spec.SecretRef.Name); err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedSecret with name %q as %s", ss.Name, status.Convert(err).Message()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	warnings, err := ownerConsent(consts.ResourceReferenceTypeSecret, ss.Name, ss.Spec.SecretRef.Namespace, ss.Spec.SecretRef.Name)
+	if err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedSecret with name %q as %s", ss.Name, status.Convert(err).Message()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	previous := previousShare(request, consts.ResourceReferenceTypeSecret)
+	if err := creatorCanGet(request, previous, consts.ResourceReferenceTypeSecret, ss.Name, ss.Spec.SecretRef.Namespace, ss.Spec.SecretRef.Name); err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedSecret with name %q as %s", ss.Name, status.Convert(err).Message()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	policyWarnings, err := s.policies.evaluateShare(request, kindSharedSecret, ss, previous)
+	if err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedSecret with name %q as %s", ss.Name, err.Error()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	warnings = append(warnings, policyWarnings...)
+	warnings = append(warnings, aliasWarnings...)
 	if s.rn.ValidateSharedSecretOpenShiftName(ss.Name, ss.Spec.SecretRef.Namespace, ss.Spec.SecretRef.Name) {
-		ret = admissionctl.Allowed("Allowed to create SharedSecret")
+		ret = admissionctl.Allowed("Allowed to create SharedSecret").WithWarnings(warnings...)
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
@@ -113,9 +477,48 @@ func (s *SharedResourcesCSIDriverWebhook) authorizeSharedSecret(request admissio
 func (s *SharedResourcesCSIDriverWebhook) authorizeSharedConfigMap(request admissionctl.Request, scm *sharev1alpha1.SharedConfigMap) admissionctl.Response {
 	klog.V(2).Info("admitting shared configmap with SharedResourceCSIVolume")
 	var ret admissionctl.Response
+	if request.Operation == admissionv1.Delete {
+		return s.authorizeShareDeletion(request, kindSharedConfigMap, scm.Name, scm.Annotations)
+	}
 
+	if err := validateShareAnnotations(scm.Annotations); err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedConfigMap with name %q as %s", scm.Name, err.Error()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	aliasWarnings, err := validateShareAliases(consts.ResourceReferenceTypeConfigMap, scm.Name, scm.Annotations)
+	if err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedConfigMap with name %q as %s", scm.Name, err.Error()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	if err := client.ValidateSharePolicy(config.LoadedConfig.SharePolicy, consts.ResourceReferenceTypeConfigMap, scm.Name, scm.Spec.ConfigMapRef.Namespace, scm.Spec.ConfigMapRef.Name); err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedConfigMap with name %q as %s", scm.Name, status.Convert(err).Message()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	warnings, err := ownerConsent(consts.ResourceReferenceTypeConfigMap, scm.Name, scm.Spec.ConfigMapRef.Namespace, scm.Spec.ConfigMapRef.Name)
+	if err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedConfigMap with name %q as %s", scm.Name, status.Convert(err).Message()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	previous := previousShare(request, consts.ResourceReferenceTypeConfigMap)
+	if err := creatorCanGet(request, previous, consts.ResourceReferenceTypeConfigMap, scm.Name, scm.Spec.ConfigMapRef.Namespace, scm.Spec.ConfigMapRef.Name); err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedConfigMap with name %q as %s", scm.Name, status.Convert(err).Message()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	policyWarnings, err := s.policies.evaluateShare(request, kindSharedConfigMap, scm, previous)
+	if err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to create SharedConfigMap with name %q as %s", scm.Name, err.Error()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	warnings = append(warnings, policyWarnings...)
+	warnings = append(warnings, aliasWarnings...)
 	if s.rn.ValidateSharedConfigMapOpenShiftName(scm.Name, scm.Spec.ConfigMapRef.Namespace, scm.Spec.ConfigMapRef.Name) {
-		ret = admissionctl.Allowed("Allowed to create SharedConfigMap")
+		ret = admissionctl.Allowed("Allowed to create SharedConfigMap").WithWarnings(warnings...)
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
@@ -126,42 +529,48 @@ func (s *SharedResourcesCSIDriverWebhook) authorizeSharedConfigMap(request admis
 }
 
 // renderPod decodes an *corev1.Pod from the incoming request.
-// If the request includes an OldObject (from an update or deletion), it will be
-// preferred, otherwise, the Object will be preferred.
+// The Object, which holds the pod as created or updated, is preferred, otherwise, the OldObject of a deletion
+// will be used.
 func (s *SharedResourcesCSIDriverWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
 	var err error
 	decoder := admissionctl.NewDecoder(scheme)
 	pod := &corev1.Pod{}
-	if len(request.OldObject.Raw) > 0 {
-		err = decoder.DecodeRaw(request.OldObject, pod)
-	} else {
+	if len(request.Object.Raw) > 0 {
 		err = decoder.DecodeRaw(request.Object, pod)
+	} else {
+		err = decoder.DecodeRaw(request.OldObject, pod)
 	}
 
 	return pod, err
 }
 
+// renderSharedSecret decodes an *sharev1alpha1.SharedSecret from the incoming request.
+// The Object, which holds the share as created or updated, is preferred, otherwise, the OldObject of a deletion
+// will be used.
 func (s *SharedResourcesCSIDriverWebhook) renderSharedSecret(request admissionctl.Request) (*sharev1alpha1.SharedSecret, error) {
 	var err error
 	decoder := admissionctl.NewDecoder(scheme)
 	sharedSecret := &sharev1alpha1.SharedSecret{}
-	if len(request.OldObject.Raw) > 0 {
-		err = decoder.DecodeRaw(request.OldObject, sharedSecret)
-	} else {
+	if len(request.Object.Raw) > 0 {
 		err = decoder.DecodeRaw(request.Object, sharedSecret)
+	} else {
+		err = decoder.DecodeRaw(request.OldObject, sharedSecret)
 	}
 
 	return sharedSecret, err
 }
 
+// renderSharedConfigMap decodes an *sharev1alpha1.SharedConfigMap from the incoming request.
+// The Object, which holds the share as created or updated, is preferred, otherwise, the OldObject of a deletion
+// will be used.
 func (s *SharedResourcesCSIDriverWebhook) renderSharedConfigMap(request admissionctl.Request) (*sharev1alpha1.SharedConfigMap, error) {
 	var err error
 	decoder := admissionctl.NewDecoder(scheme)
 	sharedConfigMap := &sharev1alpha1.SharedConfigMap{}
-	if len(request.OldObject.Raw) > 0 {
-		err = decoder.DecodeRaw(request.OldObject, sharedConfigMap)
-	} else {
+	if len(request.Object.Raw) > 0 {
 		err = decoder.DecodeRaw(request.Object, sharedConfigMap)
+	} else {
+		err = decoder.DecodeRaw(request.OldObject, sharedConfigMap)
 	}
 
 	return sharedConfigMap, err
@@ -2,16 +2,33 @@ package csidriver
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	fakekubetesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1alpha1 "github.com/openshift/api/sharedresource/v1alpha1"
+	fakeshareclientset "github.com/openshift/client-go/sharedresource/clientset/versioned/fake"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
 )
 
 var (
@@ -127,7 +144,7 @@ func TestAuthorize(t *testing.T) {
 			Operation: tc.operation,
 		}
 
-		hook := NewWebhook(nil)
+		hook := NewWebhook(nil, nil)
 		req := admissionctl.Request{
 			AdmissionRequest: Request,
 		}
@@ -139,3 +156,1471 @@ func TestAuthorize(t *testing.T) {
 		}
 	}
 }
+
+func TestAuthorizeConsumerSelectors(t *testing.T) {
+	truVal := true
+	client.SetShareClient(fakeshareclientset.NewSimpleClientset(&sharev1alpha1.SharedConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "shared-cm-build",
+			Annotations: map[string]string{consts.ConsumerPodSelectorAnnotation: "team=build"},
+		},
+		Spec: sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+	}))
+	defer client.SetShareClient(nil)
+	client.SetClient(subjectAccessReviews(true, fakekubeclientset.NewSimpleClientset()))
+	defer client.SetClient(nil)
+
+	for _, tc := range []struct {
+		name        string
+		shouldAdmit bool
+		labels      map[string]string
+	}{
+		{
+			name:        "pod labels match the consumer selector",
+			shouldAdmit: true,
+			labels:      map[string]string{"team": "build"},
+		},
+		{
+			name:        "pod labels do not match the consumer selector",
+			shouldAdmit: false,
+			labels:      map[string]string{"team": "web"},
+		},
+	} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pod-1",
+				Namespace: "test",
+				Labels:    tc.labels,
+			},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{
+						Name: "csi-one",
+						VolumeSource: corev1.VolumeSource{
+							CSI: &corev1.CSIVolumeSource{
+								ReadOnly:         &truVal,
+								Driver:           string(operatorv1.SharedResourcesCSIDriver),
+								VolumeAttributes: map[string]string{"sharedConfigMap": "shared-cm-build"},
+							},
+						},
+					},
+				},
+			},
+		}
+		raw, err := json.Marshal(pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: raw},
+				Resource:  podGvr,
+				Operation: admissionv1.Create,
+			},
+		}
+
+		response := NewWebhook(nil, nil).Authorized(req)
+
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t", tc.name, tc.shouldAdmit, response.Allowed)
+		}
+	}
+}
+
+func TestAuthorizeShareExpiry(t *testing.T) {
+	truVal := true
+	client.SetShareClient(fakeshareclientset.NewSimpleClientset(
+		&sharev1alpha1.SharedConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "shared-cm-expired",
+				Annotations: map[string]string{consts.ExpiresAtAnnotation: time.Now().Add(-time.Hour).Format(time.RFC3339)},
+			},
+			Spec: sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+		},
+		&sharev1alpha1.SharedConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "shared-cm-expiring",
+				Annotations: map[string]string{consts.NamespaceExpiresAtAnnotation: "test=" + time.Now().Add(time.Hour).Format(time.RFC3339)},
+			},
+			Spec: sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+		},
+		&sharev1alpha1.SharedConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "shared-cm-later",
+				Annotations: map[string]string{consts.ExpiresAtAnnotation: time.Now().Add(30 * 24 * time.Hour).Format(time.RFC3339)},
+			},
+			Spec: sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+		},
+	))
+	defer client.SetShareClient(nil)
+	client.SetClient(subjectAccessReviews(true, fakekubeclientset.NewSimpleClientset()))
+	defer client.SetClient(nil)
+
+	for _, tc := range []struct {
+		name        string
+		share       string
+		shouldAdmit bool
+		shouldWarn  bool
+	}{
+		{
+			name:        "expired share",
+			share:       "shared-cm-expired",
+			shouldAdmit: false,
+		},
+		{
+			name:        "share expiring soon for the namespace",
+			share:       "shared-cm-expiring",
+			shouldAdmit: true,
+			shouldWarn:  true,
+		},
+		{
+			name:        "share expiring later",
+			share:       "shared-cm-later",
+			shouldAdmit: true,
+		},
+	} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pod-1",
+				Namespace: "test",
+			},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{
+						Name: "csi-one",
+						VolumeSource: corev1.VolumeSource{
+							CSI: &corev1.CSIVolumeSource{
+								ReadOnly:         &truVal,
+								Driver:           string(operatorv1.SharedResourcesCSIDriver),
+								VolumeAttributes: map[string]string{"sharedConfigMap": tc.share},
+							},
+						},
+					},
+				},
+			},
+		}
+		raw, err := json.Marshal(pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: raw},
+				Resource:  podGvr,
+				Operation: admissionv1.Create,
+			},
+		}
+
+		response := NewWebhook(nil, nil).Authorized(req)
+
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t", tc.name, tc.shouldAdmit, response.Allowed)
+		}
+		if (len(response.Warnings) > 0) != tc.shouldWarn {
+			t.Fatalf("Mismatch: %s Should warn %t. got %v", tc.name, tc.shouldWarn, response.Warnings)
+		}
+	}
+}
+
+func TestAuthorizeShareDeprecation(t *testing.T) {
+	truVal := true
+	client.SetShareClient(fakeshareclientset.NewSimpleClientset(
+		&sharev1alpha1.SharedConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "shared-cm-deprecated",
+				Annotations: map[string]string{
+					consts.DeprecatedAnnotation: "use shared-cm-next instead",
+					consts.SunsetAtAnnotation:   time.Now().Add(30 * 24 * time.Hour).Format(time.RFC3339),
+				},
+			},
+			Spec: sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+		},
+		&sharev1alpha1.SharedConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "shared-cm-sunset",
+				Annotations: map[string]string{
+					consts.DeprecatedAnnotation: "use shared-cm-next instead",
+					consts.SunsetAtAnnotation:   time.Now().Add(-time.Hour).Format(time.RFC3339),
+				},
+			},
+			Spec: sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+		},
+		&sharev1alpha1.SharedConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared-cm-next"},
+			Spec:       sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+		},
+	))
+	defer client.SetShareClient(nil)
+	client.SetClient(subjectAccessReviews(true, fakekubeclientset.NewSimpleClientset()))
+	defer client.SetClient(nil)
+
+	for _, tc := range []struct {
+		name        string
+		share       string
+		shouldAdmit bool
+		shouldWarn  bool
+	}{
+		{
+			name:        "deprecated share",
+			share:       "shared-cm-deprecated",
+			shouldAdmit: true,
+			shouldWarn:  true,
+		},
+		{
+			name:        "sunset share",
+			share:       "shared-cm-sunset",
+			shouldAdmit: false,
+		},
+		{
+			name:        "replacement share",
+			share:       "shared-cm-next",
+			shouldAdmit: true,
+		},
+	} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pod-1",
+				Namespace: "test",
+			},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{
+						Name: "csi-one",
+						VolumeSource: corev1.VolumeSource{
+							CSI: &corev1.CSIVolumeSource{
+								ReadOnly:         &truVal,
+								Driver:           string(operatorv1.SharedResourcesCSIDriver),
+								VolumeAttributes: map[string]string{"sharedConfigMap": tc.share},
+							},
+						},
+					},
+				},
+			},
+		}
+		raw, err := json.Marshal(pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: raw},
+				Resource:  podGvr,
+				Operation: admissionv1.Create,
+			},
+		}
+
+		response := NewWebhook(nil, nil).Authorized(req)
+
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t", tc.name, tc.shouldAdmit, response.Allowed)
+		}
+		if (len(response.Warnings) > 0) != tc.shouldWarn {
+			t.Fatalf("Mismatch: %s Should warn %t. got %v", tc.name, tc.shouldWarn, response.Warnings)
+		}
+	}
+}
+
+func TestAuthorizeShareAlias(t *testing.T) {
+	truVal := true
+	client.SetShareClient(fakeshareclientset.NewSimpleClientset(
+		&sharev1alpha1.SharedConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca-2027", Annotations: map[string]string{consts.AliasesAnnotation: "ca"}},
+			Spec:       sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+		},
+	))
+	defer client.SetShareClient(nil)
+	kubeClient := fakekubeclientset.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (bool, runtime.Object, error) {
+		sar := action.(fakekubetesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		// the RBAC of the pods only grants the former name of the share
+		allowed := sar.Spec.ResourceAttributes.Resource != "sharedconfigmaps" || sar.Spec.ResourceAttributes.Name == "ca"
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}}, nil
+	})
+	client.SetClient(kubeClient)
+	defer client.SetClient(nil)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "test",
+		},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name: "csi-one",
+					VolumeSource: corev1.VolumeSource{
+						CSI: &corev1.CSIVolumeSource{
+							ReadOnly:         &truVal,
+							Driver:           string(operatorv1.SharedResourcesCSIDriver),
+							VolumeAttributes: map[string]string{"sharedConfigMap": "ca"},
+						},
+					},
+				},
+			},
+		},
+	}
+	raw, err := json.Marshal(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := NewWebhook(nil, nil).Authorized(admissionctl.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Object:    runtime.RawExtension{Raw: raw},
+			Resource:  podGvr,
+			Operation: admissionv1.Create,
+		},
+	})
+	if !response.Allowed || len(response.Warnings) != 1 || !strings.Contains(response.Warnings[0], "deprecated alias") {
+		t.Fatalf("Mismatch: the pod referencing the alias should be admitted with a warning, got %t %v", response.Allowed, response.Warnings)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		shareName   string
+		aliases     string
+		shouldAdmit bool
+	}{
+		{
+			name:        "share keeping its alias",
+			shareName:   "ca-2027",
+			aliases:     "ca",
+			shouldAdmit: true,
+		},
+		{
+			name:        "alias claimed by another share",
+			shareName:   "ca-2028",
+			aliases:     "ca",
+			shouldAdmit: false,
+		},
+		{
+			name:        "own name as alias",
+			shareName:   "ca-2028",
+			aliases:     "ca-2028",
+			shouldAdmit: false,
+		},
+		{
+			name:        "invalid alias",
+			shareName:   "ca-2028",
+			aliases:     "CA_2026",
+			shouldAdmit: false,
+		},
+	} {
+		raw, err := json.Marshal(&sharev1alpha1.SharedConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: sharev1alpha1.GroupVersion.String(), Kind: "SharedConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: tc.shareName, Annotations: map[string]string{consts.AliasesAnnotation: tc.aliases}},
+			Spec:       sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := NewWebhook(config.SetupNameReservation(), nil).Authorized(admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: raw},
+				Operation: admissionv1.Create,
+			},
+		})
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t: %s", tc.name, tc.shouldAdmit, response.Allowed, response.Result.Message)
+		}
+	}
+}
+
+func TestAuthorizeSharePolicy(t *testing.T) {
+	client.SetClient(subjectAccessReviews(true, fakekubeclientset.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "token"}, Type: corev1.SecretTypeServiceAccountToken},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "tls"}, Type: corev1.SecretTypeTLS},
+	)))
+	defer client.SetClient(nil)
+	config.LoadedConfig.SharePolicy = config.SharePolicy{
+		DeniedNamespaces:  []string{"kube-system"},
+		DeniedSecretTypes: []string{string(corev1.SecretTypeServiceAccountToken)},
+	}
+	defer func() { config.LoadedConfig.SharePolicy = config.SharePolicy{} }()
+
+	for _, tc := range []struct {
+		name        string
+		share       runtime.Object
+		shouldAdmit bool
+	}{
+		{
+			name: "allowed secret",
+			share: &sharev1alpha1.SharedSecret{
+				TypeMeta:   metav1.TypeMeta{APIVersion: sharev1alpha1.GroupVersion.String(), Kind: "SharedSecret"},
+				ObjectMeta: metav1.ObjectMeta{Name: "share-tls"},
+				Spec:       sharev1alpha1.SharedSecretSpec{SecretRef: sharev1alpha1.SharedSecretReference{Namespace: "shared", Name: "tls"}},
+			},
+			shouldAdmit: true,
+		},
+		{
+			name: "denied secret type",
+			share: &sharev1alpha1.SharedSecret{
+				TypeMeta:   metav1.TypeMeta{APIVersion: sharev1alpha1.GroupVersion.String(), Kind: "SharedSecret"},
+				ObjectMeta: metav1.ObjectMeta{Name: "share-token"},
+				Spec:       sharev1alpha1.SharedSecretSpec{SecretRef: sharev1alpha1.SharedSecretReference{Namespace: "shared", Name: "token"}},
+			},
+			shouldAdmit: false,
+		},
+		{
+			name: "denied namespace",
+			share: &sharev1alpha1.SharedConfigMap{
+				TypeMeta:   metav1.TypeMeta{APIVersion: sharev1alpha1.GroupVersion.String(), Kind: "SharedConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{Name: "share-system"},
+				Spec:       sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "kube-system", Name: "cm"}},
+			},
+			shouldAdmit: false,
+		},
+	} {
+		raw, err := json.Marshal(tc.share)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: raw},
+				Operation: admissionv1.Create,
+			},
+		}
+
+		response := NewWebhook(config.SetupNameReservation(), nil).Authorized(req)
+
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t: %s", tc.name, tc.shouldAdmit, response.Allowed, response.Result.Message)
+		}
+		if !tc.shouldAdmit && !strings.Contains(response.Result.Message, "share policy") {
+			t.Fatalf("Mismatch: %s Should be denied by the share policy, got %q", tc.name, response.Result.Message)
+		}
+	}
+}
+
+func TestAuthorizeOwnerConsent(t *testing.T) {
+	client.SetClient(subjectAccessReviews(true, fakekubeclientset.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "private"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "public",
+			Labels: map[string]string{consts.ShareableLabel: "true"}}},
+	)))
+	defer client.SetClient(nil)
+	defer func() { config.LoadedConfig.SharePolicy = config.SharePolicy{} }()
+
+	for _, tc := range []struct {
+		name        string
+		mode        string
+		secret      string
+		shouldAdmit bool
+		shouldWarn  bool
+	}{
+		{
+			name:        "consent not required",
+			secret:      "private",
+			shouldAdmit: true,
+		},
+		{
+			name:        "missing consent warns",
+			mode:        config.OwnerConsentWarn,
+			secret:      "private",
+			shouldAdmit: true,
+			shouldWarn:  true,
+		},
+		{
+			name:        "missing consent denies",
+			mode:        config.OwnerConsentEnforce,
+			secret:      "private",
+			shouldAdmit: false,
+		},
+		{
+			name:        "consent given",
+			mode:        config.OwnerConsentEnforce,
+			secret:      "public",
+			shouldAdmit: true,
+		},
+		{
+			name:        "backing secret not created yet",
+			mode:        config.OwnerConsentEnforce,
+			secret:      "missing",
+			shouldAdmit: true,
+			shouldWarn:  true,
+		},
+	} {
+		config.LoadedConfig.SharePolicy.OwnerConsent = tc.mode
+		raw, err := json.Marshal(&sharev1alpha1.SharedSecret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: sharev1alpha1.GroupVersion.String(), Kind: "SharedSecret"},
+			ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+			Spec:       sharev1alpha1.SharedSecretSpec{SecretRef: sharev1alpha1.SharedSecretReference{Namespace: "shared", Name: tc.secret}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: raw},
+				Operation: admissionv1.Create,
+			},
+		}
+
+		response := NewWebhook(config.SetupNameReservation(), nil).Authorized(req)
+
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t: %s", tc.name, tc.shouldAdmit, response.Allowed, response.Result.Message)
+		}
+		if (len(response.Warnings) > 0) != tc.shouldWarn {
+			t.Fatalf("Mismatch: %s Should warn %t. got %v", tc.name, tc.shouldWarn, response.Warnings)
+		}
+	}
+}
+
+// subjectAccessReviews makes the SubjectAccessReviews of the client return the given result
+func subjectAccessReviews(allowed bool, kubeClient *fakekubeclientset.Clientset) *fakekubeclientset.Clientset {
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (bool, runtime.Object, error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}}, nil
+	})
+	return kubeClient
+}
+
+func TestAuthorizeCreatorCanGet(t *testing.T) {
+	kubeClient := fakekubeclientset.NewSimpleClientset()
+	reviews := []*authorizationv1.SubjectAccessReview{}
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action fakekubetesting.Action) (bool, runtime.Object, error) {
+		sar := action.(fakekubetesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		reviews = append(reviews, sar)
+		// only the secrets of the user's own namespace can be read
+		allowed := sar.Spec.ResourceAttributes.Namespace == "mine"
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}}, nil
+	})
+	client.SetClient(kubeClient)
+	defer client.SetClient(nil)
+	config.LoadedConfig.SharePolicy.CreatorCheckBypassGroups = []string{"share-admins"}
+	defer func() { config.LoadedConfig.SharePolicy = config.SharePolicy{} }()
+
+	share := func(namespace string) []byte {
+		raw, err := json.Marshal(&sharev1alpha1.SharedSecret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: sharev1alpha1.GroupVersion.String(), Kind: "SharedSecret"},
+			ObjectMeta: metav1.ObjectMeta{Name: "share1"},
+			Spec:       sharev1alpha1.SharedSecretSpec{SecretRef: sharev1alpha1.SharedSecretReference{Namespace: namespace, Name: "secret1"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	for _, tc := range []struct {
+		name        string
+		operation   admissionv1.Operation
+		groups      []string
+		namespace   string
+		oldObject   []byte
+		shouldAdmit bool
+		shouldCheck bool
+	}{
+		{
+			name:        "creator can get the secret",
+			operation:   admissionv1.Create,
+			namespace:   "mine",
+			shouldAdmit: true,
+			shouldCheck: true,
+		},
+		{
+			name:        "creator cannot get the secret",
+			operation:   admissionv1.Create,
+			namespace:   "theirs",
+			shouldAdmit: false,
+			shouldCheck: true,
+		},
+		{
+			name:        "creator in the bypass group",
+			operation:   admissionv1.Create,
+			groups:      []string{"system:authenticated", "share-admins"},
+			namespace:   "theirs",
+			shouldAdmit: true,
+		},
+		{
+			name:        "update without a ref change",
+			operation:   admissionv1.Update,
+			namespace:   "theirs",
+			oldObject:   share("theirs"),
+			shouldAdmit: true,
+		},
+		{
+			name:        "update changing the ref",
+			operation:   admissionv1.Update,
+			namespace:   "theirs",
+			oldObject:   share("mine"),
+			shouldAdmit: false,
+			shouldCheck: true,
+		},
+	} {
+		reviews = reviews[:0]
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: share(tc.namespace)},
+				OldObject: runtime.RawExtension{Raw: tc.oldObject},
+				Operation: tc.operation,
+				UserInfo:  authenticationv1.UserInfo{Username: "alice", Groups: tc.groups},
+			},
+		}
+
+		response := NewWebhook(config.SetupNameReservation(), nil).Authorized(req)
+
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t: %s", tc.name, tc.shouldAdmit, response.Allowed, response.Result.Message)
+		}
+		if (len(reviews) > 0) != tc.shouldCheck {
+			t.Fatalf("Mismatch: %s Should check the creator access %t. got %d reviews", tc.name, tc.shouldCheck, len(reviews))
+		}
+		if tc.shouldCheck {
+			attributes := reviews[0].Spec.ResourceAttributes
+			if reviews[0].Spec.User != "alice" || attributes.Verb != "get" || attributes.Resource != "secrets" || attributes.Name != "secret1" {
+				t.Fatalf("Mismatch: %s unexpected review %#v", tc.name, reviews[0].Spec)
+			}
+		}
+	}
+}
+
+func TestCompilePolicies(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		policy    config.AdmissionPolicy
+		shouldErr bool
+	}{
+		{
+			name:   "valid policy",
+			policy: config.AdmissionPolicy{Name: "p", Kinds: []string{"SharedSecret"}, Expression: "object.metadata.name != ''"},
+		},
+		{
+			name:      "invalid expression",
+			policy:    config.AdmissionPolicy{Name: "p", Expression: "object.metadata.name !="},
+			shouldErr: true,
+		},
+		{
+			name:      "unknown action",
+			policy:    config.AdmissionPolicy{Name: "p", Expression: "true", Action: "Audit"},
+			shouldErr: true,
+		},
+		{
+			name:      "unknown kind",
+			policy:    config.AdmissionPolicy{Name: "p", Kinds: []string{"Secret"}, Expression: "true"},
+			shouldErr: true,
+		},
+	} {
+		_, err := CompilePolicies([]config.AdmissionPolicy{tc.policy})
+		if (err != nil) != tc.shouldErr {
+			t.Fatalf("Mismatch: %s Should error %t. got %v", tc.name, tc.shouldErr, err)
+		}
+	}
+}
+
+func TestAuthorizeAdmissionPolicies(t *testing.T) {
+	truVal := true
+	client.SetClient(subjectAccessReviews(true, fakekubeclientset.NewSimpleClientset()))
+	defer client.SetClient(nil)
+	client.SetShareClient(fakeshareclientset.NewSimpleClientset(&sharev1alpha1.SharedConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a-cm"},
+		Spec:       sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "team-a", Name: "cm"}},
+	}))
+	defer client.SetShareClient(nil)
+	policies, err := CompilePolicies([]config.AdmissionPolicy{
+		{
+			Name:       "team-prefix",
+			Kinds:      []string{"SharedSecret", "SharedConfigMap"},
+			Expression: "!object.metadata.name.startsWith('team-') || object.spec.configMapRef.namespace.startsWith('team-')",
+			Message:    "team shares must reference team namespaces",
+		},
+		{
+			Name:       "immutable-backing-namespace",
+			Kinds:      []string{"SharedConfigMap"},
+			Expression: "oldObject == null || oldObject.spec.configMapRef.namespace == object.spec.configMapRef.namespace",
+		},
+		{
+			Name:       "admins-only",
+			Kinds:      []string{"SharedConfigMap"},
+			Expression: "'admins' in request.userInfo.groups",
+			Action:     config.AdmissionPolicyWarn,
+		},
+		{
+			Name:       "existing-share",
+			Kinds:      []string{"Pod"},
+			Expression: "share != null && share.spec.configMapRef.namespace == object.metadata.namespace",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	sharedConfigMap := func(name, namespace string) runtime.Object {
+		return &sharev1alpha1.SharedConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: sharev1alpha1.GroupVersion.String(), Kind: "SharedConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: namespace, Name: "cm"}},
+		}
+	}
+	pod := func(namespace, share string) runtime.Object {
+		return &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: namespace},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{
+						Name: "csi-one",
+						VolumeSource: corev1.VolumeSource{
+							CSI: &corev1.CSIVolumeSource{
+								ReadOnly:         &truVal,
+								Driver:           string(operatorv1.SharedResourcesCSIDriver),
+								VolumeAttributes: map[string]string{"sharedConfigMap": share},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name        string
+		object      runtime.Object
+		oldObject   runtime.Object
+		groups      []string
+		shouldAdmit bool
+		shouldWarn  bool
+		denial      string
+	}{
+		{
+			name:        "team share referencing its team namespace",
+			object:      sharedConfigMap("team-a-cm", "team-a"),
+			groups:      []string{"admins"},
+			shouldAdmit: true,
+		},
+		{
+			name:        "team share referencing another namespace",
+			object:      sharedConfigMap("team-a-cm", "shared"),
+			groups:      []string{"admins"},
+			shouldAdmit: false,
+			denial:      "team shares must reference team namespaces",
+		},
+		{
+			name:        "share created by a user outside of the admins group",
+			object:      sharedConfigMap("other-cm", "shared"),
+			shouldAdmit: true,
+			shouldWarn:  true,
+		},
+		{
+			name:        "update changing the backing namespace",
+			object:      sharedConfigMap("other-cm", "other"),
+			oldObject:   sharedConfigMap("other-cm", "shared"),
+			groups:      []string{"admins"},
+			shouldAdmit: false,
+			denial:      "immutable-backing-namespace",
+		},
+		{
+			name:        "pod in the namespace of the backing resource of its share",
+			object:      pod("team-a", "team-a-cm"),
+			shouldAdmit: true,
+		},
+		{
+			name:        "pod in another namespace",
+			object:      pod("test", "team-a-cm"),
+			shouldAdmit: false,
+			denial:      "existing-share",
+		},
+		{
+			name:        "pod referencing a missing share",
+			object:      pod("team-a", "missing"),
+			shouldAdmit: false,
+			denial:      "existing-share",
+		},
+	} {
+		raw, err := json.Marshal(tc.object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: raw},
+				Operation: admissionv1.Create,
+				UserInfo:  authenticationv1.UserInfo{Username: "user", Groups: tc.groups},
+			},
+		}
+		if tc.oldObject != nil {
+			oldRaw, err := json.Marshal(tc.oldObject)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.OldObject = runtime.RawExtension{Raw: oldRaw}
+			req.Operation = admissionv1.Update
+		}
+
+		response := NewWebhook(config.SetupNameReservation(), policies).Authorized(req)
+
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t: %s", tc.name, tc.shouldAdmit, response.Allowed, response.Result.Message)
+		}
+		if !tc.shouldAdmit && !strings.Contains(response.Result.Message, tc.denial) {
+			t.Fatalf("Mismatch: %s Should be denied with %q, got %q", tc.name, tc.denial, response.Result.Message)
+		}
+		if (len(response.Warnings) > 0) != tc.shouldWarn {
+			t.Fatalf("Mismatch: %s Should warn %t. got %v", tc.name, tc.shouldWarn, response.Warnings)
+		}
+	}
+}
+
+func TestAuthorizePodVolumes(t *testing.T) {
+	truVal := true
+	falseVal := false
+	shareClient := fakeshareclientset.NewSimpleClientset(
+		&sharev1alpha1.SharedConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared-cm"},
+			Spec:       sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+		},
+		&sharev1alpha1.SharedSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared-secret-no-ref"},
+		},
+	)
+	shareClient.PrependReactor("get", "sharedconfigmaps", func(action fakekubetesting.Action) (handled bool, ret runtime.Object, err error) {
+		if action.(fakekubetesting.GetAction).GetName() != "unavailable" {
+			return false, nil, nil
+		}
+		return true, nil, fmt.Errorf("etcdserver: request timed out")
+	})
+	client.SetShareClient(shareClient)
+	defer client.SetShareClient(nil)
+	defer client.SetClient(nil)
+
+	pod := func(readOnly *bool, attributes map[string]string) runtime.Object {
+		return &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "test"},
+			Spec: corev1.PodSpec{
+				ServiceAccountName: "builder",
+				Volumes: []corev1.Volume{
+					{
+						Name: "csi-one",
+						VolumeSource: corev1.VolumeSource{
+							CSI: &corev1.CSIVolumeSource{
+								ReadOnly:         readOnly,
+								Driver:           string(operatorv1.SharedResourcesCSIDriver),
+								VolumeAttributes: attributes,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name        string
+		object      runtime.Object
+		oldObject   runtime.Object
+		sarAllowed  bool
+		shouldAdmit bool
+		shouldWarn  bool
+		message     string
+	}{
+		{
+			name:        "valid share volume",
+			object:      pod(&truVal, map[string]string{"sharedConfigMap": "shared-cm"}),
+			sarAllowed:  true,
+			shouldAdmit: true,
+		},
+		{
+			name:        "no share attribute",
+			object:      pod(&truVal, map[string]string{}),
+			sarAllowed:  true,
+			shouldAdmit: false,
+			message:     "has to be set",
+		},
+		{
+			name:        "both share attributes",
+			object:      pod(&truVal, map[string]string{"sharedConfigMap": "shared-cm", "sharedSecret": "shared-secret"}),
+			sarAllowed:  true,
+			shouldAdmit: false,
+			message:     "cannot support both",
+		},
+		{
+			name:        "unparsable refreshResource",
+			object:      pod(&truVal, map[string]string{"sharedConfigMap": "shared-cm", "refreshResource": "sometimes"}),
+			sarAllowed:  true,
+			shouldAdmit: true,
+			shouldWarn:  true,
+			message:     "is not a boolean",
+		},
+		{
+			name:        "invalid fileMode",
+			object:      pod(&truVal, map[string]string{"sharedConfigMap": "shared-cm", "fileMode": "0999"}),
+			sarAllowed:  true,
+			shouldAdmit: false,
+			message:     "has to be a file mode",
+		},
+		{
+			name:        "missing share",
+			object:      pod(&truVal, map[string]string{"sharedConfigMap": "missing"}),
+			sarAllowed:  true,
+			shouldAdmit: true,
+			shouldWarn:  true,
+			message:     "does not exist",
+		},
+		{
+			name:        "share lookup failure",
+			object:      pod(&truVal, map[string]string{"sharedConfigMap": "unavailable"}),
+			sarAllowed:  true,
+			shouldAdmit: false,
+			message:     `could not look up share "unavailable"`,
+		},
+		{
+			name:        "share without backing resource",
+			object:      pod(&truVal, map[string]string{"sharedSecret": "shared-secret-no-ref"}),
+			sarAllowed:  true,
+			shouldAdmit: false,
+			message:     "need to be set",
+		},
+		{
+			name:        "service account not allowed to use the share",
+			object:      pod(&truVal, map[string]string{"sharedConfigMap": "shared-cm"}),
+			sarAllowed:  false,
+			shouldAdmit: false,
+			message:     `service account "builder" is not allowed to use share "shared-cm"`,
+		},
+		{
+			name:        "update of a pod whose service account lost the use of the share",
+			object:      pod(&truVal, map[string]string{"sharedConfigMap": "shared-cm"}),
+			oldObject:   pod(&truVal, map[string]string{"sharedConfigMap": "shared-cm"}),
+			sarAllowed:  false,
+			shouldAdmit: true,
+		},
+		{
+			name:        "update validates the new pod",
+			object:      pod(&falseVal, map[string]string{"sharedConfigMap": "shared-cm"}),
+			oldObject:   pod(&truVal, map[string]string{"sharedConfigMap": "shared-cm"}),
+			sarAllowed:  true,
+			shouldAdmit: false,
+			message:     "ReadOnly false",
+		},
+	} {
+		client.SetClient(subjectAccessReviews(tc.sarAllowed, fakekubeclientset.NewSimpleClientset()))
+		raw, err := json.Marshal(tc.object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: raw},
+				Resource:  podGvr,
+				Operation: admissionv1.Create,
+			},
+		}
+		if tc.oldObject != nil {
+			oldRaw, err := json.Marshal(tc.oldObject)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.OldObject = runtime.RawExtension{Raw: oldRaw}
+			req.Operation = admissionv1.Update
+		}
+
+		response := NewWebhook(config.SetupNameReservation(), nil).Authorized(req)
+
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t: %s", tc.name, tc.shouldAdmit, response.Allowed, response.Result.Message)
+		}
+		if (len(response.Warnings) > 0) != tc.shouldWarn {
+			t.Fatalf("Mismatch: %s Should warn %t. got %v", tc.name, tc.shouldWarn, response.Warnings)
+		}
+		if len(tc.message) > 0 && !strings.Contains(response.Result.Message+strings.Join(response.Warnings, ""), tc.message) {
+			t.Fatalf("Mismatch: %s Should mention %q, got %q %v", tc.name, tc.message, response.Result.Message, response.Warnings)
+		}
+	}
+}
+
+func TestAuthorizeWorkloads(t *testing.T) {
+	truVal := true
+	falseVal := false
+	client.SetShareClient(fakeshareclientset.NewSimpleClientset(&sharev1alpha1.SharedConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared-cm"},
+		Spec:       sharev1alpha1.SharedConfigMapSpec{ConfigMapRef: sharev1alpha1.SharedConfigMapReference{Namespace: "shared", Name: "cm"}},
+	}))
+	defer client.SetShareClient(nil)
+	defer client.SetClient(nil)
+
+	template := func(readOnly *bool) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{
+						Name: "csi-one",
+						VolumeSource: corev1.VolumeSource{
+							CSI: &corev1.CSIVolumeSource{
+								ReadOnly:         readOnly,
+								Driver:           string(operatorv1.SharedResourcesCSIDriver),
+								VolumeAttributes: map[string]string{"sharedConfigMap": "shared-cm"},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	csi := map[string]interface{}{
+		"driver":           string(operatorv1.SharedResourcesCSIDriver),
+		"readOnly":         true,
+		"volumeAttributes": map[string]interface{}{"sharedConfigMap": "shared-cm"},
+	}
+
+	for _, tc := range []struct {
+		name        string
+		kind        string
+		object      interface{}
+		oldObject   interface{}
+		sarAllowed  bool
+		shouldAdmit bool
+		message     string
+	}{
+		{
+			name:        "deployment with a valid share volume",
+			kind:        "Deployment",
+			object:      &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"}, Spec: appsv1.DeploymentSpec{Template: template(&truVal)}},
+			sarAllowed:  true,
+			shouldAdmit: true,
+		},
+		{
+			name:        "statefulset whose service account cannot use the share",
+			kind:        "StatefulSet",
+			object:      &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"}, Spec: appsv1.StatefulSetSpec{Template: template(&truVal)}},
+			sarAllowed:  false,
+			shouldAdmit: false,
+			message:     `service account "default" is not allowed to use share "shared-cm"`,
+		},
+		{
+			name:        "deployment update keeping a share its service account can no longer use",
+			kind:        "Deployment",
+			object:      &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test", Finalizers: []string{}}, Spec: appsv1.DeploymentSpec{Template: template(&truVal)}},
+			oldObject:   &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test", Finalizers: []string{"foregroundDeletion"}}, Spec: appsv1.DeploymentSpec{Template: template(&truVal)}},
+			sarAllowed:  false,
+			shouldAdmit: true,
+			message:     `service account "default" is not allowed to use share "shared-cm"`,
+		},
+		{
+			name:        "deployment update adding a share its service account cannot use",
+			kind:        "Deployment",
+			object:      &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"}, Spec: appsv1.DeploymentSpec{Template: template(&truVal)}},
+			oldObject:   &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"}},
+			sarAllowed:  false,
+			shouldAdmit: false,
+			message:     `Not allowed to update Deployment "app"`,
+		},
+		{
+			name: "cronjob with a read-write share volume",
+			kind: "CronJob",
+			object: &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"}, Spec: batchv1.CronJobSpec{
+				JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: template(&falseVal)}},
+			}},
+			sarAllowed:  true,
+			shouldAdmit: false,
+			message:     "ReadOnly false",
+		},
+		{
+			name: "buildconfig whose builder service account cannot use the share",
+			kind: "BuildConfig",
+			object: map[string]interface{}{
+				"apiVersion": "build.openshift.io/v1",
+				"kind":       "BuildConfig",
+				"metadata":   map[string]interface{}{"name": "app", "namespace": "test"},
+				"spec": map[string]interface{}{
+					"strategy": map[string]interface{}{
+						"type": "Docker",
+						"dockerStrategy": map[string]interface{}{
+							"volumes": []interface{}{
+								map[string]interface{}{"name": "csi-one", "source": map[string]interface{}{"type": "CSI", "csi": csi}},
+							},
+						},
+					},
+				},
+			},
+			sarAllowed:  false,
+			shouldAdmit: false,
+			message:     `service account "builder" is not allowed to use share "shared-cm"`,
+		},
+		{
+			name: "taskrun with a workspace referencing two shares",
+			kind: "TaskRun",
+			object: map[string]interface{}{
+				"apiVersion": "tekton.dev/v1",
+				"kind":       "TaskRun",
+				"metadata":   map[string]interface{}{"name": "app", "namespace": "test"},
+				"spec": map[string]interface{}{
+					"workspaces": []interface{}{
+						map[string]interface{}{"name": "csi-one", "csi": map[string]interface{}{
+							"driver":           string(operatorv1.SharedResourcesCSIDriver),
+							"readOnly":         true,
+							"volumeAttributes": map[string]interface{}{"sharedConfigMap": "shared-cm", "sharedSecret": "shared-secret"},
+						}},
+					},
+				},
+			},
+			sarAllowed:  true,
+			shouldAdmit: false,
+			message:     "cannot support both",
+		},
+		{
+			name: "taskrun with a valid pod template volume",
+			kind: "TaskRun",
+			object: map[string]interface{}{
+				"apiVersion": "tekton.dev/v1",
+				"kind":       "TaskRun",
+				"metadata":   map[string]interface{}{"name": "app", "namespace": "test"},
+				"spec": map[string]interface{}{
+					"podTemplate": map[string]interface{}{
+						"volumes": []interface{}{map[string]interface{}{"name": "csi-one", "csi": csi}},
+					},
+				},
+			},
+			sarAllowed:  true,
+			shouldAdmit: true,
+		},
+	} {
+		client.SetClient(subjectAccessReviews(tc.sarAllowed, fakekubeclientset.NewSimpleClientset()))
+		raw, err := json.Marshal(tc.object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Kind: tc.kind},
+				Object:    runtime.RawExtension{Raw: raw},
+				Operation: admissionv1.Create,
+			},
+		}
+		if tc.oldObject != nil {
+			oldRaw, err := json.Marshal(tc.oldObject)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.OldObject = runtime.RawExtension{Raw: oldRaw}
+			req.Operation = admissionv1.Update
+		}
+
+		hook := NewWebhook(config.SetupNameReservation(), nil)
+		if !hook.Validate(req) {
+			t.Fatalf("Mismatch: %s Should be validated", tc.name)
+		}
+		response := hook.Authorized(req)
+
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t: %s", tc.name, tc.shouldAdmit, response.Allowed, response.Result.Message)
+		}
+		if !tc.shouldAdmit && !strings.Contains(response.Result.Message, tc.message) {
+			t.Fatalf("Mismatch: %s Should be denied with %q, got %q", tc.name, tc.message, response.Result.Message)
+		}
+		if tc.shouldAdmit && !strings.Contains(strings.Join(response.Warnings, ""), tc.message) {
+			t.Fatalf("Mismatch: %s Should warn with %q, got %v", tc.name, tc.message, response.Warnings)
+		}
+	}
+}
+
+func TestInvalidMutatingDefaults(t *testing.T) {
+	for _, defaults := range []map[string]string{
+		{"fileMode": "0999"},
+		{"fileMode": "0"},
+		{"refreshResource": "maybe"},
+	} {
+		if _, err := NewMutatingWebhook(defaults); err == nil {
+			t.Fatalf("expected the defaults %v to be rejected", defaults)
+		}
+	}
+}
+
+func TestMutatePodVolumes(t *testing.T) {
+	truVal := true
+	hook, err := NewMutatingWebhook(map[string]string{"refreshResource": "false", "fileMode": "0440", "sharedSecret": "default-share"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	pod := func(readOnly *bool, attributes map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "test"},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{
+						Name:         "other",
+						VolumeSource: corev1.VolumeSource{CSI: &corev1.CSIVolumeSource{Driver: "other.csi.example.com"}},
+					},
+					{
+						Name: "csi-one",
+						VolumeSource: corev1.VolumeSource{
+							CSI: &corev1.CSIVolumeSource{
+								ReadOnly:         readOnly,
+								Driver:           string(operatorv1.SharedResourcesCSIDriver),
+								VolumeAttributes: attributes,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		pod        *corev1.Pod
+		operation  admissionv1.Operation
+		patchPaths []string
+		annotation string
+	}{
+		{
+			name:      "volume without settings",
+			pod:       pod(nil, map[string]string{"sharedConfigMap": "shared-cm"}),
+			operation: admissionv1.Create,
+			patchPaths: []string{
+				"/metadata/annotations",
+				"/spec/volumes/1/csi/readOnly",
+				"/spec/volumes/1/csi/volumeAttributes/fileMode",
+				"/spec/volumes/1/csi/volumeAttributes/refreshResource",
+			},
+			annotation: "csi-one/readOnly=true,csi-one/fileMode=0440,csi-one/refreshResource=false",
+		},
+		{
+			name:       "volume setting its own refreshResource",
+			pod:        pod(&truVal, map[string]string{"sharedConfigMap": "shared-cm", "refreshResource": "true"}),
+			operation:  admissionv1.Create,
+			patchPaths: []string{"/metadata/annotations", "/spec/volumes/1/csi/volumeAttributes/fileMode"},
+			annotation: "csi-one/fileMode=0440",
+		},
+		{
+			name:      "volume with all its settings",
+			pod:       pod(&truVal, map[string]string{"sharedConfigMap": "shared-cm", "refreshResource": "true", "fileMode": "0400"}),
+			operation: admissionv1.Create,
+		},
+		{
+			name:      "update",
+			pod:       pod(nil, map[string]string{"sharedConfigMap": "shared-cm"}),
+			operation: admissionv1.Update,
+		},
+	} {
+		raw, err := json.Marshal(tc.pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+				Object:    runtime.RawExtension{Raw: raw},
+				Operation: tc.operation,
+			},
+		}
+
+		if !hook.Validate(req) {
+			t.Fatalf("Mismatch: %s Should be validated", tc.name)
+		}
+		response := hook.Authorized(req)
+
+		if !response.Allowed {
+			t.Fatalf("Mismatch: %s Should admit. got %s", tc.name, response.Result.Message)
+		}
+		paths := []string{}
+		annotation := ""
+		for _, patch := range response.Patches {
+			paths = append(paths, patch.Path)
+			if patch.Path == "/metadata/annotations" {
+				annotations, _ := patch.Value.(map[string]interface{})
+				annotation, _ = annotations[consts.VolumeDefaultsAnnotation].(string)
+			}
+		}
+		sort.Strings(paths)
+		if strings.Join(paths, " ") != strings.Join(tc.patchPaths, " ") {
+			t.Fatalf("Mismatch: %s Should patch %v. got %v", tc.name, tc.patchPaths, paths)
+		}
+		if annotation != tc.annotation {
+			t.Fatalf("Mismatch: %s Should annotate %q. got %q", tc.name, tc.annotation, annotation)
+		}
+	}
+}
+
+func TestAuthorizeShareDeletion(t *testing.T) {
+	truVal := true
+	consumer := func(name, share string, phase corev1.PodPhase, owner *metav1.OwnerReference, labels map[string]string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: labels},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{
+						Name: "csi-one",
+						VolumeSource: corev1.VolumeSource{
+							CSI: &corev1.CSIVolumeSource{
+								ReadOnly:         &truVal,
+								Driver:           string(operatorv1.SharedResourcesCSIDriver),
+								VolumeAttributes: map[string]string{"sharedSecret": share},
+							},
+						},
+					},
+				},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+		if owner != nil {
+			pod.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+		return pod
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := StartPodInformer(fakekubeclientset.NewSimpleClientset(
+		consumer("app-abc123-xyz", "in-use", corev1.PodRunning,
+			&metav1.OwnerReference{Kind: "ReplicaSet", Name: "app-abc123", Controller: &truVal}, map[string]string{"pod-template-hash": "abc123"}),
+		consumer("standalone", "in-use", corev1.PodPending, nil, nil),
+		consumer("done", "done", corev1.PodSucceeded, nil, nil),
+		consumer("follower", "old-name", corev1.PodRunning, nil, nil),
+	), stopCh); err != nil {
+		t.Fatal(err)
+	}
+	defer setPodInformer(nil)
+	informer := podInformer
+	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+		t.Fatal("failed to wait for the pod cache to sync")
+	}
+	client.SetShareClient(fakeshareclientset.NewSimpleClientset(&sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: "new-name", Annotations: map[string]string{consts.AliasesAnnotation: "old-name"}},
+	}))
+	defer client.SetShareClient(nil)
+	defer func() { config.LoadedConfig.SharePolicy = config.SharePolicy{} }()
+
+	for _, tc := range []struct {
+		name        string
+		protection  string
+		share       string
+		aliases     string
+		force       bool
+		unwatched   bool
+		shouldAdmit bool
+		message     string
+	}{
+		{
+			name:        "unprotected deletion",
+			share:       "in-use",
+			shouldAdmit: true,
+		},
+		{
+			name:        "warned deletion",
+			protection:  config.DeletionProtectionWarn,
+			share:       "in-use",
+			shouldAdmit: true,
+			message:     "Deployment test/app, Pod test/standalone",
+		},
+		{
+			name:        "enforced deletion",
+			protection:  config.DeletionProtectionEnforce,
+			share:       "in-use",
+			shouldAdmit: false,
+			message:     "Deployment test/app, Pod test/standalone",
+		},
+		{
+			name:        "forced deletion",
+			protection:  config.DeletionProtectionEnforce,
+			share:       "in-use",
+			force:       true,
+			shouldAdmit: true,
+			message:     "Deployment test/app, Pod test/standalone",
+		},
+		{
+			name:        "share only consumed by terminated pods",
+			protection:  config.DeletionProtectionEnforce,
+			share:       "done",
+			shouldAdmit: true,
+		},
+		{
+			name:        "renamed share",
+			protection:  config.DeletionProtectionEnforce,
+			share:       "old-name",
+			shouldAdmit: true,
+			message:     `follow share "new-name"`,
+		},
+		{
+			name:        "share consumed through its alias",
+			protection:  config.DeletionProtectionEnforce,
+			share:       "new-name",
+			aliases:     "old-name",
+			shouldAdmit: false,
+			message:     "Pod test/follower",
+		},
+		{
+			name:        "enforced deletion with unwatched pods",
+			protection:  config.DeletionProtectionEnforce,
+			share:       "in-use",
+			unwatched:   true,
+			shouldAdmit: false,
+			message:     "its consumers could not be looked up",
+		},
+		{
+			name:        "warned deletion with unwatched pods",
+			protection:  config.DeletionProtectionWarn,
+			share:       "in-use",
+			unwatched:   true,
+			shouldAdmit: true,
+			message:     "could not check whether pods consume",
+		},
+	} {
+		config.LoadedConfig.SharePolicy = config.SharePolicy{DeletionProtection: tc.protection}
+		if tc.unwatched {
+			setPodInformer(nil)
+		} else {
+			setPodInformer(informer)
+		}
+		share := &sharev1alpha1.SharedSecret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: sharev1alpha1.GroupVersion.String(), Kind: "SharedSecret"},
+			ObjectMeta: metav1.ObjectMeta{Name: tc.share},
+			Spec:       sharev1alpha1.SharedSecretSpec{SecretRef: sharev1alpha1.SharedSecretReference{Namespace: "shared", Name: "secret"}},
+		}
+		share.Annotations = map[string]string{}
+		if len(tc.aliases) > 0 {
+			share.Annotations[consts.AliasesAnnotation] = tc.aliases
+		}
+		if tc.force {
+			share.Annotations[consts.ForceDeleteAnnotation] = "true"
+		}
+		raw, err := json.Marshal(share)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admissionctl.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Kind: "SharedSecret"},
+				OldObject: runtime.RawExtension{Raw: raw},
+				Operation: admissionv1.Delete,
+			},
+		}
+
+		response := NewWebhook(config.SetupNameReservation(), nil).Authorized(req)
+
+		if response.Allowed != tc.shouldAdmit {
+			t.Fatalf("Mismatch: %s Should admit %t. got %t: %s", tc.name, tc.shouldAdmit, response.Allowed, response.Result.Message)
+		}
+		if (len(tc.message) > 0) != (!response.Allowed || len(response.Warnings) > 0) {
+			t.Fatalf("Mismatch: %s Should report the consumers %t. got %q %v", tc.name, len(tc.message) > 0, response.Result.Message, response.Warnings)
+		}
+		if len(tc.message) > 0 && !strings.Contains(response.Result.Message+strings.Join(response.Warnings, ""), tc.message) {
+			t.Fatalf("Mismatch: %s Should mention %q, got %q %v", tc.name, tc.message, response.Result.Message, response.Warnings)
+		}
+	}
+}
+
+func TestShareInformers(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := StartShareInformers(fakeshareclientset.NewSimpleClientset(&sharev1alpha1.SharedSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: "new-name", Annotations: map[string]string{consts.AliasesAnnotation: "old-name"}},
+	}), stopCh); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		client.SetSharedConfigMapsLister(nil)
+		client.SetSharedSecretsLister(nil)
+		client.SetSharedConfigMapsIndexer(nil)
+		client.SetSharedSecretsIndexer(nil)
+	}()
+
+	// without a share client, the shares can only be found in the informers
+	if renamed := renamedShare(kindSharedSecret, "old-name"); renamed != "new-name" {
+		t.Fatalf("expected old-name to be an alias of new-name, got %q", renamed)
+	}
+	share, err := client.FetchSharedSecret("new-name")
+	if err != nil || share == nil {
+		t.Fatalf("expected new-name to be cached, got %v %v", share, err)
+	}
+}
@@ -0,0 +1,137 @@
+package csidriver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+// maxListedConsumers bounds the number of workloads named in the denials and warnings about the deletion of a share
+const maxListedConsumers = 10
+
+// authorizeShareDeletion guards the deletion of a share consumed by pods, as deleting it removes its content from
+// their volumes: depending on the share policy, the deletion is denied unless the share carries the
+// ForceDeleteAnnotation, or allowed with a warning listing the workloads of the consuming pods
+func (s *SharedResourcesCSIDriverWebhook) authorizeShareDeletion(request admissionctl.Request, kind, shareName string, annotations map[string]string) admissionctl.Response {
+	var ret admissionctl.Response
+	protection := config.LoadedConfig.SharePolicy.DeletionProtection
+	if len(protection) == 0 {
+		ret = admissionctl.Allowed(fmt.Sprintf("Allowed to delete %s", kind))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	if renamed := renamedShare(kind, shareName); len(renamed) > 0 {
+		// the consumers follow the share listing the deleted share among its aliases
+		ret = admissionctl.Allowed(fmt.Sprintf("Allowed to delete %s", kind)).WithWarnings(
+			fmt.Sprintf("the volumes referencing %s %q follow share %q, which lists it among its aliases", kind, shareName, renamed))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	workloads, err := shareConsumers(kind, shareName, client.ShareAliases(annotations))
+	if err != nil {
+		// not being able to look the consumers up only keeps the share from being deleted when the protection is
+		// enforced, the force delete annotation still letting it go
+		klog.Warningf("unable to look up the consumers of %s %s: %s", kind, shareName, err.Error())
+		if protection == config.DeletionProtectionEnforce && annotations[consts.ForceDeleteAnnotation] != "true" {
+			ret = admissionctl.Denied(fmt.Sprintf("Not allowed to delete %s with name %q as its consumers could not be looked up: %s; annotate it with %s=true to delete it anyway",
+				kind, shareName, err.Error(), consts.ForceDeleteAnnotation))
+			ret.UID = request.AdmissionRequest.UID
+			return ret
+		}
+		ret = admissionctl.Allowed(fmt.Sprintf("Allowed to delete %s", kind)).WithWarnings(
+			fmt.Sprintf("could not check whether pods consume %s %q: %s", kind, shareName, err.Error()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	if len(workloads) == 0 {
+		ret = admissionctl.Allowed(fmt.Sprintf("Allowed to delete %s", kind))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	consumers := listConsumers(workloads)
+	force := annotations[consts.ForceDeleteAnnotation] == "true"
+	if protection == config.DeletionProtectionEnforce && !force {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to delete %s with name %q as it is consumed by %s; annotate it with %s=true to delete it anyway",
+			kind, shareName, consumers, consts.ForceDeleteAnnotation))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	klog.Infof("DELETE of %s %s consumed by %s by user %s allowed", kind, shareName, consumers, request.UserInfo.Username)
+	ret = admissionctl.Allowed(fmt.Sprintf("Allowed to delete %s", kind)).WithWarnings(
+		fmt.Sprintf("deleting %s %q removes its content from the volumes of %s", kind, shareName, consumers))
+	ret.UID = request.AdmissionRequest.UID
+	return ret
+}
+
+// renamedShare returns the name of the share listing the given share among its aliases, if any
+func renamedShare(kind, shareName string) string {
+	if kind == kindSharedConfigMap {
+		if scm, err := client.SharedConfigMapForAlias(shareName); err == nil && scm != nil {
+			return scm.Name
+		}
+		return ""
+	}
+	if ss, err := client.SharedSecretForAlias(shareName); err == nil && ss != nil {
+		return ss.Name
+	}
+	return ""
+}
+
+// shareConsumers returns the sorted workloads, or pods without workload, of the running pods, cluster wide, whose
+// shared resource volumes reference the share, by its name or one of its aliases
+func shareConsumers(kind, shareName string, aliases []string) ([]string, error) {
+	pods := []*corev1.Pod{}
+	for _, name := range append([]string{shareName}, aliases...) {
+		named, err := podsForShare(kind, name)
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, named...)
+	}
+	workloads := map[string]bool{}
+	for _, pod := range pods {
+		// the watch leaves the terminated pods out, but they can still be cached until it catches up
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		workloads[podWorkload(pod)] = true
+	}
+	sorted := make([]string, 0, len(workloads))
+	for workload := range workloads {
+		sorted = append(sorted, workload)
+	}
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+// podWorkload names the workload owning the pod, as "kind namespace/name"; the Deployment owning a pod through a
+// ReplicaSet is found from the name of the ReplicaSet, suffixed with the pod template hash
+func podWorkload(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	switch {
+	case owner == nil:
+		return fmt.Sprintf("Pod %s/%s", pod.Namespace, pod.Name)
+	case owner.Kind == "ReplicaSet" && len(pod.Labels["pod-template-hash"]) > 0 &&
+		strings.HasSuffix(owner.Name, "-"+pod.Labels["pod-template-hash"]):
+		return fmt.Sprintf("Deployment %s/%s", pod.Namespace, strings.TrimSuffix(owner.Name, "-"+pod.Labels["pod-template-hash"]))
+	default:
+		return fmt.Sprintf("%s %s/%s", owner.Kind, pod.Namespace, owner.Name)
+	}
+}
+
+func listConsumers(workloads []string) string {
+	if len(workloads) > maxListedConsumers {
+		return fmt.Sprintf("%s and %d more", strings.Join(workloads[:maxListedConsumers], ", "), len(workloads)-maxListedConsumers)
+	}
+	return strings.Join(workloads, ", ")
+}
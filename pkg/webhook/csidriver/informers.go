@@ -0,0 +1,139 @@
+package csidriver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	sharev1clientset "github.com/openshift/client-go/sharedresource/clientset/versioned"
+	shareinformer "github.com/openshift/client-go/sharedresource/informers/externalversions"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/client"
+)
+
+// podShareIndex indexes the pods by the shares their shared resource volumes reference
+const podShareIndex = "share"
+
+var (
+	podInformerLock = sync.RWMutex{}
+	podInformer     cache.SharedIndexInformer
+)
+
+// StartPodInformer watches the pods, cluster wide, leaving out the terminated ones, indexed by the shares their
+// volumes reference, so that the consumers of a share being deleted are looked up without listing the pods
+func StartPodInformer(kubeClient kubernetes.Interface, stopCh <-chan struct{}) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.AndSelectors(
+				fields.OneTermNotEqualSelector("status.phase", string(corev1.PodSucceeded)),
+				fields.OneTermNotEqualSelector("status.phase", string(corev1.PodFailed))).String()
+		}))
+	informer := factory.Core().V1().Pods().Informer()
+	if err := informer.AddIndexers(cache.Indexers{podShareIndex: podShareIndexFunc}); err != nil {
+		return err
+	}
+	setPodInformer(informer)
+	factory.Start(stopCh)
+	return nil
+}
+
+// shareSyncTimeout bounds how long the webhook waits for the shares to be cached before it starts serving
+const shareSyncTimeout = time.Minute
+
+// StartShareInformers watches the shares, indexed by their aliases and backing resources like the driver does, so
+// that the shares referenced by pods, and the aliases of shares being created or deleted, are looked up without
+// listing the shares; it returns once they are cached, or an error when they are not within shareSyncTimeout, in
+// which case the shares keep being fetched from the API server
+func StartShareInformers(shareClient sharev1clientset.Interface, stopCh <-chan struct{}) error {
+	factory := shareinformer.NewSharedInformerFactory(shareClient, 0)
+	sharedConfigMapInformer := factory.Sharedresource().V1alpha1().SharedConfigMaps().Informer()
+	sharedSecretInformer := factory.Sharedresource().V1alpha1().SharedSecrets().Informer()
+	if err := sharedConfigMapInformer.AddIndexers(cache.Indexers{
+		client.BackingResourceIndex: client.SharedConfigMapBackingResourceIndexFunc,
+		client.AliasIndex:           client.SharedConfigMapAliasIndexFunc,
+	}); err != nil {
+		return err
+	}
+	if err := sharedSecretInformer.AddIndexers(cache.Indexers{
+		client.BackingResourceIndex: client.SharedSecretBackingResourceIndexFunc,
+		client.AliasIndex:           client.SharedSecretAliasIndexFunc,
+	}); err != nil {
+		return err
+	}
+	factory.Start(stopCh)
+	// an unsynced index would miss shares, so the lookups only switch to the informers once they are synced
+	syncCh := make(chan struct{})
+	timer := time.AfterFunc(shareSyncTimeout, func() { close(syncCh) })
+	defer timer.Stop()
+	if !cache.WaitForCacheSync(syncCh, sharedConfigMapInformer.HasSynced, sharedSecretInformer.HasSynced) {
+		return fmt.Errorf("the shares were not cached within %s", shareSyncTimeout)
+	}
+	client.SetSharedConfigMapsLister(factory.Sharedresource().V1alpha1().SharedConfigMaps().Lister())
+	client.SetSharedSecretsLister(factory.Sharedresource().V1alpha1().SharedSecrets().Lister())
+	client.SetSharedConfigMapsIndexer(sharedConfigMapInformer.GetIndexer())
+	client.SetSharedSecretsIndexer(sharedSecretInformer.GetIndexer())
+	return nil
+}
+
+func setPodInformer(informer cache.SharedIndexInformer) {
+	podInformerLock.Lock()
+	defer podInformerLock.Unlock()
+	podInformer = informer
+}
+
+// podsForShare returns the cached pods whose volumes reference the share of the given kind
+func podsForShare(kind, shareName string) ([]*corev1.Pod, error) {
+	podInformerLock.RLock()
+	informer := podInformer
+	podInformerLock.RUnlock()
+	if informer == nil {
+		return nil, fmt.Errorf("the pods are not watched")
+	}
+	if !informer.HasSynced() {
+		return nil, fmt.Errorf("the pods are not synced yet")
+	}
+	objs, err := informer.GetIndexer().ByIndex(podShareIndex, podShareIndexKey(kind, shareName))
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func podShareIndexKey(kind, shareName string) string {
+	return kind + "/" + shareName
+}
+
+func podShareIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return []string{}, nil
+	}
+	indexKeys := []string{}
+	for _, volume := range pod.Spec.Volumes {
+		csi := volume.VolumeSource.CSI
+		if csi == nil || csi.Driver != string(operatorv1.SharedResourcesCSIDriver) {
+			continue
+		}
+		if name := csi.VolumeAttributes[sharedConfigMapShareKey]; len(name) > 0 {
+			indexKeys = append(indexKeys, podShareIndexKey(kindSharedConfigMap, name))
+		}
+		if name := csi.VolumeAttributes[sharedSecretShareKey]; len(name) > 0 {
+			indexKeys = append(indexKeys, podShareIndexKey(kindSharedSecret, name))
+		}
+	}
+	return indexKeys, nil
+}
@@ -0,0 +1,155 @@
+package csidriver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/consts"
+)
+
+const (
+	MutatingURI         string = "/resource-mutation"
+	MutatingWebhookName string = "sharedresourcecsidriver-defaults"
+)
+
+// SharedResourcesCSIDriverMutatingWebhook defaults the settings of the shared resource volumes of the pods being
+// created: it makes them read only, as the driver requires, and sets the default volume attributes of the
+// configuration they do not set
+type SharedResourcesCSIDriverMutatingWebhook struct {
+	defaults map[string]string
+}
+
+// NewMutatingWebhook creates a new mutating webhook setting the given default volume attributes, returning an error
+// when one of them would be rejected by the validating webhook or the driver; the attributes referencing shares are
+// ignored, as a default share makes no sense
+func NewMutatingWebhook(defaults map[string]string) (*SharedResourcesCSIDriverMutatingWebhook, error) {
+	filtered := map[string]string{}
+	for key, value := range defaults {
+		switch key {
+		case sharedConfigMapShareKey, sharedSecretShareKey:
+			klog.Warningf("ignoring the default volume attribute %q, shares cannot be defaulted", key)
+			continue
+		case refreshResourceKey:
+			if _, err := strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("the default volumeAttribute %q has to be a boolean, not %q", key, value)
+			}
+		case fileModeKey:
+			if err := validateFileMode(value); err != nil {
+				return nil, fmt.Errorf("invalid default: %s", err.Error())
+			}
+		}
+		filtered[key] = value
+	}
+	return &SharedResourcesCSIDriverMutatingWebhook{defaults: filtered}, nil
+}
+
+// GetURI implements Webhook interface
+func (m *SharedResourcesCSIDriverMutatingWebhook) GetURI() string { return MutatingURI }
+
+// Name implements Webhook interface
+func (m *SharedResourcesCSIDriverMutatingWebhook) Name() string { return MutatingWebhookName }
+
+// Validate if the incoming request even valid
+func (m *SharedResourcesCSIDriverMutatingWebhook) Validate(req admissionctl.Request) bool {
+	return req.Kind.Kind == "Pod"
+}
+
+// Authorized implements Webhook interface, patching the pod with the defaulted settings of its shared resource
+// volumes, which it lists in the VolumeDefaultsAnnotation
+func (m *SharedResourcesCSIDriverMutatingWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	var ret admissionctl.Response
+	// the volumes of a pod cannot be changed once it is created
+	if request.Operation != admissionv1.Create {
+		ret = admissionctl.Allowed("No volume to default")
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	pod := &unstructured.Unstructured{}
+	if err := json.Unmarshal(request.Object.Raw, &pod.Object); err != nil {
+		return admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("Could not render a Pod from %s: %s", request.Kind.String(), err.Error()))
+	}
+	changes, err := m.defaultPodVolumes(pod)
+	if err != nil {
+		return admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("Could not default the volumes of pod %q: %s", pod.GetName(), err.Error()))
+	}
+	if len(changes) == 0 {
+		ret = admissionctl.Allowed("No volume to default")
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	annotations := pod.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[consts.VolumeDefaultsAnnotation] = strings.Join(changes, ",")
+	pod.SetAnnotations(annotations)
+	defaulted, err := json.Marshal(pod.Object)
+	if err != nil {
+		return admissionctl.Errored(http.StatusInternalServerError, err)
+	}
+	klog.V(2).Infof("defaulted the shared resource volumes of pod %s/%s: %s", request.Namespace, pod.GetName(), strings.Join(changes, ","))
+	ret = admissionctl.PatchResponseFromRaw(request.Object.Raw, defaulted)
+	ret.UID = request.AdmissionRequest.UID
+	return ret
+}
+
+// defaultPodVolumes defaults the settings of the shared resource volumes of the pod, returning the
+// "volume/setting=value" settings it changed; the pod is handled as an unstructured object so that the patch only
+// holds these changes
+func (m *SharedResourcesCSIDriverMutatingWebhook) defaultPodVolumes(pod *unstructured.Unstructured) ([]string, error) {
+	volumes, found, err := unstructured.NestedSlice(pod.Object, "spec", "volumes")
+	if err != nil || !found {
+		return nil, err
+	}
+	keys := make([]string, 0, len(m.defaults))
+	for key := range m.defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changes := []string{}
+	for i, v := range volumes {
+		volume, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		csi, ok := volume["csi"].(map[string]interface{})
+		if !ok || csi["driver"] != string(operatorv1.SharedResourcesCSIDriver) {
+			continue
+		}
+		name, _ := volume["name"].(string)
+		if readOnly, _ := csi["readOnly"].(bool); !readOnly {
+			csi["readOnly"] = true
+			changes = append(changes, fmt.Sprintf("%s/readOnly=true", name))
+		}
+		attributes, _ := csi["volumeAttributes"].(map[string]interface{})
+		for _, key := range keys {
+			if _, ok := attributes[key]; ok {
+				continue
+			}
+			if attributes == nil {
+				attributes = map[string]interface{}{}
+				csi["volumeAttributes"] = attributes
+			}
+			attributes[key] = m.defaults[key]
+			changes = append(changes, fmt.Sprintf("%s/%s=%s", name, key, m.defaults[key]))
+		}
+		volumes[i] = volume
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return changes, unstructured.SetNestedSlice(pod.Object, volumes, "spec", "volumes")
+}
@@ -0,0 +1,215 @@
+package csidriver
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/csi-driver-shared-resource/pkg/config"
+)
+
+/*
+Admission policies let administrators add their own rules to the webhook, as CEL expressions which have to evaluate to
+true for a request to pass, like "shares whose name starts with team- must reference a namespace with the same prefix":
+
+	!object.metadata.name.startsWith('team-') || object.spec.secretRef.namespace.startsWith('team-')
+
+The expressions of the policies of shares are evaluated with these variables:
+
+- object: the SharedSecret or SharedConfigMap, as created or updated
+- oldObject: the share before the update, null otherwise
+- request: the "operation" and the "userInfo", with its "username", "uid", "groups" and "extra", of the request
+
+And the expressions of the policies of pods are evaluated once for every shared resource volume of the pod, with:
+
+- object: the pod
+- volume: the volume of the pod
+- share: the SharedSecret or SharedConfigMap the volume references, null when it does not exist yet
+- request: as for shares
+
+The policies are compiled when the webhook starts, which it does again when its configuration changes.
+*/
+
+const (
+	kindSharedSecret    = "SharedSecret"
+	kindSharedConfigMap = "SharedConfigMap"
+	kindPod             = "Pod"
+
+	// policyCostLimit bounds the cost of the evaluation of an expression, so that a policy cannot stall admission
+	policyCostLimit = 1000000
+)
+
+// Policies are the compiled admission policies
+type Policies struct {
+	policies []compiledPolicy
+}
+
+type compiledPolicy struct {
+	config.AdmissionPolicy
+	kinds   map[string]bool
+	program cel.Program
+}
+
+// CompilePolicies compiles the CEL expressions of the admission policies, failing on the first invalid one
+func CompilePolicies(policies []config.AdmissionPolicy) (*Policies, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.Variable("oldObject", cel.DynType),
+		cel.Variable("volume", cel.DynType),
+		cel.Variable("share", cel.DynType),
+		cel.Variable("request", cel.DynType),
+	)
+	if err != nil {
+		return nil, err
+	}
+	compiled := &Policies{}
+	for _, policy := range policies {
+		if len(policy.Action) == 0 {
+			policy.Action = config.AdmissionPolicyDeny
+		}
+		if policy.Action != config.AdmissionPolicyDeny && policy.Action != config.AdmissionPolicyWarn {
+			return nil, fmt.Errorf("admission policy %q has an unknown action %q", policy.Name, policy.Action)
+		}
+		kinds := map[string]bool{}
+		for _, kind := range policy.Kinds {
+			if kind != kindSharedSecret && kind != kindSharedConfigMap && kind != kindPod {
+				return nil, fmt.Errorf("admission policy %q applies to an unknown kind %q", policy.Name, kind)
+			}
+			kinds[kind] = true
+		}
+		ast, issues := env.Compile(policy.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("admission policy %q has an invalid expression: %s", policy.Name, issues.Err().Error())
+		}
+		program, err := env.Program(ast, cel.CostLimit(policyCostLimit))
+		if err != nil {
+			return nil, fmt.Errorf("admission policy %q has an invalid expression: %s", policy.Name, err.Error())
+		}
+		compiled.policies = append(compiled.policies, compiledPolicy{AdmissionPolicy: policy, kinds: kinds, program: program})
+	}
+	return compiled, nil
+}
+
+// evaluate checks the object of the given kind against the policies, returning the denial of the first policy with
+// the Deny action it fails, and the warnings of the policies with the Warn action it fails; an expression which
+// cannot be evaluated fails its policy
+func (p *Policies) evaluate(kind string, vars map[string]interface{}) ([]string, error) {
+	if p == nil {
+		return nil, nil
+	}
+	warnings := []string{}
+	for _, policy := range p.policies {
+		if len(policy.kinds) > 0 && !policy.kinds[kind] {
+			continue
+		}
+		violation := ""
+		out, _, err := policy.program.Eval(vars)
+		switch {
+		case err != nil:
+			violation = fmt.Sprintf("cannot be evaluated against admission policy %q: %s", policy.Name, err.Error())
+		case out.Value() != true:
+			violation = fmt.Sprintf("fails admission policy %q", policy.Name)
+			if len(policy.Message) > 0 {
+				violation = fmt.Sprintf("%s: %s", violation, policy.Message)
+			}
+		default:
+			continue
+		}
+		name := objectName(vars["object"])
+		klog.Infof("%s of %s %s: it %s", policy.Action, kind, name, violation)
+		if policy.Action == config.AdmissionPolicyWarn {
+			warnings = append(warnings, fmt.Sprintf("%s %q %s", kind, name, violation))
+			continue
+		}
+		return nil, fmt.Errorf("it %s", violation)
+	}
+	return warnings, nil
+}
+
+// evaluateShare checks the share, and its previous version on update, against the policies
+func (p *Policies) evaluateShare(request admissionctl.Request, kind string, share, oldShare runtime.Object) ([]string, error) {
+	if p == nil || len(p.policies) == 0 {
+		return nil, nil
+	}
+	object, err := toUnstructured(share)
+	if err != nil {
+		return nil, err
+	}
+	oldObject, err := toUnstructured(oldShare)
+	if err != nil {
+		return nil, err
+	}
+	return p.evaluate(kind, map[string]interface{}{
+		"object":    object,
+		"oldObject": oldObject,
+		"volume":    nil,
+		"share":     nil,
+		"request":   requestVariable(request),
+	})
+}
+
+// evaluatePodVolume checks the shared resource volume of the pod, and the share it references, against the policies
+func (p *Policies) evaluatePodVolume(request admissionctl.Request, pod *corev1.Pod, volume corev1.Volume, share runtime.Object) ([]string, error) {
+	if p == nil || len(p.policies) == 0 {
+		return nil, nil
+	}
+	object, err := toUnstructured(pod)
+	if err != nil {
+		return nil, err
+	}
+	volumeObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&volume)
+	if err != nil {
+		return nil, err
+	}
+	shareObject, err := toUnstructured(share)
+	if err != nil {
+		return nil, err
+	}
+	return p.evaluate(kindPod, map[string]interface{}{
+		"object":    object,
+		"oldObject": nil,
+		"volume":    volumeObject,
+		"share":     shareObject,
+		"request":   requestVariable(request),
+	})
+}
+
+// toUnstructured converts the object to the maps the expressions navigate, a nil object being null
+func toUnstructured(obj runtime.Object) (interface{}, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+func requestVariable(request admissionctl.Request) map[string]interface{} {
+	extra := map[string]interface{}{}
+	for key, values := range request.UserInfo.Extra {
+		extra[key] = []string(values)
+	}
+	return map[string]interface{}{
+		"operation": string(request.Operation),
+		"userInfo": map[string]interface{}{
+			"username": request.UserInfo.Username,
+			"uid":      request.UserInfo.UID,
+			"groups":   request.UserInfo.Groups,
+			"extra":    extra,
+		},
+	}
+}
+
+func objectName(object interface{}) string {
+	if m, ok := object.(map[string]interface{}); ok {
+		if metadata, ok := m["metadata"].(map[string]interface{}); ok {
+			if name, ok := metadata["name"].(string); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,245 @@
+package csidriver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+)
+
+// the kinds of the workloads whose pod templates are checked, so that a volume which cannot be mounted is reported
+// when the workload is applied rather than when its pods fail to start
+const (
+	kindDeployment  = "Deployment"
+	kindStatefulSet = "StatefulSet"
+	kindJob         = "Job"
+	kindCronJob     = "CronJob"
+	kindBuildConfig = "BuildConfig"
+	kindTaskRun     = "TaskRun"
+
+	// defaultBuildServiceAccount is the service account builds run with, unless their BuildConfig names another one
+	defaultBuildServiceAccount = "builder"
+)
+
+func isWorkload(kind string) bool {
+	switch kind {
+	case kindDeployment, kindStatefulSet, kindJob, kindCronJob, kindBuildConfig, kindTaskRun:
+		return true
+	}
+	return false
+}
+
+func (s *SharedResourcesCSIDriverWebhook) authorizeWorkload(request admissionctl.Request) admissionctl.Response {
+	kind := request.Kind.Kind
+	klog.V(2).Infof("admitting %s with SharedResourceCSIVolume", kind)
+	var ret admissionctl.Response
+	if request.Operation == admissionv1.Delete {
+		ret = admissionctl.Allowed(fmt.Sprintf("Allowed to delete %s", kind))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	pod, err := renderWorkloadPod(kind, request.Object)
+	if err != nil {
+		return admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("Could not render the pod template of %s %q: %s", kind, request.Name, err.Error()))
+	}
+	verb := "create"
+	if request.Operation == admissionv1.Update {
+		verb = "update"
+	}
+	namespace := pod.Namespace
+	if len(namespace) == 0 {
+		namespace = request.Namespace
+	}
+	warnings, err := s.validatePodVolumes(request, pod, namespace)
+	if err != nil && request.Operation == admissionv1.Update && !shareVolumesChanged(kind, request.OldObject, pod) {
+		// a share which expired, was revoked, or is no longer usable by the workload must not keep its unrelated
+		// updates, like the removal of its finalizers, from being made
+		ret = admissionctl.Allowed(fmt.Sprintf("Allowed to update %s", kind)).WithWarnings(
+			fmt.Sprintf("the pod template of %s %q has a %s", kind, pod.Name, err.Error()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	if err != nil {
+		ret = admissionctl.Denied(fmt.Sprintf("Not allowed to %s %s %q as its pod template has a %s", verb, kind, pod.Name, err.Error()))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	ret = admissionctl.Allowed(fmt.Sprintf("Allowed to %s %s", verb, kind)).WithWarnings(warnings...)
+	ret.UID = request.AdmissionRequest.UID
+	return ret
+}
+
+// shareVolumesChanged returns whether the update of the workload changes the shared resource volumes of its pod
+// template, or the service account they are used with, compared with the workload before the update
+func shareVolumesChanged(kind string, oldObject runtime.RawExtension, pod *corev1.Pod) bool {
+	if len(oldObject.Raw) == 0 {
+		return true
+	}
+	previous, err := renderWorkloadPod(kind, oldObject)
+	if err != nil {
+		return true
+	}
+	return previous.Spec.ServiceAccountName != pod.Spec.ServiceAccountName ||
+		!equality.Semantic.DeepEqual(shareVolumes(previous), shareVolumes(pod))
+}
+
+// shareVolumes returns the shared resource volumes of the pod
+func shareVolumes(pod *corev1.Pod) []corev1.Volume {
+	volumes := []corev1.Volume{}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.VolumeSource.CSI != nil && volume.VolumeSource.CSI.Driver == string(operatorv1.SharedResourcesCSIDriver) {
+			volumes = append(volumes, volume)
+		}
+	}
+	return volumes
+}
+
+// renderWorkloadPod decodes the workload of the given kind from the Object, or OldObject, of the incoming request,
+// and returns the pod it creates: its pod template, named and namespaced after the workload. As the build and Tekton
+// APIs are not part of this module, BuildConfigs and TaskRuns are read as unstructured objects, the pod only holding
+// their volumes and service account.
+func renderWorkloadPod(kind string, raw runtime.RawExtension) (*corev1.Pod, error) {
+	decoder := admissionctl.NewDecoder(scheme)
+	var meta metav1.Object
+	var template corev1.PodTemplateSpec
+	switch kind {
+	case kindDeployment:
+		deployment := &appsv1.Deployment{}
+		if err := decoder.DecodeRaw(raw, deployment); err != nil {
+			return nil, err
+		}
+		meta, template = deployment, deployment.Spec.Template
+	case kindStatefulSet:
+		statefulSet := &appsv1.StatefulSet{}
+		if err := decoder.DecodeRaw(raw, statefulSet); err != nil {
+			return nil, err
+		}
+		meta, template = statefulSet, statefulSet.Spec.Template
+	case kindJob:
+		job := &batchv1.Job{}
+		if err := decoder.DecodeRaw(raw, job); err != nil {
+			return nil, err
+		}
+		meta, template = job, job.Spec.Template
+	case kindCronJob:
+		cronJob := &batchv1.CronJob{}
+		if err := decoder.DecodeRaw(raw, cronJob); err != nil {
+			return nil, err
+		}
+		meta, template = cronJob, cronJob.Spec.JobTemplate.Spec.Template
+	case kindBuildConfig:
+		return unstructuredWorkloadPod(raw, buildConfigPodSpec)
+	case kindTaskRun:
+		return unstructuredWorkloadPod(raw, taskRunPodSpec)
+	default:
+		return nil, fmt.Errorf("unknown workload kind %s", kind)
+	}
+	pod := &corev1.Pod{ObjectMeta: template.ObjectMeta, Spec: template.Spec}
+	pod.Name = meta.GetName()
+	pod.Namespace = meta.GetNamespace()
+	return pod, nil
+}
+
+func unstructuredWorkloadPod(raw runtime.RawExtension, podSpec func(obj *unstructured.Unstructured) (corev1.PodSpec, error)) (*corev1.Pod, error) {
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(raw.Raw, &obj.Object); err != nil {
+		return nil, err
+	}
+	spec, err := podSpec(obj)
+	if err != nil {
+		return nil, err
+	}
+	pod := &corev1.Pod{Spec: spec}
+	pod.Name = obj.GetName()
+	pod.Namespace = obj.GetNamespace()
+	pod.Labels = obj.GetLabels()
+	return pod, nil
+}
+
+// buildConfigPodSpec returns the CSI volumes of the source or docker strategy of the BuildConfig, which are
+// mounted in its build pods
+func buildConfigPodSpec(obj *unstructured.Unstructured) (corev1.PodSpec, error) {
+	spec := corev1.PodSpec{ServiceAccountName: defaultBuildServiceAccount}
+	if serviceAccount, ok, _ := unstructured.NestedString(obj.Object, "spec", "serviceAccount"); ok && len(serviceAccount) > 0 {
+		spec.ServiceAccountName = serviceAccount
+	}
+	for _, strategy := range []string{"sourceStrategy", "dockerStrategy"} {
+		volumes, _, err := unstructured.NestedSlice(obj.Object, "spec", "strategy", strategy, "volumes")
+		if err != nil {
+			return spec, err
+		}
+		for _, v := range volumes {
+			volume, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			csi, ok, _ := unstructured.NestedMap(volume, "source", "csi")
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(volume, "name")
+			source := &corev1.CSIVolumeSource{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(csi, source); err != nil {
+				return spec, err
+			}
+			spec.Volumes = append(spec.Volumes, corev1.Volume{Name: name, VolumeSource: corev1.VolumeSource{CSI: source}})
+		}
+	}
+	return spec, nil
+}
+
+// taskRunPodSpec returns the volumes of the pod template of the TaskRun, along with its CSI workspaces, which are
+// mounted in its pod
+func taskRunPodSpec(obj *unstructured.Unstructured) (corev1.PodSpec, error) {
+	spec := corev1.PodSpec{}
+	spec.ServiceAccountName, _, _ = unstructured.NestedString(obj.Object, "spec", "serviceAccountName")
+	volumes, _, err := unstructured.NestedSlice(obj.Object, "spec", "podTemplate", "volumes")
+	if err != nil {
+		return spec, err
+	}
+	for _, v := range volumes {
+		volume, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		converted := corev1.Volume{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(volume, &converted); err != nil {
+			return spec, err
+		}
+		spec.Volumes = append(spec.Volumes, converted)
+	}
+	workspaces, _, err := unstructured.NestedSlice(obj.Object, "spec", "workspaces")
+	if err != nil {
+		return spec, err
+	}
+	for _, w := range workspaces {
+		workspace, ok := w.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		csi, ok, _ := unstructured.NestedMap(workspace, "csi")
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(workspace, "name")
+		source := &corev1.CSIVolumeSource{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(csi, source); err != nil {
+			return spec, err
+		}
+		spec.Volumes = append(spec.Volumes, corev1.Volume{Name: name, VolumeSource: corev1.VolumeSource{CSI: source}})
+	}
+	return spec, nil
+}
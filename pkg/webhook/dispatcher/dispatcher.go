@@ -70,7 +70,14 @@ func (d *Dispatcher) HandleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	SendResponse(w, d.hook.Authorized(request))
+	response := d.hook.Authorized(request)
+	// serializes the JSON patches of mutating webhooks into the response
+	if err := response.Complete(request); err != nil {
+		log.Error(err, "Failed to complete Response", "response", response)
+		SendResponse(w, admissionctl.Errored(http.StatusInternalServerError, err))
+		return
+	}
+	SendResponse(w, response)
 	return
 }
 